@@ -0,0 +1,172 @@
+package muse
+
+import (
+	"path"
+	"slices"
+	"strings"
+)
+
+// Addresses are dotted identifiers, each component can be a glob pattern:
+//
+//	voice*.filter  the filter of all receivers starting with voice
+//	*.reverb       the reverb of all sub patches
+//	drums.**       all receivers inside drums and its sub patches
+//	#fx            all receivers tagged with fx
+//
+// A "**" component matches zero or more components, a trailing "**" matches all descendants but not
+// the patch itself. "*", "?" and "[...]" match within a single component like path.Match
+
+// IsPattern returns true if address contains wildcards or tags and can match multiple receivers
+func IsPattern(address string) bool {
+	return strings.ContainsAny(address, "*?[#")
+}
+
+// patchLookup is implemented by BasePatch and all patches that embed it
+type patchLookup interface {
+	lookupAll(components []string, prefix string, result []match) []match
+	forEachReceiver(f func(identifier string, rcvr MessageReceiver))
+}
+
+// match is a receiver matching an address pattern together with its exact address
+type match struct {
+	address  string
+	receiver MessageReceiver
+}
+
+// LookupAll returns all receivers matching address in identifier order, an exact address returns at most
+// one receiver
+func (p *BasePatch) LookupAll(address string) []MessageReceiver {
+	matches := p.lookupMatches(address)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	rcvrs := make([]MessageReceiver, len(matches))
+	for i, m := range matches {
+		rcvrs[i] = m.receiver
+	}
+
+	return rcvrs
+}
+
+func (p *BasePatch) lookupMatches(address string) []match {
+	if !IsPattern(address) {
+		if rcvr := p.Lookup(address); rcvr != nil {
+			return []match{{address: address, receiver: rcvr}}
+		}
+
+		return nil
+	}
+
+	return p.lookupAll(strings.Split(address, "."), "", nil)
+}
+
+func (p *BasePatch) lookupAll(components []string, prefix string, result []match) []match {
+	component := components[0]
+	rest := components[1:]
+
+	if component == "**" {
+		if len(rest) > 0 {
+			result = p.lookupAll(rest, prefix, result)
+		}
+
+		for _, id := range p.sortedIdentifiers() {
+			rcvr := p.receivers[id]
+
+			if len(rest) == 0 {
+				result = appendMatch(result, prefix+id, rcvr)
+			}
+
+			if sub, ok := rcvr.(patchLookup); ok {
+				result = sub.lookupAll(components, prefix+id+".", result)
+			}
+		}
+
+		return result
+	}
+
+	for _, id := range p.sortedIdentifiers() {
+		if !p.matchComponent(component, id) {
+			continue
+		}
+
+		rcvr := p.receivers[id]
+
+		if len(rest) == 0 {
+			result = appendMatch(result, prefix+id, rcvr)
+		} else if sub, ok := rcvr.(patchLookup); ok {
+			result = sub.lookupAll(rest, prefix+id+".", result)
+		}
+	}
+
+	return result
+}
+
+// appendMatch appends rcvr if it is not in result yet, a receiver can match a pattern with "**" in more than one way
+func appendMatch(result []match, address string, rcvr MessageReceiver) []match {
+	for _, other := range result {
+		if other.receiver == rcvr {
+			return result
+		}
+	}
+
+	return append(result, match{address: address, receiver: rcvr})
+}
+
+// forEachReceiver calls f for all receivers of the patch in identifier order
+func (p *BasePatch) forEachReceiver(f func(identifier string, rcvr MessageReceiver)) {
+	for _, id := range p.sortedIdentifiers() {
+		f(id, p.receivers[id])
+	}
+}
+
+func (p *BasePatch) matchComponent(pattern string, identifier string) bool {
+	if tag, ok := strings.CutPrefix(pattern, "#"); ok {
+		return slices.Contains(p.tags[identifier], tag)
+	}
+
+	matched, err := path.Match(pattern, identifier)
+
+	return err == nil && matched
+}
+
+// sortedIdentifiers returns the receiver identifiers in order, the list is cached until receivers change
+func (p *BasePatch) sortedIdentifiers() []string {
+	if p.identifiers == nil {
+		p.identifiers = make([]string, 0, len(p.receivers))
+		for id := range p.receivers {
+			p.identifiers = append(p.identifiers, id)
+		}
+
+		slices.Sort(p.identifiers)
+	}
+
+	return p.identifiers
+}
+
+// Tag adds tags to the receiver with identifier, tagged receivers are addressed with "#tag". Tags belong
+// to the identifier and are kept when the receiver is replaced
+func (p *BasePatch) Tag(identifier string, tags ...string) {
+	for _, tag := range tags {
+		if !slices.Contains(p.tags[identifier], tag) {
+			p.tags[identifier] = append(p.tags[identifier], tag)
+		}
+	}
+}
+
+// Untag removes tags from the receiver with identifier, all tags are removed if none are given
+func (p *BasePatch) Untag(identifier string, tags ...string) {
+	if len(tags) == 0 {
+		delete(p.tags, identifier)
+		return
+	}
+
+	p.tags[identifier] = slices.DeleteFunc(p.tags[identifier], func(tag string) bool {
+		return slices.Contains(tags, tag)
+	})
+}
+
+// Tags returns the tags of the receiver with identifier
+func (p *BasePatch) Tags(identifier string) []string {
+	return slices.Clone(p.tags[identifier])
+}
@@ -0,0 +1,127 @@
+package muse
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// AudioCallback processes one block of deinterleaved audio, in holds a buffer per input channel and out a buffer
+// per output channel
+type AudioCallback func(in, out [][]float32)
+
+// AudioDevice describes an audio device of a backend
+type AudioDevice struct {
+	Name              string
+	HostAPI           string
+	MaxInputChannels  int
+	MaxOutputChannels int
+	DefaultSampleRate float64
+	// SampleRates are the common sample rates the device supports
+	SampleRates []float64
+}
+
+// SupportsSampleRate returns true if the device can run at sampleRate
+func (d AudioDevice) SupportsSampleRate(sampleRate float64) bool {
+	return sampleRate == d.DefaultSampleRate || slices.Contains(d.SampleRates, sampleRate)
+}
+
+// AudioStreamParameters are the parameters of a stream opened by an audio backend, an empty device name
+// selects the default device of the backend
+type AudioStreamParameters struct {
+	InputDevice  string
+	OutputDevice string
+	NumInputs    int
+	NumOutputs   int
+	SampleRate   float64
+	BufferSize   int
+}
+
+// AudioBackend runs the audio callback of a muse for a stream of blocks, like a sound card, a timer or
+// sound files. A backend opens a single stream at a time
+type AudioBackend interface {
+	// Devices returns the devices the backend can open
+	Devices() ([]AudioDevice, error)
+	// Open prepares a stream, the callback is called for every block once the stream is started
+	Open(params AudioStreamParameters, callback AudioCallback) error
+	Start() error
+	// Stop stops calling the callback, the stream can be started again
+	Stop() error
+	// Close stops and releases the stream
+	Close() error
+}
+
+// FiniteAudioBackend is implemented by backends with a stream that ends by itself, Done is closed when the
+// stream has ended and Err returns the error that ended it
+type FiniteAudioBackend interface {
+	AudioBackend
+	Done() <-chan struct{}
+	Err() error
+}
+
+// CommonSampleRates are the sample rates checked for device support
+var CommonSampleRates = []float64{22050, 32000, 44100, 48000, 88200, 96000, 176400, 192000}
+
+// ErrNoAudioDevice is returned when no device matches a query
+var ErrNoAudioDevice = errors.New("no matching audio device")
+
+// AudioDeviceQuery selects a device by name, channel count and sample rate, zero fields match any device
+type AudioDeviceQuery struct {
+	// Name matches the device name case insensitive, a device with the exact name is preferred over a device
+	// that contains the name
+	Name        string
+	MinInputs   int
+	MinOutputs  int
+	SampleRate  float64
+	HostAPIName string
+}
+
+func (q AudioDeviceQuery) matches(d AudioDevice) bool {
+	return (q.Name == "" || strings.Contains(strings.ToLower(d.Name), strings.ToLower(q.Name))) &&
+		(q.HostAPIName == "" || strings.EqualFold(q.HostAPIName, d.HostAPI)) &&
+		d.MaxInputChannels >= q.MinInputs &&
+		d.MaxOutputChannels >= q.MinOutputs &&
+		(q.SampleRate == 0 || d.SupportsSampleRate(q.SampleRate))
+}
+
+// FindAudioDevice returns the first device of backend that matches query
+func FindAudioDevice(backend AudioBackend, query AudioDeviceQuery) (AudioDevice, error) {
+	devices, err := backend.Devices()
+	if err != nil {
+		return AudioDevice{}, err
+	}
+
+	var found *AudioDevice
+
+	for i, device := range devices {
+		if !query.matches(device) {
+			continue
+		}
+
+		if strings.EqualFold(device.Name, query.Name) {
+			return device, nil
+		}
+
+		if found == nil {
+			found = &devices[i]
+		}
+	}
+
+	if found == nil {
+		return AudioDevice{}, fmt.Errorf("%w: %+v", ErrNoAudioDevice, query)
+	}
+
+	return *found, nil
+}
+
+// allocateAudioBuffers returns numChannels buffers of bufferSize samples
+func allocateAudioBuffers(numChannels int, bufferSize int) [][]float32 {
+	buffers := make([][]float32, numChannels)
+
+	for i := range buffers {
+		buffers[i] = make([]float32, bufferSize)
+	}
+
+	return buffers
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/almerlucke/muse"
+	"github.com/almerlucke/muse/patchdoc"
+	"github.com/almerlucke/sndfile/writer"
+)
+
+func render(cmd *command, args []string) error {
+	var (
+		pf     patchFlags
+		opts   muse.RenderOptions
+		output string
+		format string
+		fs     = cmd.flagSet()
+	)
+
+	pf.register(fs)
+	fs.StringVar(&output, "o", "", "output file, defaults to the patch name with the extension of the format")
+	fs.StringVar(&format, "format", "wav", "file format: wav or aiff")
+	fs.Float64Var(&opts.SampleRate, "file-sr", 0, "sample rate of the file, defaults to the render sample rate")
+	fs.Float64Var(&opts.Duration, "duration", 10, "duration in seconds, the minimum duration with -tail")
+	fs.Float64Var(&opts.TailDuration, "tail", 0, "render until the output is silent for this many milliseconds")
+	fs.Float64Var(&opts.MaxDuration, "max", muse.DefaultRenderMaxDuration, "maximum duration in seconds with -tail")
+	fs.Float64Var(&opts.TailThreshold, "threshold", muse.DefaultTailThreshold, "level below which the tail is silent")
+	fs.Float64Var(&opts.Start, "start", 0, "seconds to skip at the start")
+	fs.Float64Var(&opts.FadeIn, "fade-in", 0, "fade in in milliseconds")
+	fs.Float64Var(&opts.FadeOut, "fade-out", 0, "fade out in milliseconds")
+	fs.BoolVar(&opts.Normalize, "normalize", false, "normalize the output")
+	fs.IntVar(&opts.BitDepth, "bits", 0, "write 16 or 24 bit integer WAV files instead of float")
+	fs.BoolVar(&opts.Dither, "dither", false, "dither integer files")
+
+	filePath, err := parsePatchArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "wav":
+		opts.FileFormat = writer.WAV
+	case "aiff", "aif":
+		opts.FileFormat = writer.AIFC
+	default:
+		return fmt.Errorf("unknown file format %q", format)
+	}
+
+	if output == "" {
+		output = outputPath(filePath, map[writer.FileFormat]string{writer.WAV: ".wav", writer.AIFC: ".aif"}[opts.FileFormat])
+	}
+
+	root, _, err := pf.load(filePath)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+
+	if err = root.Render(output, opts); err != nil {
+		return err
+	}
+
+	fmt.Printf("rendered %s in %v\n", output, time.Since(start).Round(time.Millisecond))
+
+	return nil
+}
+
+func play(cmd *command, args []string) error {
+	var (
+		pf       patchFlags
+		backend  string
+		input    string
+		output   string
+		list     bool
+		duration float64
+		fs       = cmd.flagSet()
+	)
+
+	pf.register(fs)
+	fs.StringVar(&backend, "backend", "portaudio", "audio backend: portaudio or null")
+	fs.StringVar(&input, "in", "", "input device name, defaults to the default device")
+	fs.StringVar(&output, "out", "", "output device name, defaults to the default device")
+	fs.BoolVar(&list, "devices", false, "list the devices of the backend and exit")
+	fs.Float64Var(&duration, "duration", 0, "play for this many seconds instead of until enter is pressed")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var audioBackend muse.AudioBackend
+
+	switch backend {
+	case "portaudio":
+		audioBackend = muse.NewPortAudioBackend()
+	case "null":
+		audioBackend = muse.NewNullAudioBackend()
+	default:
+		return fmt.Errorf("unknown audio backend %q", backend)
+	}
+
+	// Listing devices does not need a patch
+	if list {
+		return listDevices(audioBackend)
+	}
+
+	filePath, err := parsePatchArgs(fs, fs.Args())
+	if err != nil {
+		return err
+	}
+
+	root, _, err := pf.load(filePath)
+	if err != nil {
+		return err
+	}
+
+	root.SetAudioBackend(audioBackend)
+	root.SetAudioDevices(input, output)
+
+	if duration <= 0 {
+		return root.RenderAudio()
+	}
+
+	if err = root.InitializeAudio(); err != nil {
+		return err
+	}
+
+	defer root.TerminateAudio()
+
+	if err = root.StartAudio(); err != nil {
+		return err
+	}
+
+	time.Sleep(time.Duration(duration * float64(time.Second)))
+
+	return root.StopAudio()
+}
+
+func listDevices(backend muse.AudioBackend) error {
+	devices, err := backend.Devices()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "NAME\tHOST API\tINPUTS\tOUTPUTS\tSAMPLE RATES")
+
+	for _, device := range devices {
+		rates := make([]string, len(device.SampleRates))
+		for i, rate := range device.SampleRates {
+			rates[i] = fmt.Sprint(rate)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\n", device.Name, device.HostAPI, device.MaxInputChannels,
+			device.MaxOutputChannels, strings.Join(rates, " "))
+	}
+
+	return tw.Flush()
+}
+
+func graph(cmd *command, args []string) error {
+	fs := cmd.flagSet()
+
+	filePath, err := parsePatchArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	doc, err := patchdoc.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	printDocument(doc, "")
+
+	return nil
+}
+
+// printDocument prints the objects and connections of doc and its sub patches indented by indent
+func printDocument(doc *patchdoc.Document, indent string) {
+	id := doc.ID
+	if id == "" {
+		id = patchdoc.SelfAddress
+	}
+
+	fmt.Printf("%spatch %s (%d in, %d out)\n", indent, id, doc.Inputs, doc.Outputs)
+
+	indent += "  "
+
+	for _, sub := range doc.Patches {
+		printDocument(sub, indent)
+	}
+
+	groups := []struct {
+		kind    string
+		objects []*patchdoc.Object
+	}{{"module", doc.Modules}, {"messenger", doc.Messengers}, {"control", doc.Controls}}
+
+	for _, group := range groups {
+		for _, obj := range group.objects {
+			fmt.Printf("%s%s %s: %s", indent, group.kind, obj.ID, obj.Type)
+
+			if len(obj.Tags) > 0 {
+				fmt.Printf(" #%s", strings.Join(obj.Tags, " #"))
+			}
+
+			fmt.Println()
+		}
+	}
+
+	for _, conn := range doc.Connections {
+		printConnection(indent, "->", conn)
+	}
+
+	for _, conn := range doc.ControlConnections {
+		printConnection(indent, "~>", conn)
+	}
+}
+
+func printConnection(indent string, arrow string, conn *patchdoc.Connection) {
+	to := fmt.Sprintf("%s:%d", conn.To, conn.In)
+	if conn.Param != "" {
+		to = conn.To + "." + conn.Param
+	}
+
+	feedback := ""
+	if conn.Feedback {
+		feedback = " (feedback)"
+	}
+
+	fmt.Printf("%s%s:%d %s %s%s\n", indent, conn.From, conn.Out, arrow, to, feedback)
+}
+
+func types(cmd *command, args []string) error {
+	fs := cmd.flagSet()
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	reg := patchdoc.DefaultRegistry
+	names := fs.Args()
+
+	if len(names) == 0 {
+		names = reg.Types()
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+	for _, name := range names {
+		if !reg.Has(name) {
+			return fmt.Errorf("unknown type %q", name)
+		}
+
+		fmt.Fprintf(tw, "%s\n", name)
+
+		// Create an instance with default parameters to describe its parameters
+		obj, err := reg.New(name, patchdoc.Params{})
+		if err != nil {
+			fmt.Fprintf(tw, "  (no defaults: %v)\n", err)
+			continue
+		}
+
+		for _, param := range muse.ParametersOf(obj) {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", param.Name, param.Type, describeRange(param), param.Unit)
+		}
+	}
+
+	return tw.Flush()
+}
+
+// describeRange returns the range and default of a parameter
+func describeRange(param muse.Parameter) string {
+	switch param.Type {
+	case muse.ParameterFloat, muse.ParameterInt:
+		return fmt.Sprintf("%v - %v (default %v)", param.Min, param.Max, param.Default)
+	case muse.ParameterBang, muse.ParameterAny:
+		return ""
+	default:
+		return fmt.Sprintf("(default %v)", param.Default)
+	}
+}
+
+func plot(cmd *command, args []string) error {
+	var (
+		pf       patchFlags
+		address  string
+		outIndex int
+		blocks   int
+		width    float64
+		height   float64
+		output   string
+		fs       = cmd.flagSet()
+	)
+
+	pf.register(fs)
+	fs.StringVar(&address, "module", "", "address of the module to plot, like voice.filter")
+	fs.IntVar(&outIndex, "out", 0, "output index of the module")
+	fs.IntVar(&blocks, "blocks", 1, "number of blocks to plot")
+	fs.Float64Var(&width, "width", 800, "width of the image in points")
+	fs.Float64Var(&height, "height", 300, "height of the image in points")
+	fs.StringVar(&output, "o", "", "output image, defaults to the patch name with a .png extension")
+
+	filePath, err := parsePatchArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if address == "" {
+		return errors.New("missing -module")
+	}
+
+	if output == "" {
+		output = outputPath(filePath, ".png")
+	}
+
+	root, _, err := pf.load(filePath)
+	if err != nil {
+		return err
+	}
+
+	module, ok := root.Lookup(address).(muse.Module)
+	if !ok {
+		return fmt.Errorf("no module at %q", address)
+	}
+
+	if outIndex < 0 || outIndex >= module.NumOutputs() {
+		return fmt.Errorf("module %q has no output %d", address, outIndex)
+	}
+
+	return root.PlotModule(module, outIndex, blocks, width, height, output)
+}
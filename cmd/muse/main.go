@@ -0,0 +1,142 @@
+// Command muse renders, plays and inspects patch documents (see package patchdoc) without writing Go.
+//
+// Usage:
+//
+//	muse render [flags] patch.json   render a patch to a sound file
+//	muse play [flags] patch.json     play a patch live
+//	muse graph patch.json            print the modules and connections of a patch
+//	muse types [type ...]            list the registered types and their parameters
+//	muse plot [flags] patch.json     plot a module output to an image
+//
+// Run muse <command> -h for the flags of a command.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/almerlucke/muse"
+	"github.com/almerlucke/muse/patchdoc"
+	_ "github.com/almerlucke/muse/patchdoc/builtin"
+)
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(cmd *command, args []string) error
+}
+
+var commands = []*command{
+	{name: "render", usage: "[flags] patch", summary: "render a patch to a sound file", run: render},
+	{name: "play", usage: "[flags] patch", summary: "play a patch live", run: play},
+	{name: "graph", usage: "patch", summary: "print the modules and connections of a patch", run: graph},
+	{name: "types", usage: "[type ...]", summary: "list the registered types and their parameters", run: types},
+	{name: "plot", usage: "[flags] patch", summary: "plot a module output to an image", run: plot},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: muse <command> [arguments]\n\nCommands:\n")
+
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			if err := cmd.run(cmd, os.Args[2:]); errors.Is(err, flag.ErrHelp) {
+				os.Exit(2)
+			} else if err != nil {
+				fmt.Fprintf(os.Stderr, "muse %s: %v\n", cmd.name, err)
+				os.Exit(1)
+			}
+
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "muse: unknown command %q\n\n", os.Args[1])
+	usage()
+	os.Exit(2)
+}
+
+// flagSet returns the flag set of a command with a usage message
+func (cmd *command) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("muse "+cmd.name, flag.ContinueOnError)
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: muse %s %s\n\n%s\n\n", cmd.name, cmd.usage, cmd.summary)
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// patchFlags are the flags of the commands that build a patch
+type patchFlags struct {
+	sampleRate float64
+	bufferSize int
+	seed       uint64
+	workers    int
+}
+
+func (pf *patchFlags) register(fs *flag.FlagSet) {
+	fs.Float64Var(&pf.sampleRate, "sr", muse.DefaultSamplerate, "sample rate")
+	fs.IntVar(&pf.bufferSize, "buffer", muse.DefaultBufferSize, "buffer size in samples")
+	fs.Uint64Var(&pf.seed, "seed", 1, "master seed of all random sources of the patch")
+	fs.IntVar(&pf.workers, "workers", 0, "render in parallel on this many worker goroutines, -1 uses the number of CPUs")
+}
+
+// parsePatchArgs parses the flags and returns the single patch argument
+func parsePatchArgs(fs *flag.FlagSet, args []string) (string, error) {
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return "", fmt.Errorf("expected a single patch document")
+	}
+
+	return fs.Arg(0), nil
+}
+
+// load builds the patch document at filePath into a new muse with the sample rate and buffer size of the flags
+func (pf *patchFlags) load(filePath string) (*muse.Muse, *patchdoc.Document, error) {
+	doc, err := patchdoc.ReadFile(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	muse.PushConfiguration(&muse.Configuration{
+		SampleRate: pf.sampleRate,
+		BufferSize: pf.bufferSize,
+	})
+
+	root := muse.NewWithInputs(doc.Inputs, doc.Outputs)
+
+	if err = doc.BuildInto(root, patchdoc.DefaultRegistry); err != nil {
+		return nil, nil, err
+	}
+
+	root.SetSeed(pf.seed)
+	root.SetWorkers(pf.workers)
+
+	return root, doc, nil
+}
+
+// outputPath returns the path of the patch document with another extension
+func outputPath(filePath string, ext string) string {
+	return strings.TrimSuffix(filePath, filepath.Ext(filePath)) + ext
+}
@@ -0,0 +1,91 @@
+package muse
+
+import (
+	"errors"
+	"log"
+)
+
+// DefaultCommandQueueSize is the number of commands that can be posted to a muse between two blocks
+var DefaultCommandQueueSize = 1024
+
+// ErrQueueFull is returned when a command is posted while the command queue is full
+var ErrQueueFull = errors.New("command queue is full")
+
+// Command is executed on the audio thread at the start of a block, before anything is synthesized
+type Command func(m *Muse) error
+
+// Post queues a command to run at the start of the next block. Post can be called from any
+// goroutine, posting goroutines are serialized so the audio thread can drain the queue without locks
+func (m *Muse) Post(cmd Command) error {
+	m.postLock.Lock()
+	defer m.postLock.Unlock()
+
+	if !m.commands.Push(cmd) {
+		return ErrQueueFull
+	}
+
+	return nil
+}
+
+// PostMessage sends a message on the audio thread
+func (m *Muse) PostMessage(msg *Message) error {
+	return m.Post(func(m *Muse) error {
+		m.SendMessage(msg)
+		return nil
+	})
+}
+
+// PostMessageTo lets a receiver receive a message on the audio thread, messages returned by the
+// receiver are sent from the muse patch
+func (m *Muse) PostMessageTo(rcvr MessageReceiver, msg any) error {
+	return m.Post(func(m *Muse) error {
+		m.SendMessages(rcvr.ReceiveMessage(msg))
+		return nil
+	})
+}
+
+// PostControlValue lets a control receive a value on the audio thread
+func (m *Muse) PostControlValue(ctrl Control, value any, index int) error {
+	return m.Post(func(m *Muse) error {
+		ctrl.ReceiveControlValue(value, index)
+		return nil
+	})
+}
+
+// PostAddModule adds a module to the muse patch on the audio thread
+func (m *Muse) PostAddModule(module Module) error {
+	return m.Post(func(m *Muse) error {
+		m.AddModule(module)
+		return nil
+	})
+}
+
+// PostRemoveModule removes a module from the muse patch on the audio thread
+func (m *Muse) PostRemoveModule(module Module) error {
+	return m.Post(func(m *Muse) error {
+		m.RemoveModule(module)
+		return nil
+	})
+}
+
+// PostConnect connects two modules on the audio thread, connection errors are logged
+func (m *Muse) PostConnect(from Module, outIndex int, to Module, inIndex int) error {
+	return m.Post(func(m *Muse) error {
+		return from.TryConnect(outIndex, to, inIndex)
+	})
+}
+
+// ProcessCommands runs all posted commands, it is called at the start of each block and
+// should only be called directly when the muse is synthesized manually from another goroutine
+func (m *Muse) ProcessCommands() {
+	for {
+		cmd, ok := m.commands.Pop()
+		if !ok {
+			return
+		}
+
+		if err := cmd(m); err != nil {
+			log.Printf("muse command failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package smooth
+
+import (
+	"math"
+
+	"github.com/almerlucke/muse/utils"
+)
+
+// Mode determines the curve of a parameter change
+type Mode int
+
+const (
+	// Linear changes the value in equal steps and reaches the target after the smoothing time
+	Linear Mode = iota
+	// Exponential changes the value by an equal factor each sample, useful for frequencies.
+	// Changes that cross or touch zero fall back to linear
+	Exponential
+	// OnePole approaches the target with a one-pole lowpass, the smoothing time is the time constant
+	OnePole
+)
+
+// DefaultTime is the smoothing time in milliseconds for modules that adopt smoothed parameters
+var DefaultTime = 10.0
+
+// Param is a smoothed parameter, targets set with Set or Ramp are reached over time while
+// Next is called once per sample. Jump changes the value immediately
+type Param struct {
+	value      float64
+	target     float64
+	step       float64
+	remaining  int
+	mode       Mode
+	geometric  bool
+	time       float64
+	sampleRate float64
+}
+
+// New creates a parameter with an initial value, a smoothing time in milliseconds and a mode
+func New(value float64, time float64, mode Mode, sampleRate float64) *Param {
+	return &Param{
+		value:      value,
+		target:     value,
+		mode:       mode,
+		time:       time,
+		sampleRate: sampleRate,
+	}
+}
+
+// Value returns the current value
+func (p *Param) Value() float64 {
+	return p.value
+}
+
+// Target returns the value the parameter is moving to
+func (p *Param) Target() float64 {
+	return p.target
+}
+
+// Time returns the smoothing time in milliseconds
+func (p *Param) Time() float64 {
+	return p.time
+}
+
+// SetTime sets the smoothing time in milliseconds, 0 disables smoothing
+func (p *Param) SetTime(time float64) {
+	p.time = time
+}
+
+func (p *Param) Mode() Mode {
+	return p.mode
+}
+
+func (p *Param) SetMode(mode Mode) {
+	p.mode = mode
+}
+
+// IsSmoothing returns true if the value has not reached the target yet
+func (p *Param) IsSmoothing() bool {
+	return p.remaining > 0
+}
+
+// Jump sets the value and target immediately
+func (p *Param) Jump(value float64) {
+	p.value = value
+	p.target = value
+	p.remaining = 0
+}
+
+// Set moves to a target over the smoothing time
+func (p *Param) Set(target float64) {
+	p.Ramp(target, p.time)
+}
+
+// Ramp moves to a target over time milliseconds
+func (p *Param) Ramp(target float64, time float64) {
+	n := int(time * 0.001 * p.sampleRate)
+	if n <= 0 || target == p.value {
+		p.Jump(target)
+		return
+	}
+
+	p.target = target
+	p.remaining = n
+	// The curve is chosen once per ramp, a linear ramp that reaches the sign of the target stays linear
+	p.geometric = p.mode == Exponential && p.value*target > 0
+
+	switch p.mode {
+	case Exponential:
+		if p.geometric {
+			p.step = math.Pow(target/p.value, 1.0/float64(n))
+		} else {
+			p.step = (target - p.value) / float64(n)
+		}
+	case OnePole:
+		// Run for 7 time constants, then snap to the target (remaining error < 0.1%)
+		p.step = 1.0 - math.Exp(-1.0/float64(n))
+		p.remaining = 7 * n
+	default:
+		p.step = (target - p.value) / float64(n)
+	}
+}
+
+// Next advances the parameter one sample and returns the new value
+func (p *Param) Next() float64 {
+	if p.remaining == 0 {
+		return p.value
+	}
+
+	p.remaining--
+
+	if p.remaining == 0 {
+		p.value = p.target
+		return p.value
+	}
+
+	switch p.mode {
+	case Exponential:
+		if p.geometric {
+			p.value *= p.step
+		} else {
+			p.value += p.step
+		}
+	case OnePole:
+		p.value += (p.target - p.value) * p.step
+	default:
+		p.value += p.step
+	}
+
+	return p.value
+}
+
+// Receive handles the value of a message or control value, either a number that is smoothed with
+// the smoothing time or a map with a "value" and an optional "ramp" time in milliseconds to glide
+// over. Returns false if the value has another form
+func (p *Param) Receive(raw any) bool {
+	target, ramp, ok := Parse(raw)
+	if !ok {
+		return false
+	}
+
+	if ramp < 0 {
+		p.Set(target)
+	} else {
+		p.Ramp(target, ramp)
+	}
+
+	return true
+}
+
+// Parse returns the target and ramp time of a message or control value, the ramp time is -1 if
+// the value does not specify a ramp
+func Parse(raw any) (target float64, ramp float64, ok bool) {
+	if target, ok = utils.ToFloat(raw); ok {
+		return target, -1, true
+	}
+
+	v, isMap := raw.(map[string]any)
+	if !isMap {
+		return 0, 0, false
+	}
+
+	target, ok = utils.ToFloat(v["value"])
+	if !ok {
+		return 0, 0, false
+	}
+
+	ramp = -1
+	if rawRamp, hasRamp := v["ramp"]; hasRamp {
+		ramp, ok = utils.ToFloat(rawRamp)
+		if !ok {
+			return 0, 0, false
+		}
+	}
+
+	return target, ramp, true
+}
+
+// Smoother is implemented by modules with smoothed parameters
+type Smoother interface {
+	SetSmoothing(time float64, mode Mode)
+}
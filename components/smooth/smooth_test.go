@@ -0,0 +1,76 @@
+package smooth
+
+import (
+	"math"
+	"testing"
+)
+
+// ramp returns the first samples of a ramp from value to target over n samples
+func ramp(mode Mode, value float64, target float64, n int, samples int) []float64 {
+	p := New(value, 0, mode, 1000)
+	p.Ramp(target, float64(n))
+
+	values := make([]float64, samples)
+	for i := range values {
+		values[i] = p.Next()
+	}
+
+	return values
+}
+
+func expectValues(t *testing.T, values []float64, expected func(i int) float64) {
+	t.Helper()
+
+	for i, v := range values {
+		if math.Abs(v-expected(i)) > 1e-9 {
+			t.Fatalf("sample %d is %v, expected %v (%v)", i, v, expected(i), values)
+		}
+	}
+}
+
+func TestExponentialRamp(t *testing.T) {
+	values := ramp(Exponential, 1, 16, 4, 4)
+
+	expectValues(t, values, func(i int) float64 {
+		return math.Pow(2, float64(i+1))
+	})
+}
+
+func TestExponentialRampFromZero(t *testing.T) {
+	values := ramp(Exponential, 0, 1, 10, 10)
+
+	expectValues(t, values, func(i int) float64 {
+		return float64(i+1) / 10
+	})
+}
+
+func TestExponentialRampThroughZero(t *testing.T) {
+	values := ramp(Exponential, -1, 1, 10, 10)
+
+	expectValues(t, values, func(i int) float64 {
+		return -1 + float64(i+1)/5
+	})
+}
+
+func TestLinearRamp(t *testing.T) {
+	values := ramp(Linear, 2, 4, 4, 4)
+
+	expectValues(t, values, func(i int) float64 {
+		return 2 + float64(i+1)/2
+	})
+}
+
+func TestOnePoleReachesTarget(t *testing.T) {
+	// A one pole ramp runs for 7 time constants
+	values := ramp(OnePole, 0, 1, 10, 70)
+
+	for i := 1; i < len(values); i++ {
+		if values[i] < values[i-1] {
+			t.Fatalf("one pole ramp is not monotonic at sample %d", i)
+		}
+	}
+
+	if values[len(values)-1] != 1 {
+		t.Fatalf("one pole ramp ends at %v", values[len(values)-1])
+	}
+}
@@ -1,8 +1,18 @@
 package muse
 
+import "github.com/almerlucke/muse/buffer"
+
 type Connection struct {
 	Module Module
 	Index  int
+	// Tap holds the output of the previous block for feedback connections, the
+	// input and output side of a feedback connection share the same tap
+	Tap buffer.Buffer
+}
+
+// IsFeedback returns true if the connection reads the output of the previous block
+func (c *Connection) IsFeedback() bool {
+	return c.Tap != nil
 }
 
 // IConn for quick connecting multiple module/control outputs to inputs of module/control
@@ -0,0 +1,250 @@
+package muse
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sync/atomic"
+
+	"github.com/almerlucke/muse/utils"
+)
+
+// ErrInvalidMessage is wrapped by all errors for messages and control values a receiver can not decode
+var ErrInvalidMessage = errors.New("invalid message")
+
+// MessageError describes a message or control value that a receiver could not decode
+type MessageError struct {
+	Receiver string
+	// Key is the message key, empty for control values and for messages without map content
+	Key string
+	// Index is the control input index, -1 for messages
+	Index  int
+	Value  any
+	Reason string
+}
+
+func (e *MessageError) Error() string {
+	var value string
+	if e.Value != nil {
+		value = fmt.Sprintf(" (%T %v)", e.Value, e.Value)
+	}
+
+	switch {
+	case e.Index >= 0:
+		return fmt.Sprintf("%s: control %d: %s%s", e.Receiver, e.Index, e.Reason, value)
+	case e.Key != "":
+		return fmt.Sprintf("%s: message key %q: %s%s", e.Receiver, e.Key, e.Reason, value)
+	default:
+		return fmt.Sprintf("%s: message: %s (%T)", e.Receiver, e.Reason, e.Value)
+	}
+}
+
+func (e *MessageError) Unwrap() error {
+	return ErrInvalidMessage
+}
+
+var messageErrorHandler atomic.Pointer[func(error)]
+
+// SetMessageErrorHandler sets the function invalid messages and control values are reported to, nil
+// restores the default handler that logs the error. The handler is called on the audio thread
+func SetMessageErrorHandler(handler func(error)) {
+	if handler == nil {
+		messageErrorHandler.Store(nil)
+		return
+	}
+
+	messageErrorHandler.Store(&handler)
+}
+
+// ReportMessageError passes err to the message error handler
+func ReportMessageError(err error) {
+	if handler := messageErrorHandler.Load(); handler != nil {
+		(*handler)(err)
+		return
+	}
+
+	log.Printf("muse: %v", err)
+}
+
+// describeReceiver returns the identifier of receiver or its type, embedding types like Polyphony
+// report the type of the embedding self
+func describeReceiver(receiver any) string {
+	if id, ok := receiver.(Identifiable); ok && id.Identifier() != "" {
+		return id.Identifier()
+	}
+
+	if s, ok := receiver.(Selfie); ok && s.Self() != nil {
+		receiver = s.Self()
+	}
+
+	return fmt.Sprintf("%T", receiver)
+}
+
+// Content gives typed access to the keys of map message content. Keys that are missing are ignored,
+// keys with a value of the wrong type are reported to the message error handler
+type Content struct {
+	receiver any
+	values   map[string]any
+}
+
+// DecodeMessage returns the content of msg for receiver, nil is decoded as empty content. If msg is not
+// map content an error is reported and false is returned, a bang is not reported
+func DecodeMessage(receiver any, msg any) (Content, bool) {
+	if values, ok := msg.(map[string]any); ok {
+		return Content{receiver: receiver, values: values}, true
+	}
+
+	if msg == nil {
+		return Content{receiver: receiver}, true
+	}
+
+	if msg != Bang {
+		ReportMessageError(&MessageError{Receiver: describeReceiver(receiver), Index: -1, Value: msg, Reason: "expected map content"})
+	}
+
+	return Content{receiver: receiver}, false
+}
+
+// Values returns the raw content
+func (c Content) Values() map[string]any {
+	return c.values
+}
+
+func (c Content) Has(key string) bool {
+	_, ok := c.values[key]
+	return ok
+}
+
+// Value returns the raw value for key
+func (c Content) Value(key string) (any, bool) {
+	v, ok := c.values[key]
+	return v, ok
+}
+
+// Invalid reports the value of key as invalid
+func (c Content) Invalid(key string, reason string) {
+	ReportMessageError(&MessageError{Receiver: describeReceiver(c.receiver), Key: key, Index: -1, Value: c.values[key], Reason: reason})
+}
+
+// Require reports all missing keys and returns true if all keys are present
+func (c Content) Require(keys ...string) bool {
+	ok := true
+
+	for _, key := range keys {
+		if !c.Has(key) {
+			c.Invalid(key, "missing")
+			ok = false
+		}
+	}
+
+	return ok
+}
+
+// Float returns the value of key converted to float64, ints and json.Number are converted
+func (c Content) Float(key string) (float64, bool) {
+	raw, ok := c.values[key]
+	if !ok {
+		return 0, false
+	}
+
+	f, ok := utils.ToFloat(raw)
+	if !ok {
+		c.Invalid(key, "expected number")
+	}
+
+	return f, ok
+}
+
+// Int returns the value of key converted to int, floats without fractional part and json.Number are converted
+func (c Content) Int(key string) (int, bool) {
+	raw, ok := c.values[key]
+	if !ok {
+		return 0, false
+	}
+
+	i, ok := utils.ToInt(raw)
+	if !ok {
+		c.Invalid(key, "expected integer")
+	}
+
+	return i, ok
+}
+
+func (c Content) Bool(key string) (bool, bool) {
+	raw, ok := c.values[key]
+	if !ok {
+		return false, false
+	}
+
+	b, ok := raw.(bool)
+	if !ok {
+		c.Invalid(key, "expected bool")
+	}
+
+	return b, ok
+}
+
+func (c Content) String(key string) (string, bool) {
+	raw, ok := c.values[key]
+	if !ok {
+		return "", false
+	}
+
+	s, ok := raw.(string)
+	if !ok {
+		c.Invalid(key, "expected string")
+	}
+
+	return s, ok
+}
+
+func decodeValue[T any](c Content, key string) (T, bool) {
+	raw, ok := c.values[key]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+
+	v, ok := raw.(T)
+	if !ok {
+		c.Invalid(key, fmt.Sprintf("expected %v", reflect.TypeOf((*T)(nil)).Elem()))
+	}
+
+	return v, ok
+}
+
+// Receive passes the value of key to f if present, the value is reported as invalid if f returns false.
+// Smoothed parameters can be passed directly: c.Receive("frequency", param.Receive)
+func (c Content) Receive(key string, f func(any) bool) {
+	if raw, ok := c.values[key]; ok && !f(raw) {
+		c.Invalid(key, "unsupported value")
+	}
+}
+
+// ControlFloat converts a control value to float64, invalid values are reported
+func ControlFloat(receiver any, index int, value any) (float64, bool) {
+	f, ok := utils.ToFloat(value)
+	if !ok {
+		ReportMessageError(&MessageError{Receiver: describeReceiver(receiver), Index: index, Value: value, Reason: "expected number"})
+	}
+
+	return f, ok
+}
+
+// ControlInt converts a control value to int, invalid values are reported
+func ControlInt(receiver any, index int, value any) (int, bool) {
+	i, ok := utils.ToInt(value)
+	if !ok {
+		ReportMessageError(&MessageError{Receiver: describeReceiver(receiver), Index: index, Value: value, Reason: "expected integer"})
+	}
+
+	return i, ok
+}
+
+// ReceiveControl passes a control value to f, the value is reported as invalid if f returns false
+func ReceiveControl(receiver any, index int, value any, f func(any) bool) {
+	if !f(value) {
+		ReportMessageError(&MessageError{Receiver: describeReceiver(receiver), Index: index, Value: value, Reason: "unsupported value"})
+	}
+}
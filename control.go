@@ -22,6 +22,8 @@ type Control interface {
 	AddControlOutputConnection(int, Control, int)
 	RemoveControlInputConnection(int, Control, int)
 	RemoveControlOutputConnection(int, Control, int)
+	CtrlInputConnections() map[int][]*ControlConnection
+	CtrlOutputConnections() map[int][]*ControlConnection
 	CtrlIConns([]*IConn) Control
 	CtrlIn(...any) Control
 	CtrlConnect(int, Control, int)
@@ -40,6 +42,8 @@ type BaseControl struct {
 	self           any
 	inConnections  map[int][]*ControlConnection
 	outConnections map[int][]*ControlConnection
+	offset         int
+	origin         any
 }
 
 func NewBaseControl() *BaseControl {
@@ -61,6 +65,17 @@ func (c *BaseControl) SetIdentifier(id string) {
 	c.identifier = id
 }
 
+// Origin returns what SetOrigin stored
+func (c *BaseControl) Origin() any {
+	return c.origin
+}
+
+// SetOrigin stores how the control was created, patch documents use it to export the
+// parameters an object was created with. The origin is released together with the control
+func (c *BaseControl) SetOrigin(origin any) {
+	c.origin = origin
+}
+
 func (c *BaseControl) CtrlNamed(name string) Control {
 	self := c.Self().(Control)
 	self.SetIdentifier(name)
@@ -88,10 +103,45 @@ func (c *BaseControl) ReceiveMessage(msg any) []*Message {
 	return nil
 }
 
+// Offset returns the sample offset of the control value or message that is being received,
+// values sent while receiving are sent at the same offset
+func (c *BaseControl) Offset() int {
+	return c.offset
+}
+
+// ReceiveControlValueAt receives a value at a sample offset, controls do not render audio so the
+// value is received right away and the offset is passed on with the values the control sends
+func (c *BaseControl) ReceiveControlValueAt(value any, index int, offset int) {
+	prevOffset := c.offset
+	c.offset = offset
+	c.Self().(ControlReceiver).ReceiveControlValue(value, index)
+	c.offset = prevOffset
+}
+
+// ReceiveMessageAt receives a message at a sample offset, like ReceiveControlValueAt
+func (c *BaseControl) ReceiveMessageAt(msg any, offset int) []*Message {
+	prevOffset := c.offset
+	c.offset = offset
+	msgs := c.Self().(MessageReceiver).ReceiveMessage(msg)
+	c.offset = prevOffset
+
+	return msgs
+}
+
 func (c *BaseControl) SendControlValue(value any, index int) {
+	c.SendControlValueAt(value, index, c.offset)
+}
+
+// SendControlValueAt sends a value at a sample offset within the current block. Modules that split
+// their block apply the value at the offset, other receivers receive the value right away
+func (c *BaseControl) SendControlValueAt(value any, index int, offset int) {
 	connections := c.outConnections[index]
 	for _, connection := range connections {
-		connection.Control.ReceiveControlValue(value, connection.Index)
+		if timed, ok := connection.Control.(TimedControlReceiver); ok && offset > 0 {
+			timed.ReceiveControlValueAt(value, connection.Index, offset)
+		} else {
+			connection.Control.ReceiveControlValue(value, connection.Index)
+		}
 	}
 }
 
@@ -149,6 +199,14 @@ func (c *BaseControl) RemoveControlOutputConnection(outputIndex int, receiver Co
 	}
 }
 
+func (c *BaseControl) CtrlInputConnections() map[int][]*ControlConnection {
+	return c.inConnections
+}
+
+func (c *BaseControl) CtrlOutputConnections() map[int][]*ControlConnection {
+	return c.outConnections
+}
+
 func (c *BaseControl) CtrlAddTo(p Patch) Control {
 	return p.AddControl(c.Self().(Control))
 }
@@ -169,6 +227,9 @@ func (c *BaseControl) CtrlConnect(outIndex int, receiver Control, inIndex int) {
 	self := c.Self().(Control)
 	self.AddControlOutputConnection(outIndex, receiver, inIndex)
 	receiver.AddControlInputConnection(inIndex, self, outIndex)
+
+	controlGraphChanged(self)
+	controlGraphChanged(receiver)
 }
 
 func (c *BaseControl) CtrlDisconnect() {
@@ -177,14 +238,18 @@ func (c *BaseControl) CtrlDisconnect() {
 	for inIndex, inConns := range c.inConnections {
 		for _, inConn := range inConns {
 			inConn.Control.RemoveControlOutputConnection(inConn.Index, self, inIndex)
+			controlGraphChanged(inConn.Control)
 		}
 	}
 
 	for outIndex, outConns := range c.outConnections {
 		for _, outConn := range outConns {
 			outConn.Control.RemoveControlInputConnection(outConn.Index, self, outIndex)
+			controlGraphChanged(outConn.Control)
 		}
 	}
+
+	controlGraphChanged(self)
 }
 
 type ControlThru struct {
@@ -192,7 +257,10 @@ type ControlThru struct {
 }
 
 func NewControlThru() *ControlThru {
-	return &ControlThru{BaseControl: NewBaseControl()}
+	ct := &ControlThru{BaseControl: NewBaseControl()}
+	ct.SetSelf(ct)
+
+	return ct
 }
 
 func (ct *ControlThru) ReceiveControlValue(value any, index int) {
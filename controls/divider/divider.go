@@ -21,6 +21,13 @@ func New(n int) *Divider {
 	return d
 }
 
+// Parameters describes the bang that is counted
+func (d *Divider) Parameters() []muse.Parameter {
+	return []muse.Parameter{
+		{Name: "bang", ControlIndex: 0, Type: muse.ParameterBang},
+	}
+}
+
 func (d *Divider) ReceiveControlValue(value any, index int) {
 	if index == 0 && muse.IsBang(value) {
 		d.cnt++
@@ -35,6 +35,14 @@ func New[T any](gen genny.Generator[T], useTick bool) *Gen[T] {
 	return NewWithFunctions(gen, useTick, nil, nil)
 }
 
+// Parameters describes the bang that generates the next value and the bang that resets the generator
+func (g *Gen[T]) Parameters() []muse.Parameter {
+	return []muse.Parameter{
+		{Name: "bang", ControlIndex: 0, MessageKey: "bang", Type: muse.ParameterBang},
+		{Name: "reset", ControlIndex: 1, Type: muse.ParameterBang},
+	}
+}
+
 func (g *Gen[T]) bang() {
 	g.SendControlValue(g.gen.Generate(), 0)
 }
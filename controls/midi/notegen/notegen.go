@@ -43,6 +43,11 @@ func New(channel uint8, noteGen genny.Generator[notes.Note], velocityGen genny.G
 	return ng
 }
 
+// SetSeed seeds the generators that have a random source
+func (ng *NoteGen) SetSeed(seed uint64) {
+	muse.SeedObjects(seed, ng.noteGen, ng.velocityGen, ng.durationGen)
+}
+
 func (ng *NoteGen) hasActiveNote(key uint8) bool {
 	for it := ng.activeNotes.Iterator(true); !it.Finished(); {
 		v, _ := it.Next()
@@ -55,6 +60,13 @@ func (ng *NoteGen) hasActiveNote(key uint8) bool {
 	return false
 }
 
+// Parameters describes the bang that plays the next note
+func (ng *NoteGen) Parameters() []muse.Parameter {
+	return []muse.Parameter{
+		{Name: "bang", ControlIndex: 0, Type: muse.ParameterBang},
+	}
+}
+
 func (ng *NoteGen) ReceiveControlValue(value any, index int) {
 	if muse.IsBang(value) {
 		if ng.durationGen.Done() {
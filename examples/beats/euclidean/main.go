@@ -22,15 +22,18 @@ import (
 	"github.com/almerlucke/muse/modules/effects/pingpong"
 	"github.com/almerlucke/muse/synths/drums"
 	"github.com/almerlucke/muse/utils/notes"
+	"github.com/almerlucke/muse/utils/rand"
 	"github.com/almerlucke/muse/utils/timing"
 	"github.com/almerlucke/sndfile"
 	"github.com/google/uuid"
 	"gitlab.com/gomidi/midi/v2"
 	_ "gitlab.com/gomidi/midi/v2/drivers/rtmididrv"
 	"log"
-	"math/rand"
 )
 
+// random is the random source of the example, it has a fixed seed so every run is the same
+var random = rand.NewRandWithSeed(1)
+
 func addDrumTrack(p muse.Patch, polyIdentifier string, sound genny.Generator[string], speed genny.Generator[float64], amp genny.Generator[float64], steps genny.Generator[*swing.Step], bpm int, noteDivision int) {
 	identifier := uuid.New().String()
 
@@ -87,7 +90,7 @@ func main() {
 	snareConst := constant.New("snare1")
 	snareLow := 0.5
 	snareHigh := 3.5
-	snareRand := function.New(func() float64 { return rand.Float64()*(snareHigh-snareLow) + snareLow })
+	snareRand := function.New(func() float64 { return random.RandFloat()*(snareHigh-snareLow) + snareLow })
 
 	addDrumTrack(root, "drums", bucket.NewLoop(bucket.Random, "kick1", "kick2"), function.NewRandom(0.5, 1.25), constant.New(0.5), kickRhythm, bpm, 1)
 	addDrumTrack(root, "drums", hihatConst, function.NewRandom(1.0, 2.0), constant.New(0.1), hihatRhythm, bpm, 4)
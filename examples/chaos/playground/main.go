@@ -12,7 +12,7 @@ import (
 	"github.com/almerlucke/genny/template"
 	"github.com/almerlucke/muse/modules/effects/chorus"
 
-	"math/rand"
+	"github.com/almerlucke/muse/utils/rand"
 
 	"github.com/almerlucke/muse"
 	"github.com/almerlucke/muse/controls/gen"
@@ -27,6 +27,9 @@ import (
 	"github.com/almerlucke/muse/modules/waveshaper"
 )
 
+// random is the random source of the example, it has a fixed seed so every run is the same
+var random = rand.NewRandWithSeed(1)
+
 type ChaosVoice struct {
 	*muse.BasePatch
 	verhulst         *chaos.Verhulst
@@ -109,7 +112,7 @@ func (v *ChaosVoice) Note(duration float64, amplitude float64, msg any, config *
 		v.panner.SetPan(p.(float64))
 	}
 
-	v.iter.SetValues([]float64{rand.Float64()})
+	v.iter.SetValues([]float64{random.RandFloat()})
 	v.ampEnv.TriggerWithDuration(duration, amplitude)
 	v.filterEnv.TriggerWithDuration(duration, 1.0)
 }
@@ -126,7 +129,7 @@ func (v *ChaosVoice) Clear() {
 }
 
 func randMinMax(min float64, max float64) float64 {
-	return rand.Float64()*(max-min) + min
+	return random.RandFloat()*(max-min) + min
 }
 
 func main() {
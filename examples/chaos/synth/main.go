@@ -2,7 +2,7 @@ package main
 
 import (
 	"github.com/almerlucke/muse/modules/effects/chorus"
-	"math/rand"
+	"github.com/almerlucke/muse/utils/rand"
 
 	adsrc "github.com/almerlucke/genny/float/envelopes/adsr"
 	"github.com/almerlucke/genny/float/interp"
@@ -28,6 +28,9 @@ import (
 	"github.com/almerlucke/muse/modules/waveshaper"
 )
 
+// random is the random source of the example, it has a fixed seed so every run is the same
+var random = rand.NewRandWithSeed(1)
+
 type ChaosVoice struct {
 	*muse.BasePatch
 	verhulst         *chaos.Verhulst
@@ -110,7 +113,7 @@ func (v *ChaosVoice) Note(duration float64, amplitude float64, msg any, config *
 		v.panner.SetPan(p.(float64))
 	}
 
-	v.iter.SetValues([]float64{rand.Float64()})
+	v.iter.SetValues([]float64{random.RandFloat()})
 
 	v.ampEnv.TriggerWithDuration(duration, amplitude)
 	v.filterEnv.TriggerWithDuration(duration, 1.0)
@@ -128,7 +131,7 @@ func (v *ChaosVoice) Clear() {
 }
 
 func randMinMax(min float64, max float64) float64 {
-	return rand.Float64()*(max-min) + min
+	return random.RandFloat()*(max-min) + min
 }
 
 func main() {
@@ -2,23 +2,26 @@ package main
 
 import (
 	"math"
-	"math/rand"
 	"sort"
 
 	"github.com/almerlucke/muse/plot"
+	"github.com/almerlucke/muse/utils/rand"
 	"gonum.org/v1/plot/plotter"
 )
 
+// random is the random source of the example, it has a fixed seed so every run is the same
+var random = rand.NewRandWithSeed(1)
+
 // distribution 0 -> center
 // distribution 1 -> center +- width random 100%
 func randCenter(center float64, width float64, distribution float64) float64 {
 	mult := 0.0
 
 	if distribution >= 0.001 {
-		mult = 1.0 - math.Pow(rand.Float64(), distribution)
+		mult = 1.0 - math.Pow(random.RandFloat(), distribution)
 	}
 
-	left := rand.Intn(2)
+	left := random.Intn(2)
 	out := 0.0
 	if left == 0 {
 		out = center - width*0.5*mult
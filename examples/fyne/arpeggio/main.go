@@ -99,7 +99,7 @@ func main() {
 	root := muse.New(2)
 
 	ampEnvSetting := adsrc.NewSetting(1.0, 5.0, 0.2, 5.0, 0.0, 1300.0)
-	ampEnvControl := adsrctrl.NewADSRControl("Amplitude ADSR", ampEnvSetting)
+	ampEnvControl := adsrctrl.NewADSRControl("Amplitude ADSR", ampEnvSetting).PostTo(root)
 
 	voices1 := []polyphony.Voice{}
 	for i := 0; i < 20; i++ {
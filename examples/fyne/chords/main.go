@@ -94,7 +94,7 @@ func main() {
 	root := muse.New(1)
 
 	ampEnvSetting := adsrc.NewSetting(1.0, 5.0, 0.3, 5.0, 0.1, 1300.0)
-	ampEnvControl := adsrctrl.NewADSRControl("Amplitude ADSR", ampEnvSetting)
+	ampEnvControl := adsrctrl.NewADSRControl("Amplitude ADSR", ampEnvSetting).PostTo(root)
 
 	voices := []polyphony.Voice{}
 	for i := 0; i < 20; i++ {
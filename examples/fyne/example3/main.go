@@ -135,8 +135,8 @@ func main() {
 
 	ampEnvSetting := adsrc.NewSetting(1.0, 5.0, 0.2, 5.0, 0.0, 1500.0)
 	filterEnvSetting := adsrc.NewSetting(1.0, 5.0, 0.2, 5.0, 0.0, 1500.0)
-	ampEnvControl := adsrctrl.NewADSRControl("Amplitude ADSR", ampEnvSetting)
-	filterEnvControl := adsrctrl.NewADSRControl("Filter ADSR", filterEnvSetting)
+	ampEnvControl := adsrctrl.NewADSRControl("Amplitude ADSR", ampEnvSetting).PostTo(root)
+	filterEnvControl := adsrctrl.NewADSRControl("Filter ADSR", filterEnvSetting).PostTo(root)
 
 	voices := []polyphony.Voice{}
 	for i := 0; i < 40; i++ {
@@ -267,7 +267,7 @@ func main() {
 	// if deskCanvas, ok := w.Canvas().(desktop.Canvas); ok {
 	// 	deskCanvas.SetOnKeyDown(func(k *fyne.KeyEvent) {
 	// 		if f, ok := keyMap[string(k.Name)]; ok {
-	// 			poly.ReceiveMessage(map[string]any{
+	// 			_ = root.PostMessageTo(poly, map[string]any{
 	// 				"command":   "trigger",
 	// 				"noteOn":    string(k.Name),
 	// 				"amplitude": 1.0,
@@ -282,7 +282,7 @@ func main() {
 
 	// 	deskCanvas.SetOnKeyUp(func(k *fyne.KeyEvent) {
 	// 		if _, ok := keyMap[string(k.Name)]; ok {
-	// 			poly.ReceiveMessage(map[string]any{
+	// 			_ = root.PostMessageTo(poly, map[string]any{
 	// 				"command": "trigger",
 	// 				"noteOff": string(k.Name),
 	// 			})
@@ -300,7 +300,10 @@ func main() {
 					_ = root.StopAudio()
 				}),
 				// widget.NewButton("Notes Off", func() {
-				// 	poly.(*polyphony.Polyphony).AllNotesOff()
+				// 	_ = root.Post(func(*muse.Muse) error {
+				// 		poly.(*polyphony.Polyphony).AllNotesOff()
+				// 		return nil
+				// 	})
 				// }),
 			),
 			container.NewHBox(
@@ -11,10 +11,10 @@ import (
 	"github.com/almerlucke/genny/template"
 	"github.com/almerlucke/genny/transform"
 	"github.com/almerlucke/muse/modules/effects/chorus"
+	"github.com/almerlucke/muse/utils/rand"
 	"github.com/almerlucke/sndfile"
 	"github.com/almerlucke/sndfile/writer"
 	"log"
-	"math/rand"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
@@ -42,6 +42,9 @@ import (
 	"github.com/almerlucke/muse/modules/waveshaper"
 )
 
+// random is the random source of the example, it has a fixed seed so every run is the same
+var random = rand.NewRandWithSeed(1)
+
 type TestVoice struct {
 	*muse.BasePatch
 	ampEnv           *adsr.ADSR
@@ -145,7 +148,7 @@ func addDrumTrack(p muse.Patch, moduleName string, soundBuffer sndfile.SoundFile
 	p.AddMessenger(stepper.NewStepper(swing.New(tempo, division, steps), []string{identifier}))
 
 	p.AddMessenger(banger.NewTemplateBang([]string{moduleName}, template.Template{
-		"speed": function.New(func() float64 { return rand.Float64()*(highSpeed-lowSpeed) + lowSpeed }),
+		"speed": function.New(func() float64 { return random.RandFloat()*(highSpeed-lowSpeed) + lowSpeed }),
 		"bang":  true,
 	}).MsgrNamed(identifier))
 
@@ -162,8 +165,8 @@ func main() {
 
 	ampEnvSetting := adsrc.NewSetting(1.0, 5.0, 0.2, 37.0, 0.0, 1630.0)
 	filterEnvSetting := adsrc.NewSetting(1.0, 5.0, 0.43, 50.0, 0.0, 1700.0)
-	ampEnvControl := adsrctrl.NewADSRControl("Amplitude ADSR", ampEnvSetting)
-	filterEnvControl := adsrctrl.NewADSRControl("Filter ADSR", filterEnvSetting)
+	ampEnvControl := adsrctrl.NewADSRControl("Amplitude ADSR", ampEnvSetting).PostTo(root)
+	filterEnvControl := adsrctrl.NewADSRControl("Filter ADSR", filterEnvSetting).PostTo(root)
 
 	var voices []polyphony.Voice
 	for i := 0; i < 20; i++ {
@@ -333,7 +336,10 @@ func main() {
 					_ = root.StopAudio()
 				}),
 				// widget.NewButton("Notes Off", func() {
-				// 	poly.(*polyphony.Polyphony).AllNotesOff()
+				// 	_ = root.Post(func(*muse.Muse) error {
+				// 		poly.(*polyphony.Polyphony).AllNotesOff()
+				// 		return nil
+				// 	})
 				// }),
 			),
 			container.NewHBox(
@@ -10,7 +10,6 @@ import (
 	"github.com/almerlucke/genny/float/shape/shapers/mirror"
 	"github.com/almerlucke/genny/float/shape/shapers/series"
 	"math"
-	"math/rand"
 
 	"github.com/almerlucke/genny/float/iter/updaters/chaos"
 	"github.com/almerlucke/muse"
@@ -21,6 +20,9 @@ import (
 	museRand "github.com/almerlucke/muse/utils/rand"
 )
 
+// random is the random source of the example, it has a fixed seed so every run is the same
+var random = museRand.NewRandWithSeed(1)
+
 type SFParam struct {
 	onset         int64
 	duration      float64
@@ -137,7 +139,7 @@ func (s *SFSource) New(cfg any) granular.Source {
 }
 
 func randBetween(min float64, max float64) float64 {
-	return min + rand.Float64()*(max-min)
+	return min + random.RandFloat()*(max-min)
 }
 
 func quantize(v float64, binSize float64) float64 {
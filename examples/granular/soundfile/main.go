@@ -4,7 +4,6 @@ import (
 	"github.com/almerlucke/sndfile"
 	"log"
 	"math"
-	"math/rand"
 
 	"github.com/almerlucke/muse"
 	"github.com/almerlucke/muse/modules/granular"
@@ -13,6 +12,9 @@ import (
 	museRand "github.com/almerlucke/muse/utils/rand"
 )
 
+// random is the random source of the example, it has a fixed seed so every run is the same
+var random = museRand.NewRandWithSeed(1)
+
 type LookupMode int
 
 const (
@@ -170,7 +172,7 @@ func (sf *SFSourceFactory) New(_ any) granular.Source {
 }
 
 func randBetween(min float64, max float64) float64 {
-	return min + rand.Float64()*(max-min)
+	return min + random.RandFloat()*(max-min)
 }
 
 func quantize(v float64, binSize float64) float64 {
@@ -223,7 +225,7 @@ func (pgen *SFParameterGenerator) Next(timestamp int64, config *muse.Configurati
 	p.lookupMode = Wrap
 	p.onset = int64(pgen.onsetClustering.Rand() * 0.01 * config.SampleRate)
 
-	if rand.Float64() < pgen.reversePlayChance {
+	if random.RandFloat() < pgen.reversePlayChance {
 		p.speed *= -1.0
 	}
 
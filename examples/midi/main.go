@@ -44,12 +44,7 @@ func main() {
 
 	ng := notegen.New(0, noteGen, velocityGen, durationGen, send).CtrlAddTo(root).CtrlIn(timer.NewControl(500, nil).CtrlAddTo(root)).(*notegen.NoteGen)
 
-	defer func() {
-		ng.NotesOff()
-		root.MidiStop()
-	}()
-
-	root.MidiStart()
+	defer ng.NotesOff()
 
 	_ = root.RenderAudio()
 }
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"log"
+
+	"github.com/almerlucke/muse"
+	"github.com/almerlucke/muse/patchdoc"
+	_ "github.com/almerlucke/muse/patchdoc/builtin"
+)
+
+func main() {
+	doc, err := patchdoc.ReadFile("patch.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	root := muse.New(doc.Outputs)
+
+	err = doc.BuildInto(root, patchdoc.DefaultRegistry)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_ = root.RenderAudio()
+}
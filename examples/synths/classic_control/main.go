@@ -16,7 +16,7 @@ import (
 	"github.com/almerlucke/muse/modules/filters/rbj"
 	"github.com/almerlucke/sndfile"
 
-	"math/rand"
+	"github.com/almerlucke/muse/utils/rand"
 	"strings"
 
 	"github.com/almerlucke/muse"
@@ -34,6 +34,9 @@ import (
 	"github.com/almerlucke/muse/utils/notes"
 )
 
+// random is the random source of the example, it has a fixed seed so every run is the same
+var random = rand.NewRandWithSeed(1)
+
 type ClassicSynth struct {
 	*muse.BasePatch
 	controls         *controls.Group
@@ -232,7 +235,7 @@ func addDrumTrack(p muse.Patch, polyName string, sounds []string, tempo int, div
 		"duration":  0.0,
 		"amplitude": amp,
 		"message": template.Template{
-			"speed": function.New(func() float64 { return rand.Float64()*(highSpeed-lowSpeed) + lowSpeed }),
+			"speed": function.New(func() float64 { return random.RandFloat()*(highSpeed-lowSpeed) + lowSpeed }),
 			"sound": sequence.NewLoop(sounds...),
 		},
 	}).MsgrNamed(identifier))
@@ -21,6 +21,7 @@ import (
 
 type ClassicSynth struct {
 	*muse.BasePatch
+	root             *muse.Muse
 	controls         *controls.Group
 	ampEnvSetting    *adsr.Setting
 	filterEnvSetting *adsr.Setting
@@ -29,9 +30,10 @@ type ClassicSynth struct {
 	chorus2          *chorus.Chorus
 }
 
-func NewClassicSynth(bpm float64) *ClassicSynth {
+func NewClassicSynth(root *muse.Muse, bpm float64) *ClassicSynth {
 	synth := &ClassicSynth{
 		BasePatch: muse.NewPatch(0, 2),
+		root:      root,
 		controls:  controls.NewGroup("group.main", "Classic Synth"),
 	}
 
@@ -112,8 +114,17 @@ func (cs *ClassicSynth) SetupControls(w fyne.Window) {
 	cs.controls.AddListenerDeep(cs)
 }
 
+// ControlChanged is called on the UI goroutine, the change is applied on the audio thread
 func (cs *ClassicSynth) ControlChanged(ctrl controls.Control, oldValue any, newValue any, setter any) {
 	id := ctrl.Identifier()
+
+	_ = cs.root.Post(func(*muse.Muse) error {
+		cs.applyControl(id, newValue)
+		return nil
+	})
+}
+
+func (cs *ClassicSynth) applyControl(id string, newValue any) {
 	components := strings.Split(id, ".")
 	route := components[0]
 
@@ -160,7 +171,7 @@ func (cs *ClassicSynth) ReceiveMessage(msg any) []*muse.Message {
 			if ctrl.Type() == controls.SliderType {
 				// Change control from message will take a lot of cpy because it updates fyne UI elements, which is really inefficient
 				// ctrl.(*control.SliderControl).Set(v.(float64), nil)
-				cs.ControlChanged(ctrl, 0, v.(float64), nil)
+				cs.applyControl(k, v.(float64))
 			}
 		}
 	}
@@ -174,7 +185,7 @@ func main() {
 	root := muse.New(2)
 
 	bpm := 100.0
-	synth := NewClassicSynth(bpm)
+	synth := NewClassicSynth(root, bpm)
 
 	synth.AddTo(root)
 	root.In(synth, synth, 1)
@@ -252,7 +263,7 @@ func main() {
 		case msg.GetNoteStart(&ch, &key, &vel):
 			velocity := float64(vel) / 127.0
 			log.Printf("velocity %v", velocity)
-			synth.Synth.ReceiveMessage(map[string]any{
+			_ = root.PostMessageTo(synth.Synth, map[string]any{
 				"command":   "trigger",
 				"noteOn":    fmt.Sprintf("%d", key),
 				"amplitude": velocity,
@@ -263,7 +274,7 @@ func main() {
 
 			fmt.Printf("starting note %s on channel %v with velocity %v\n", midi.Note(key), ch, vel)
 		case msg.GetNoteEnd(&ch, &key):
-			synth.Synth.ReceiveMessage(map[string]any{
+			_ = root.PostMessageTo(synth.Synth, map[string]any{
 				"command": "trigger",
 				"noteOff": fmt.Sprintf("%d", key),
 			})
@@ -0,0 +1,244 @@
+package muse
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/almerlucke/sndfile"
+	"github.com/almerlucke/sndfile/writer"
+)
+
+// FileAudioBackend reads the input channels from a sound file and writes the output channels to a sound file,
+// the callback runs as fast as possible. The stream ends with the input file or after NumSeconds, without input
+// file and NumSeconds it runs until stopped. Input channels the file does not have are silent
+type FileAudioBackend struct {
+	InputPath  string
+	OutputPath string
+	FileFormat writer.FileFormat
+	NumSeconds float64
+	input      *sndfile.SoundFile
+	output     *writer.Writer
+	params     AudioStreamParameters
+	callback   AudioCallback
+	position   int64
+	stop       chan struct{}
+	done       chan struct{}
+	wg         sync.WaitGroup
+	err        error
+}
+
+// NewFileAudioBackend returns a file backend, inputPath or outputPath can be empty to run without input or output
+func NewFileAudioBackend(inputPath string, outputPath string, fileFormat writer.FileFormat) *FileAudioBackend {
+	return &FileAudioBackend{
+		InputPath:  inputPath,
+		OutputPath: outputPath,
+		FileFormat: fileFormat,
+	}
+}
+
+// Devices returns a single file device with the channels of the input file, or any channel count without
+func (fb *FileAudioBackend) Devices() ([]AudioDevice, error) {
+	device := AudioDevice{
+		Name:              "file",
+		MaxInputChannels:  256,
+		MaxOutputChannels: 256,
+		DefaultSampleRate: DefaultSamplerate,
+		SampleRates:       CommonSampleRates,
+	}
+
+	if fb.InputPath != "" {
+		input, err := sndfile.NewSoundFile(fb.InputPath)
+		if err != nil {
+			return nil, err
+		}
+
+		device.Name = fb.InputPath
+		device.MaxInputChannels = input.NumChannels()
+		device.DefaultSampleRate = input.SampleRate()
+		device.SampleRates = []float64{input.SampleRate()}
+	}
+
+	return []AudioDevice{device}, nil
+}
+
+func (fb *FileAudioBackend) Open(params AudioStreamParameters, callback AudioCallback) error {
+	if params.SampleRate <= 0 || params.BufferSize <= 0 {
+		return errors.New("file audio backend needs a sample rate and buffer size")
+	}
+
+	if fb.InputPath != "" {
+		input, err := sndfile.NewSoundFile(fb.InputPath)
+		if err != nil {
+			return err
+		}
+
+		if input.SampleRate() != params.SampleRate {
+			return fmt.Errorf("input file sample rate %v does not match stream sample rate %v", input.SampleRate(), params.SampleRate)
+		}
+
+		fb.input = input
+	}
+
+	if fb.OutputPath != "" && params.NumOutputs > 0 {
+		output, err := writer.New(fb.OutputPath, fb.FileFormat, params.NumOutputs, params.SampleRate,
+			writer.NewNoConverter(params.BufferSize))
+		if err != nil {
+			return err
+		}
+
+		fb.output = output
+	}
+
+	fb.params = params
+	fb.callback = callback
+	fb.position = 0
+
+	return nil
+}
+
+// numFrames returns the length of the stream, -1 if the stream runs until stopped
+func (fb *FileAudioBackend) numFrames() int64 {
+	if fb.NumSeconds > 0 {
+		return int64(fb.NumSeconds * fb.params.SampleRate)
+	}
+
+	if fb.input != nil {
+		return fb.input.NumFrames()
+	}
+
+	return -1
+}
+
+func (fb *FileAudioBackend) Start() error {
+	if fb.callback == nil {
+		return errors.New("file audio stream is not open")
+	}
+
+	if fb.stop != nil {
+		return nil
+	}
+
+	fb.stop = make(chan struct{})
+	fb.done = nil
+
+	if fb.numFrames() >= 0 {
+		fb.done = make(chan struct{})
+	}
+
+	fb.wg.Add(1)
+
+	go fb.run(fb.stop, fb.done)
+
+	return nil
+}
+
+func (fb *FileAudioBackend) run(stop chan struct{}, done chan struct{}) {
+	defer fb.wg.Done()
+
+	if done != nil {
+		defer close(done)
+	}
+
+	bufferSize := fb.params.BufferSize
+	in := allocateAudioBuffers(fb.params.NumInputs, bufferSize)
+	out := allocateAudioBuffers(fb.params.NumOutputs, bufferSize)
+	interleaved := make([]float32, bufferSize*fb.params.NumOutputs)
+	numFrames := fb.numFrames()
+
+	for numFrames < 0 || fb.position < numFrames {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		fb.readInput(in)
+		fb.callback(in, out)
+
+		// The last block only writes the frames up to the end of the stream
+		blockFrames := int64(bufferSize)
+		if numFrames >= 0 {
+			blockFrames = min(blockFrames, numFrames-fb.position)
+		}
+
+		fb.position += blockFrames
+
+		if fb.output != nil {
+			end := numFrames >= 0 && fb.position >= numFrames
+			if err := fb.output.Write(interleave(interleaved, out, int(blockFrames)), end); err != nil {
+				fb.err = err
+				return
+			}
+		}
+	}
+}
+
+// interleave interleaves the first numFrames frames of the channels into buf
+func interleave(buf []float32, channels [][]float32, numFrames int) []float32 {
+	numChannels := len(channels)
+
+	for c, channel := range channels {
+		for i := 0; i < numFrames; i++ {
+			buf[i*numChannels+c] = channel[i]
+		}
+	}
+
+	return buf[:numFrames*numChannels]
+}
+
+// readInput copies the next block of the input file to in
+func (fb *FileAudioBackend) readInput(in [][]float32) {
+	for c, buf := range in {
+		clear(buf)
+
+		if fb.input == nil || c >= fb.input.NumChannels() {
+			continue
+		}
+
+		channel := fb.input.Buffer(c, 0)
+
+		for i := range buf {
+			if pos := fb.position + int64(i); pos < int64(len(channel)) {
+				buf[i] = float32(channel[pos])
+			}
+		}
+	}
+}
+
+// Stop stops the stream, a stream that ended by itself can not be started again
+func (fb *FileAudioBackend) Stop() error {
+	if fb.stop != nil {
+		close(fb.stop)
+		fb.wg.Wait()
+		fb.stop = nil
+	}
+
+	return nil
+}
+
+// Close stops the stream and closes the output file
+func (fb *FileAudioBackend) Close() error {
+	err := fb.Stop()
+
+	if fb.output != nil {
+		err = errors.Join(err, fb.output.Close())
+		fb.output = nil
+	}
+
+	fb.input = nil
+	fb.callback = nil
+
+	return err
+}
+
+// Done is closed when the stream has ended, it is nil before the stream is started and for a stream that runs
+// until stopped
+func (fb *FileAudioBackend) Done() <-chan struct{} {
+	return fb.done
+}
+
+// Err returns the error that ended the stream
+func (fb *FileAudioBackend) Err() error {
+	return fb.err
+}
@@ -12,8 +12,10 @@ require (
 	github.com/fogleman/gg v1.3.0
 	github.com/gordonklaus/portaudio v0.0.0-20230709114228-aafa478834f5
 	gitlab.com/gomidi/midi v1.23.7
+	gitlab.com/gomidi/midi/v2 v2.1.7
 	gitlab.com/gomidi/rtmididrv v0.15.0
 	gonum.org/v1/plot v0.14.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -44,12 +46,10 @@ require (
 	github.com/stretchr/testify v1.8.4 // indirect
 	github.com/tevino/abool v1.2.0 // indirect
 	github.com/yuin/goldmark v1.5.5 // indirect
-	gitlab.com/gomidi/midi/v2 v2.1.7 // indirect
 	golang.org/x/image v0.15.0 // indirect
 	golang.org/x/mobile v0.0.0-20230531173138-3c911d8e3eda // indirect
 	golang.org/x/net v0.17.0 // indirect
 	golang.org/x/sys v0.13.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 	honnef.co/go/js/dom v0.0.0-20210725211120-f030747120f2 // indirect
 )
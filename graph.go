@@ -0,0 +1,308 @@
+package muse
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/almerlucke/muse/buffer"
+	"github.com/almerlucke/muse/utils/workers"
+)
+
+// ErrCycle is returned when a connection would create a cycle in the graph
+var ErrCycle = errors.New("connection creates a cycle")
+
+// graphMember is implemented by all modules through BaseModule, it lets a module tell the
+// patch that contains it that its connections changed
+type graphMember interface {
+	setParent(p *BasePatch)
+	parentPatch() *BasePatch
+	graphChanged()
+}
+
+// setParent is called by the patch a module is added to
+func (m *BaseModule) setParent(p *BasePatch) {
+	m.parent = p
+}
+
+func (m *BaseModule) parentPatch() *BasePatch {
+	return m.parent
+}
+
+// graphChanged tells the patch that contains the module to recompile its schedule
+func (m *BaseModule) graphChanged() {
+	if m.parent != nil {
+		m.parent.invalidate()
+	}
+}
+
+// controlGraphChanged tells the patch of a control that is also a module that its control
+// connections changed, control connections decide how a patch is divided in stages
+func controlGraphChanged(c Control) {
+	if member, ok := c.(graphMember); ok {
+		member.graphChanged()
+	}
+}
+
+// invalidate increments the graph version of the patch so it recompiles its schedule before
+// the next block. The parent patches are invalidated as well, other patches keep their schedule
+func (p *BasePatch) invalidate() {
+	p.graphVersion.Add(1)
+	p.BaseModule.graphChanged()
+}
+
+func describeModule(m Module) string {
+	if id := m.Identifier(); id != "" {
+		return id
+	}
+
+	return fmt.Sprintf("%T", m)
+}
+
+// isDownstream checks if target can be reached from start by following output connections,
+// feedback connections are skipped because they are allowed to form cycles
+func isDownstream(start Module, target Module) bool {
+	visited := map[Module]bool{}
+	stack := []Module{start}
+
+	for len(stack) > 0 {
+		m := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if m == target {
+			return true
+		}
+
+		if visited[m] {
+			continue
+		}
+
+		visited[m] = true
+
+		for i := 0; i < m.NumOutputs(); i++ {
+			for _, conn := range m.OutputAtIndex(i).Connections {
+				if !conn.IsFeedback() {
+					stack = append(stack, conn.Module)
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// schedules checks if the patch schedules a module that is connected to sub module to but is not
+// part of the patch. Modules that were never added to a patch are scheduled like sub modules, like
+// they used to be pulled by the modules they are connected to. Modules of other patches are
+// synthesized by their own patch at an unknown moment, the connection is reported and skipped
+func (p *BasePatch) schedules(m Module, to Module) bool {
+	if member, ok := m.(graphMember); ok && member.parentPatch() != nil {
+		log.Printf("muse: %s is connected to %s but belongs to another patch, connect patches through their inputs and outputs", describeModule(m), describeModule(to))
+		return false
+	}
+
+	return true
+}
+
+// feedbackTap copies the output of a feedback connection sender at the end of each block
+type feedbackTap struct {
+	output buffer.Buffer
+	tap    buffer.Buffer
+}
+
+// compile creates a flat execution order of all sub modules that need to be synthesized,
+// every module comes after the modules connected to its inputs. Feedback connections do
+// not determine the order, their senders are scheduled after all other modules. Modules
+// that are not connected to an output and do not need to be synthesized on their own are left out.
+// Connected modules that were never added to a patch are scheduled as well, see schedules
+func (p *BasePatch) compile(version uint64) {
+	members := make(map[Module]bool, len(p.subModules))
+	for _, m := range p.subModules {
+		members[m] = true
+	}
+
+	// Input modules are synthesized before the schedule runs
+	for _, input := range p.inputModules {
+		members[input] = false
+	}
+
+	visited := make(map[Module]bool, len(p.subModules))
+	schedule := p.schedule[:0]
+
+	var (
+		feedbackSenders []Module
+		visit           func(Module)
+	)
+
+	visit = func(m Module) {
+		if visited[m] {
+			return
+		}
+
+		visited[m] = true
+
+		for i := 0; i < m.NumInputs(); i++ {
+			for _, conn := range m.InputAtIndex(i).Connections {
+				if !members[conn.Module] {
+					if _, ok := members[conn.Module]; ok || !p.schedules(conn.Module, m) {
+						continue
+					}
+
+					members[conn.Module] = true
+				}
+
+				if conn.IsFeedback() {
+					feedbackSenders = append(feedbackSenders, conn.Module)
+				} else {
+					visit(conn.Module)
+				}
+			}
+		}
+
+		schedule = append(schedule, m)
+	}
+
+	for _, m := range p.subModules {
+		if members[m] && m.MustSynthesize() {
+			visit(m)
+		}
+	}
+
+	for _, output := range p.outputModules {
+		visit(output)
+	}
+
+	// Senders of feedback connections can add new feedback senders
+	for len(feedbackSenders) > 0 {
+		m := feedbackSenders[0]
+		feedbackSenders = feedbackSenders[1:]
+		visit(m)
+	}
+
+	taps := p.taps[:0]
+
+	for _, m := range schedule {
+		if _, ok := m.(Patch); ok {
+			// The output modules of a sub patch keep their own taps
+			continue
+		}
+
+		for i := 0; i < m.NumOutputs(); i++ {
+			output := m.OutputAtIndex(i)
+			for _, conn := range output.Connections {
+				if conn.IsFeedback() {
+					taps = append(taps, feedbackTap{output: output.Buffer, tap: conn.Tap})
+				}
+			}
+		}
+	}
+
+	p.schedule = schedule
+	p.taps = taps
+	p.scheduleVersion = version
+
+	if p.pool != nil {
+		p.stages.compile(schedule, members)
+	}
+}
+
+// stages divides a schedule into groups of modules that do not depend on each other
+// and can be synthesized in parallel, modules within a stage keep their schedule order
+type stages struct {
+	modules []Module
+	ends    []int
+	current []Module
+	batch   *workers.Batch
+}
+
+// hasControlOutputs checks if a control sends control values to other controls
+func hasControlOutputs(c Control) bool {
+	for _, conns := range c.CtrlOutputConnections() {
+		if len(conns) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compile creates the stages for a schedule. The schedule is split in segments at modules
+// that send control values, these run in a stage of their own so control values arrive in
+// the same order as with sequential rendering. Within a segment a module is placed one
+// stage after the last stage of the modules it depends on
+func (st *stages) compile(schedule []Module, members map[Module]bool) {
+	st.modules = st.modules[:0]
+	st.ends = st.ends[:0]
+
+	levels := map[Module]int{}
+	segment := make([]Module, 0, len(schedule))
+	maxLevel := -1
+
+	flush := func() {
+		for level := 0; level <= maxLevel; level++ {
+			for _, m := range segment {
+				if levels[m] == level {
+					st.modules = append(st.modules, m)
+				}
+			}
+
+			st.ends = append(st.ends, len(st.modules))
+		}
+
+		clear(levels)
+		segment = segment[:0]
+		maxLevel = -1
+	}
+
+	for _, m := range schedule {
+		if hasControlOutputs(m) {
+			flush()
+			st.modules = append(st.modules, m)
+			st.ends = append(st.ends, len(st.modules))
+			continue
+		}
+
+		level := 0
+
+		for i := 0; i < m.NumInputs(); i++ {
+			for _, conn := range m.InputAtIndex(i).Connections {
+				if conn.IsFeedback() || !members[conn.Module] {
+					continue
+				}
+
+				if sourceLevel, ok := levels[conn.Module]; ok && sourceLevel >= level {
+					level = sourceLevel + 1
+				}
+			}
+		}
+
+		levels[m] = level
+		segment = append(segment, m)
+
+		if level > maxLevel {
+			maxLevel = level
+		}
+	}
+
+	flush()
+}
+
+// RunTask synthesizes a module of the current stage
+func (st *stages) RunTask(index int) {
+	m := st.current[index]
+	m.PrepareSynthesis()
+	m.Synthesize()
+}
+
+// run synthesizes all stages in order, the modules of a stage run in parallel
+func (st *stages) run(pool *workers.Pool) {
+	start := 0
+
+	for _, end := range st.ends {
+		st.current = st.modules[start:end]
+		pool.Run(st.batch, end-start)
+		start = end
+	}
+
+	st.current = nil
+}
@@ -0,0 +1,156 @@
+package muse_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/almerlucke/muse"
+)
+
+// adder outputs its input plus a value and records the order in which modules are synthesized
+type adder struct {
+	*muse.BaseModule
+	value float64
+	order *[]string
+}
+
+func newAdder(name string, value float64, order *[]string) *adder {
+	a := &adder{
+		BaseModule: muse.NewBaseModule(1, 1),
+		value:      value,
+		order:      order,
+	}
+
+	a.SetSelf(a)
+	a.SetIdentifier(name)
+
+	return a
+}
+
+func (a *adder) Synthesize() bool {
+	if !a.BaseModule.Synthesize() {
+		return false
+	}
+
+	*a.order = append(*a.order, a.Identifier())
+
+	for i := 0; i < a.Config.BufferSize; i++ {
+		a.Outputs[0].Buffer[i] = a.Inputs[0].Buffer[i] + a.value
+	}
+
+	return true
+}
+
+// expectOutput checks if all samples of the first output of root have the expected value
+func expectOutput(t *testing.T, root *muse.Muse, expected float64) {
+	t.Helper()
+
+	for i, sample := range root.OutputAtIndex(0).Buffer {
+		if sample != expected {
+			t.Fatalf("expected output %v, got %v at frame %d", expected, sample, i)
+		}
+	}
+}
+
+func TestScheduleOrder(t *testing.T) {
+	var order []string
+
+	root := muse.New(1)
+
+	// Modules are added in the reverse order of the chain
+	c := newAdder("c", 1, &order).AddTo(root)
+	b := newAdder("b", 1, &order).AddTo(root).In(newAdder("a", 1, &order).AddTo(root))
+	root.In(c.In(b))
+
+	for block := 0; block < 2; block++ {
+		order = order[:0]
+		root.Synthesize()
+
+		if expected := []string{"a", "b", "c"}; !slices.Equal(order, expected) {
+			t.Fatalf("synthesized %v, expected %v", order, expected)
+		}
+
+		expectOutput(t, root, 3)
+	}
+
+	// Unconnected modules are not synthesized
+	newAdder("d", 1, &order).AddTo(root)
+	order = order[:0]
+	root.Synthesize()
+
+	if slices.Contains(order, "d") {
+		t.Fatalf("unconnected module was synthesized: %v", order)
+	}
+}
+
+func TestConnectRejectsCycles(t *testing.T) {
+	var order []string
+
+	root := muse.New(1)
+
+	a := newAdder("a", 1, &order).AddTo(root)
+	b := newAdder("b", 1, &order).AddTo(root).In(a)
+	c := newAdder("c", 1, &order).AddTo(root).In(b)
+
+	if err := c.TryConnect(0, a, 0); !errors.Is(err, muse.ErrCycle) {
+		t.Fatalf("expected a cycle error, got %v", err)
+	}
+
+	if err := a.TryConnect(0, a, 0); !errors.Is(err, muse.ErrCycle) {
+		t.Fatalf("expected a cycle error for a self connection, got %v", err)
+	}
+
+	// Connect logs the cycle and connects nothing
+	c.Connect(0, a, 0)
+
+	if conns := a.InputAtIndex(0).Connections; len(conns) != 0 {
+		t.Fatalf("cycle was connected: %v", conns)
+	}
+
+	root.In(c)
+	root.Synthesize()
+	expectOutput(t, root, 3)
+}
+
+func TestFeedbackDelay(t *testing.T) {
+	var order []string
+
+	root := muse.New(1)
+
+	a := newAdder("a", 1, &order).AddTo(root)
+	b := newAdder("b", 0, &order).AddTo(root).In(a)
+
+	// b feeds back into a, a reads the output b produced in the previous block
+	if err := b.ConnectFeedback(0, a, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	root.In(a)
+
+	for block := 0; block < 4; block++ {
+		root.Synthesize()
+		expectOutput(t, root, float64(block+1))
+	}
+}
+
+func TestModuleNotAddedIsSynthesized(t *testing.T) {
+	var order []string
+
+	root := muse.New(1)
+
+	// The source is connected but never added to the patch
+	source := newAdder("source", 2, &order)
+	root.In(newAdder("a", 1, &order).AddTo(root).In(source))
+
+	for block := 0; block < 2; block++ {
+		order = order[:0]
+		root.Synthesize()
+
+		if expected := []string{"source", "a"}; !slices.Equal(order, expected) {
+			t.Fatalf("synthesized %v, expected %v", order, expected)
+		}
+
+		expectOutput(t, root, 3)
+	}
+}
@@ -0,0 +1,197 @@
+package io
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/almerlucke/muse/utils/rand"
+)
+
+// PCMWav is a sound file writer backend for 16 and 24 bit WAV files. Samples are kept as float in a temporary
+// file until Close, so normalization is applied before the samples are quantized. With dither the samples are
+// quantized with TPDF dither and first order noise shaping, which moves the quantization noise up in frequency
+type PCMWav struct {
+	filePath    string
+	numChannels int
+	sampleRate  int
+	bitDepth    int
+	dither      bool
+	gain        float64
+	temp        *os.File
+	tempWriter  *bufio.Writer
+	numSamples  int64
+}
+
+// NewPCMWav creates a WAV file backend with bitDepth 16 or 24
+func NewPCMWav(filePath string, numChannels int, sampleRate float64, bitDepth int, dither bool) (*PCMWav, error) {
+	if bitDepth != 16 && bitDepth != 24 {
+		return nil, fmt.Errorf("unsupported bit depth %d, expected 16 or 24", bitDepth)
+	}
+
+	temp, err := os.CreateTemp("", "muse-pcm-*.raw")
+	if err != nil {
+		return nil, err
+	}
+
+	return &PCMWav{
+		filePath:    filePath,
+		numChannels: numChannels,
+		sampleRate:  int(sampleRate),
+		bitDepth:    bitDepth,
+		dither:      dither,
+		gain:        1.0,
+		temp:        temp,
+		tempWriter:  bufio.NewWriter(temp),
+	}, nil
+}
+
+// Write adds interleaved samples
+func (w *PCMWav) Write(samples []float32) error {
+	w.numSamples += int64(len(samples))
+
+	return binary.Write(w.tempWriter, binary.LittleEndian, samples)
+}
+
+// Normalize scales the samples so the peak is at full scale
+func (w *PCMWav) Normalize(peak float32) error {
+	if peak > 0 {
+		w.gain = 1.0 / float64(peak)
+	}
+
+	return nil
+}
+
+// Close quantizes the samples and writes the WAV file
+func (w *PCMWav) Close() error {
+	defer func() {
+		_ = w.temp.Close()
+		_ = os.Remove(w.temp.Name())
+	}()
+
+	err := w.tempWriter.Flush()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.temp.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(w.filePath)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(file)
+
+	err = errors.Join(w.writeHeader(out), w.writeSamples(bufio.NewReader(w.temp), out), w.writePad(out), out.Flush())
+
+	return errors.Join(err, file.Close())
+}
+
+// dataSize returns the size of the samples in bytes
+func (w *PCMWav) dataSize() uint32 {
+	return uint32(w.numSamples) * uint32(w.bitDepth/8)
+}
+
+// padSize returns the size of the pad byte that keeps RIFF chunks at even sizes, 24 bit files with an odd
+// number of samples need one
+func (w *PCMWav) padSize() uint32 {
+	return w.dataSize() % 2
+}
+
+func (w *PCMWav) writeHeader(out io.Writer) error {
+	dataSize := w.dataSize()
+	blockAlign := uint16(w.numChannels * w.bitDepth / 8)
+
+	header := []any{
+		[]byte("RIFF"), 36 + dataSize + w.padSize(), []byte("WAVE"),
+		[]byte("fmt "), uint32(16), uint16(1), uint16(w.numChannels), uint32(w.sampleRate),
+		uint32(w.sampleRate) * uint32(blockAlign), blockAlign, uint16(w.bitDepth),
+		[]byte("data"), dataSize,
+	}
+
+	for _, field := range header {
+		if err := binary.Write(out, binary.LittleEndian, field); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *PCMWav) writeSamples(in io.Reader, out io.Writer) error {
+	var (
+		sample   float32
+		quantize = newQuantizer(w.numChannels, w.bitDepth, w.dither)
+		bytes    = make([]byte, 4)
+		size     = w.bitDepth / 8
+	)
+
+	for i := int64(0); i < w.numSamples; i++ {
+		if err := binary.Read(in, binary.LittleEndian, &sample); err != nil {
+			return err
+		}
+
+		q := quantize.quantize(int(i%int64(w.numChannels)), float64(sample)*w.gain)
+
+		binary.LittleEndian.PutUint32(bytes, uint32(q))
+
+		if _, err := out.Write(bytes[:size]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *PCMWav) writePad(out io.Writer) error {
+	_, err := out.Write(make([]byte, w.padSize()))
+
+	return err
+}
+
+// quantizer converts samples to integers of a bit depth, optionally with dither and noise shaping
+type quantizer struct {
+	scale  float64
+	max    float64
+	dither bool
+	rand   *rand.Rand
+	// err is the quantization error of the previous sample per channel
+	err []float64
+}
+
+func newQuantizer(numChannels int, bitDepth int, dither bool) *quantizer {
+	scale := math.Pow(2, float64(bitDepth-1))
+
+	return &quantizer{
+		scale:  scale,
+		max:    scale - 1,
+		dither: dither,
+		rand:   rand.NewRand(),
+		err:    make([]float64, numChannels),
+	}
+}
+
+func (q *quantizer) quantize(channel int, sample float64) int32 {
+	v := sample * q.scale
+
+	if !q.dither {
+		return int32(math.Max(-q.scale, math.Min(q.max, math.Round(v))))
+	}
+
+	// Subtracting the previous error shapes the noise with a first order highpass
+	v -= q.err[channel]
+	tpdf := q.rand.RandFloat() - q.rand.RandFloat()
+	out := math.Max(-q.scale, math.Min(q.max, math.Round(v+tpdf)))
+	// Clipping would feed back a large error, limit it to the error of rounding with dither
+	q.err[channel] = math.Max(-2, math.Min(2, out-v))
+
+	return int32(out)
+}
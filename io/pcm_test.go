@@ -0,0 +1,138 @@
+package io
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeWav writes samples with a PCM backend and returns the file contents
+func writeWav(t *testing.T, numChannels int, bitDepth int, dither bool, peak float32, samples []float32) []byte {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.wav")
+
+	w, err := NewPCMWav(path, numChannels, 48000, bitDepth, dither)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Write(samples); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Normalize(peak); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+// sample24 returns the 24 bit sample at index i of the data chunk
+func sample24(data []byte, i int) int32 {
+	b := data[44+i*3:]
+	return int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+}
+
+func TestPCMWavHeader(t *testing.T) {
+	data := writeWav(t, 1, 24, false, 0, []float32{0.5, -0.5, 0})
+
+	// 9 bytes of samples are padded to an even chunk size
+	if len(data) != 44+9+1 {
+		t.Fatalf("file size %d, expected %d", len(data), 44+9+1)
+	}
+
+	le := binary.LittleEndian
+
+	fields := []struct {
+		name     string
+		value    uint32
+		expected uint32
+	}{
+		{"RIFF size", le.Uint32(data[4:]), uint32(len(data) - 8)},
+		{"format", uint32(le.Uint16(data[20:])), 1},
+		{"channels", uint32(le.Uint16(data[22:])), 1},
+		{"sample rate", le.Uint32(data[24:]), 48000},
+		{"byte rate", le.Uint32(data[28:]), 48000 * 3},
+		{"block align", uint32(le.Uint16(data[32:])), 3},
+		{"bit depth", uint32(le.Uint16(data[34:])), 24},
+		{"data size", le.Uint32(data[40:]), 9},
+		{"pad byte", uint32(data[len(data)-1]), 0},
+	}
+
+	for _, field := range fields {
+		if field.value != field.expected {
+			t.Errorf("%s is %d, expected %d", field.name, field.value, field.expected)
+		}
+	}
+
+	if !bytes.Equal(data[:4], []byte("RIFF")) || !bytes.Equal(data[8:16], []byte("WAVEfmt ")) || !bytes.Equal(data[36:40], []byte("data")) {
+		t.Fatalf("invalid chunk ids in header %q", data[:44])
+	}
+
+	for i, expected := range []int32{1 << 22, -(1 << 22), 0} {
+		if s := sample24(data, i); s != expected {
+			t.Errorf("sample %d is %d, expected %d", i, s, expected)
+		}
+	}
+}
+
+func TestPCMWavEvenDataIsNotPadded(t *testing.T) {
+	data := writeWav(t, 2, 24, false, 0, []float32{0, 0, 0, 0})
+
+	if len(data) != 44+12 || binary.LittleEndian.Uint32(data[4:]) != 36+12 {
+		t.Fatalf("file size %d with RIFF size %d", len(data), binary.LittleEndian.Uint32(data[4:]))
+	}
+}
+
+func TestPCMWavNormalizeAndClip(t *testing.T) {
+	data := writeWav(t, 2, 16, false, 0.5, []float32{0.25, -0.5, 0.75, 0})
+
+	expected := []int16{16384, -32768, 32767, 0}
+
+	for i, e := range expected {
+		if s := int16(binary.LittleEndian.Uint16(data[44+i*2:])); s != e {
+			t.Errorf("sample %d is %d, expected %d", i, s, e)
+		}
+	}
+}
+
+func TestPCMWavDither(t *testing.T) {
+	const n = 10000
+
+	samples := make([]float32, n)
+	for i := range samples {
+		samples[i] = 0.1
+	}
+
+	data := writeWav(t, 1, 16, true, 0, samples)
+
+	exact := 0.1 * 32768
+	sum := 0.0
+
+	for i := 0; i < n; i++ {
+		s := float64(int16(binary.LittleEndian.Uint16(data[44+i*2:])))
+		if math.Abs(s-exact) > 3 {
+			t.Fatalf("dithered sample %d is %v, expected %v within 3", i, s, exact)
+		}
+
+		sum += s
+	}
+
+	// Dither keeps the average at the exact value
+	if mean := sum / n; math.Abs(mean-exact) > 0.1 {
+		t.Fatalf("mean of dithered samples is %v, expected %v", mean, exact)
+	}
+}
@@ -1,8 +1,10 @@
 package muse
 
+// Message is sent to the receiver at Address, Offset is the sample offset within the current block
 type Message struct {
 	Address string `json:"address"`
 	Content any    `json:"content"`
+	Offset  int    `json:"offset,omitempty"`
 }
 
 func NewMessage(address string, content any) *Message {
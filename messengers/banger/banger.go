@@ -31,6 +31,20 @@ func NewBang(banger Banger) *Bang {
 	return b
 }
 
+// Parameters describes the bang, followed by the parameters of the banger if it describes them
+func (b *Bang) Parameters() []muse.Parameter {
+	params := []muse.Parameter{
+		{Name: "bang", ControlIndex: 0, MessageKey: "bang", Type: muse.ParameterBang},
+	}
+
+	return append(params, muse.ParametersOf(b.banger)...)
+}
+
+// SetSeed seeds the banger if it has a random source
+func (b *Bang) SetSeed(seed uint64) {
+	muse.SeedObjects(seed, b.banger)
+}
+
 func (b *Bang) ReceiveControlValue(value any, index int) {
 	if index == 0 {
 		if value == muse.Bang {
@@ -71,6 +85,10 @@ func NewGenBang(gen genny.Generator[[]*muse.Message]) *Bang {
 	return NewBang(newGenBanger(gen))
 }
 
+func (gb *genBanger) SetSeed(seed uint64) {
+	muse.SeedObjects(seed, gb.gen)
+}
+
 func (gb *genBanger) ReceiveControlValue(value any, index int) {
 	// STUB
 }
@@ -132,7 +150,10 @@ func (d *templateDestination) ReceiveControlValue(value any, index int) {
 }
 
 func (d *templateDestination) ReceiveMessage(msg any) []*muse.Message {
-	d.paramMap = msg.(map[string]any)
+	if content, ok := muse.DecodeMessage(d, msg); ok && content.Values() != nil {
+		d.paramMap = content.Values()
+	}
+
 	return nil
 }
 
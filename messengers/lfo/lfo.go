@@ -1,12 +1,15 @@
 package lfo
 
 import (
+	"math"
+
 	"github.com/almerlucke/genny/float/shape"
 	"github.com/almerlucke/genny/float/shape/shapers/linear"
 	"github.com/almerlucke/genny/float/shape/shapers/lookup"
 	"github.com/almerlucke/genny/float/shape/shapers/series"
 	"github.com/almerlucke/genny/template"
 	"github.com/almerlucke/muse"
+	"github.com/almerlucke/muse/utils"
 )
 
 var lfoSineShaper = lookup.NewNormalizedSineTable(512.0)
@@ -50,6 +53,8 @@ type LFO struct {
 	shapeIndex int
 	shapes     []shape.Shaper
 	targets    []*Target
+	synced     bool
+	transport  *muse.Transport
 }
 
 func NewControlLFO(speed float64, min float64, max float64, shapeIndex int, shapes []shape.Shaper) *LFO {
@@ -109,6 +114,16 @@ func NewBasicLFO(speed float64, scale float64, offset float64, addresses []strin
 	return NewLFO(speed, ts)
 }
 
+// Parameters describes the parameters of the lfo, the output range set by min and max is not limited
+func (lfo *LFO) Parameters() []muse.Parameter {
+	return []muse.Parameter{
+		{Name: "speed", ControlIndex: 0, MessageKey: "speed", Type: muse.ParameterFloat, Min: 0.01, Max: 100, Default: 1.0, Unit: "Hz", Scaling: muse.ScalingExponential, Value: lfo.Speed()},
+		{Name: "min", ControlIndex: 1, MessageKey: "min", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.0, Value: lfo.Min()},
+		{Name: "max", ControlIndex: 2, MessageKey: "max", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 1.0, Value: lfo.Max()},
+		{Name: "shapeIndex", ControlIndex: 3, MessageKey: "shapeIndex", Type: muse.ParameterInt, Min: 0, Max: float64(max(len(lfo.shapes)-1, 0)), Default: 0, Value: lfo.ShapeIndex()},
+	}
+}
+
 func (lfo *LFO) ReceiveControlValue(value any, index int) {
 	// Index == 0 -> speed (float)
 	// Index == 1 -> min (float)
@@ -117,38 +132,43 @@ func (lfo *LFO) ReceiveControlValue(value any, index int) {
 
 	switch index {
 	case 0: // speed
-		if speed, ok := value.(float64); ok {
+		if speed, ok := muse.ControlFloat(lfo, index, value); ok {
 			lfo.SetSpeed(speed)
 		}
 	case 1: // min
-		if mi, ok := value.(float64); ok {
+		if mi, ok := muse.ControlFloat(lfo, index, value); ok {
 			lfo.SetMin(mi)
 		}
 	case 2: // max
-		if ma, ok := value.(float64); ok {
+		if ma, ok := muse.ControlFloat(lfo, index, value); ok {
 			lfo.SetMax(ma)
 		}
 	case 3: // shape index
-		lfo.SetShapeIndex(value)
+		if shapeIndex, ok := muse.ControlFloat(lfo, index, value); ok {
+			lfo.SetShapeIndex(shapeIndex)
+		}
 	}
 }
 
 func (lfo *LFO) ReceiveMessage(msg any) []*muse.Message {
-	content := msg.(map[string]any)
+	content, ok := muse.DecodeMessage(lfo, msg)
+	if !ok {
+		return nil
+	}
 
-	if speed, ok := content["speed"]; ok {
-		lfo.SetSpeed(speed.(float64))
+	if speed, ok := content.Float("speed"); ok {
+		lfo.SetSpeed(speed)
 	}
 
-	if mi, ok := content["min"]; ok {
-		lfo.SetMin(mi.(float64))
+	if mi, ok := content.Float("min"); ok {
+		lfo.SetMin(mi)
 	}
 
-	if ma, ok := content["max"]; ok {
-		lfo.SetMax(ma.(float64))
+	if ma, ok := content.Float("max"); ok {
+		lfo.SetMax(ma)
 	}
 
-	if shapeIndex, ok := content["shapeIndex"]; ok {
+	if shapeIndex, ok := content.Float("shapeIndex"); ok {
 		lfo.SetShapeIndex(shapeIndex)
 	}
 
@@ -197,19 +217,43 @@ func (lfo *LFO) ShapeIndex() int {
 func (lfo *LFO) SetShapeIndex(anyIndex any) {
 	index := lfo.shapeIndex
 
-	if newIndex, ok := anyIndex.(float64); ok {
+	if newIndex, ok := utils.ToFloat(anyIndex); ok {
 		index = int(newIndex)
-	} else if newIndex, ok := anyIndex.(int); ok {
-		index = newIndex
 	}
 
-	if index < len(lfo.shapes) {
+	if index >= 0 && index < len(lfo.shapes) {
 		lfo.shapeIndex = index
 	}
 }
 
-func (lfo *LFO) Tick(timestamp int64, config *muse.Configuration) {
-	out := (lfo.max-lfo.min)*lfo.shapes[lfo.shapeIndex].Shape(lfo.phase) + lfo.min
+// SyncToTransport locks the phase to the transport, the speed is in cycles per beat instead of cycles per second.
+// The lfo holds its phase while the transport is stopped
+func (lfo *LFO) SyncToTransport() *LFO {
+	lfo.synced = true
+
+	return lfo
+}
+
+// SetTransport is called by the patch the lfo is added to
+func (lfo *LFO) SetTransport(transport *muse.Transport) {
+	lfo.transport = transport
+}
+
+// syncPhase takes the phase of a synced lfo from the transport at the start of the block
+func (lfo *LFO) syncPhase() {
+	if transport := lfo.transport; lfo.synced && transport != nil && transport.Playing() {
+		_, lfo.phase = math.Modf(transport.Beat() * lfo.speed)
+		if lfo.phase < 0 {
+			lfo.phase += 1.0
+		}
+	}
+}
+
+// advance moves the phase of a free running lfo to the next block
+func (lfo *LFO) advance() {
+	if lfo.synced {
+		return
+	}
 
 	lfo.phase += lfo.delta
 
@@ -220,22 +264,25 @@ func (lfo *LFO) Tick(timestamp int64, config *muse.Configuration) {
 	for lfo.phase < 0.0 {
 		lfo.phase += 1.0
 	}
+}
+
+func (lfo *LFO) Tick(timestamp int64, config *muse.Configuration) {
+	lfo.syncPhase()
+
+	out := (lfo.max-lfo.min)*lfo.shapes[lfo.shapeIndex].Shape(lfo.phase) + lfo.min
+
+	lfo.advance()
 
 	lfo.SendControlValue(out, 0)
 }
 
 func (lfo *LFO) Messages(timestamp int64, config *muse.Configuration) []*muse.Message {
+	lfo.syncPhase()
+
 	out := lfo.phase
 	controlOut := (lfo.max-lfo.min)*lfo.shapes[lfo.shapeIndex].Shape(lfo.phase) + lfo.min
 
-	lfo.phase += lfo.delta
-
-	for lfo.phase >= 1.0 {
-		lfo.phase -= 1.0
-	}
-	for lfo.phase < 0.0 {
-		lfo.phase += 1.0
-	}
+	lfo.advance()
 
 	var msgs []*muse.Message
 
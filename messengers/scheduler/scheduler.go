@@ -124,28 +124,40 @@ func (s *Scheduler) Tick(timestamp int64, config *muse.Configuration) {
 	_ = s.Messages(timestamp, config)
 }
 
+// Messages sends the messages and control values of all events within the current block at their
+// sample offset, functions are called at the start of the block
 func (s *Scheduler) Messages(timestamp int64, config *muse.Configuration) []*muse.Message {
 	var (
-		when      = config.SampsToMilli(timestamp)
+		end       = config.SampsToMilli(timestamp + int64(config.BufferSize))
 		numEvents = len(s.events)
 		messages  []*muse.Message
 	)
 
 	for s.eventIndex < numEvents {
 		event := s.events[s.eventIndex]
-		if event.When > when {
+		if event.When >= end {
 			break
 		}
 
+		offset := int(config.MilliToSamps(event.When) - timestamp)
+		if offset < 0 {
+			offset = 0
+		}
+
 		for _, f := range event.Functions {
 			f()
 		}
 
 		for _, controlMessage := range event.ControlMessages {
-			s.SendControlValue(controlMessage.Content, controlMessage.OutIndex)
+			s.SendControlValueAt(controlMessage.Content, controlMessage.OutIndex, offset)
+		}
+
+		for _, msg := range event.Messages {
+			timedMsg := *msg
+			timedMsg.Offset = offset
+			messages = append(messages, &timedMsg)
 		}
 
-		messages = append(messages, event.Messages...)
 		s.eventIndex++
 	}
 
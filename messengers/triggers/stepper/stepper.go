@@ -10,6 +10,9 @@ type Stepper struct {
 	addresses   []string
 	accum       float64
 	durationGen genny.Generator[float64]
+	synced      bool
+	transport   *muse.Transport
+	nextBeat    float64
 }
 
 func NewStepper(durationGen genny.Generator[float64], addresses []string) *Stepper {
@@ -24,48 +27,102 @@ func NewStepper(durationGen genny.Generator[float64], addresses []string) *Stepp
 	return s
 }
 
+// SyncToTransport lets the stepper follow the transport, durations are in beats and steps only
+// happen while the transport plays
+func (s *Stepper) SyncToTransport() *Stepper {
+	s.synced = true
+
+	return s
+}
+
+// SetTransport is called by the patch the stepper is added to
+func (s *Stepper) SetTransport(transport *muse.Transport) {
+	s.transport = transport
+}
+
+// SetSeed seeds the duration generator if it has a random source, like a swing
+func (s *Stepper) SetSeed(seed uint64) {
+	muse.SeedObjects(seed, s.durationGen)
+}
+
 func (s *Stepper) Tick(timestamp int64, config *muse.Configuration) {
 	_ = s.Messages(timestamp, config)
 }
 
+// Messages bangs at the sample offset of every step that starts within the current block
 func (s *Stepper) Messages(timestamp int64, config *muse.Configuration) []*muse.Message {
+	if s.synced && s.transport != nil {
+		return s.syncedMessages(s.transport)
+	}
+
 	var (
-		messages   []*muse.Message
-		bang       bool
-		durationMs float64
+		messages       []*muse.Message
+		floatTimestamp = float64(timestamp)
+		end            = float64(timestamp + int64(config.BufferSize))
 	)
 
-	for {
-		if float64(timestamp) < s.accum {
-			break
+	for s.accum < end {
+		offset := 0
+		if s.accum > floatTimestamp {
+			offset = int(s.accum - floatTimestamp)
 		}
 
 		if s.durationGen.Done() {
-			s.SendControlValue(muse.Bang, 2)
+			s.SendControlValueAt(muse.Bang, 2, offset)
 			s.durationGen.Reset()
 		}
 
-		durationMs = s.durationGen.Generate()
+		durationMs := s.durationGen.Generate()
 
 		wait := config.MilliToSampsf(durationMs)
-		if wait > 0 {
-			bang = true
-			s.accum += wait
-		} else {
+		if wait <= 0 {
+			// Negative durations are rests
 			s.accum -= wait
+			continue
 		}
+
+		s.accum += wait
+
+		messages = s.step(durationMs, offset, messages)
 	}
 
-	if bang {
-		s.SendControlValue(durationMs, 1)
-		s.SendControlValue(muse.Bang, 0)
+	return messages
+}
+
+// syncedMessages bangs at the sample offset of every step that starts within the current block of the transport
+func (s *Stepper) syncedMessages(transport *muse.Transport) []*muse.Message {
+	var messages []*muse.Message
 
-		for _, address := range s.addresses {
-			messages = append(messages, &muse.Message{
-				Address: address,
-				Content: muse.Bang,
-			})
+	s.nextBeat = transport.Steps(s.nextBeat, func(beat float64, offset int) float64 {
+		if s.durationGen.Done() {
+			s.SendControlValueAt(muse.Bang, 2, offset)
+			s.durationGen.Reset()
 		}
+
+		duration := s.durationGen.Generate()
+		if duration <= 0 {
+			// Negative durations are rests
+			return beat - duration
+		}
+
+		messages = s.step(duration, offset, messages)
+
+		return beat + duration
+	})
+
+	return messages
+}
+
+func (s *Stepper) step(duration float64, offset int, messages []*muse.Message) []*muse.Message {
+	s.SendControlValueAt(duration, 1, offset)
+	s.SendControlValueAt(muse.Bang, 0, offset)
+
+	for _, address := range s.addresses {
+		messages = append(messages, &muse.Message{
+			Address: address,
+			Content: muse.Bang,
+			Offset:  offset,
+		})
 	}
 
 	return messages
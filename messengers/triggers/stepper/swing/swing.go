@@ -2,8 +2,9 @@ package swing
 
 import (
 	"github.com/almerlucke/genny"
+	"github.com/almerlucke/muse/utils/rand"
 	"math"
-	"math/rand"
+	mathrand "math/rand"
 )
 
 var _shuffleMultiplier = 0.7
@@ -18,19 +19,29 @@ type Step struct {
 	NumBurst    int     `json:"numBurst"`
 }
 
-func (s *Step) shuffleDelay(milliPerNote float64) float64 {
+func (s *Step) shuffleDelay(r *rand.Rand, milliPerNote float64) float64 {
 	shuffleRandBandwidth := math.Min(s.Shuffle, 1.0-s.Shuffle)
-	shuffleAmount := s.Shuffle + (rand.Float64()*2.0-1.0)*math.Min(s.ShuffleRand, shuffleRandBandwidth)
+	shuffleAmount := s.Shuffle + (r.RandFloat()*2.0-1.0)*math.Min(s.ShuffleRand, shuffleRandBandwidth)
 
 	return _shuffleMultiplier * shuffleAmount * milliPerNote
 }
 
+// Burst draws the burst chance from the global math/rand source, a swing draws from its own seeded source
 func (s *Step) Burst() bool {
-	return rand.Float64() < s.BurstChance
+	return mathrand.Float64() < s.BurstChance
 }
 
+// SkipStep draws the skip chance from the global math/rand source, a swing draws from its own seeded source
 func (s *Step) SkipStep() bool {
-	return s.Skip || rand.Float64() < s.SkipChance
+	return s.Skip || mathrand.Float64() < s.SkipChance
+}
+
+func (s *Step) burst(r *rand.Rand) bool {
+	return r.RandFloat() < s.BurstChance
+}
+
+func (s *Step) skipStep(r *rand.Rand) bool {
+	return s.Skip || r.RandFloat() < s.SkipChance
 }
 
 /*
@@ -77,6 +88,7 @@ type Swing struct {
 	burstCount        int
 	burstMode         bool
 	burstDuration     float64
+	rand              *rand.Rand
 }
 
 func New(bpm int, noteDivision int, steps genny.Generator[*Step]) *Swing {
@@ -85,6 +97,36 @@ func New(bpm int, noteDivision int, steps genny.Generator[*Step]) *Swing {
 		noteDivision: noteDivision,
 		bpm:          bpm,
 		milliPerNote: (60000.0 / float64(bpm)) / float64(noteDivision),
+		rand:         rand.NewRandWithSeed(rand.AutoSeed()),
+	}
+}
+
+// NewBeats returns a swing that generates durations in beats instead of milliseconds, for a stepper that is
+// synced to the transport
+func NewBeats(noteDivision int, steps genny.Generator[*Step]) *Swing {
+	return &Swing{
+		steps:        steps,
+		noteDivision: noteDivision,
+		milliPerNote: 1.0 / float64(noteDivision),
+		rand:         rand.NewRandWithSeed(rand.AutoSeed()),
+	}
+}
+
+// SetSeed seeds the random source of the shuffle, skip and burst chances, the steps are seeded with a derived
+// seed if they have a random source
+func (sw *Swing) SetSeed(seed uint64) {
+	sw.rand.Seed(seed)
+
+	if seeder, ok := sw.steps.(interface{ SetSeed(uint64) }); ok {
+		seeder.SetSeed(rand.DeriveSeed(seed, "steps"))
+	}
+}
+
+// SetBPM changes the tempo of a swing in milliseconds, it has no effect on a swing in beats
+func (sw *Swing) SetBPM(bpm int) {
+	if sw.bpm > 0 && bpm > 0 {
+		sw.bpm = bpm
+		sw.milliPerNote = (60000.0 / float64(bpm)) / float64(sw.noteDivision)
 	}
 }
 
@@ -113,18 +155,18 @@ func (sw *Swing) Generate() float64 {
 
 	milliPerNote := sw.milliPerNote * multiply
 
-	if step.SkipStep() {
+	if step.skipStep(sw.rand) {
 		return -milliPerNote
 	}
 
-	if step.Burst() {
+	if step.burst(sw.rand) {
 		sw.burstMode = true
 		sw.burstCount = step.NumBurst
 		sw.burstDuration = milliPerNote / float64(step.NumBurst)
 		return sw.Generate()
 	}
 
-	delay := step.shuffleDelay(milliPerNote)
+	delay := step.shuffleDelay(sw.rand, milliPerNote)
 	if delay > 0.0 {
 		sw.remainingDuration = milliPerNote - delay
 		sw.delayed = true
@@ -14,6 +14,9 @@ type Timer struct {
 	gen           genny.Generator[float64]
 	accum         float64
 	sampleRate    float64
+	synced        bool
+	transport     *muse.Transport
+	nextBeat      float64
 }
 
 func New(intervalMilli float64, addresses []string, gen genny.Generator[float64]) *Timer {
@@ -40,66 +43,142 @@ func NewControl(intervalMilli float64, gen genny.Generator[float64]) *Timer {
 	return New(intervalMilli, nil, gen)
 }
 
+// SetSeed seeds the interval generator if it has a random source
+func (t *Timer) SetSeed(seed uint64) {
+	muse.SeedObjects(seed, t.gen)
+}
+
+// Interval in milliseconds, or in beats if the timer is synced to the transport
+func (t *Timer) Interval() float64 {
+	return t.intervalMilli
+}
+
+func (t *Timer) Addresses() []string {
+	return t.addresses
+}
+
+// Parameters describes the parameters of the timer
+func (t *Timer) Parameters() []muse.Parameter {
+	if t.synced {
+		return []muse.Parameter{
+			{Name: "interval", ControlIndex: 0, MessageKey: "interval", Type: muse.ParameterFloat, Min: 1.0 / 16.0, Max: 16, Default: 1.0, Unit: "beats", Scaling: muse.ScalingExponential, Value: t.Interval()},
+		}
+	}
+
+	return []muse.Parameter{
+		{Name: "interval", ControlIndex: 0, MessageKey: "interval", Type: muse.ParameterFloat, Min: 1, Max: 10000, Default: 250.0, Unit: "ms", Scaling: muse.ScalingExponential, Value: t.Interval()},
+	}
+}
+
+func (t *Timer) setInterval(intervalMilli float64) {
+	if intervalMilli > 0 {
+		t.intervalMilli = intervalMilli
+		t.interval = timing.MilliToSampsf(intervalMilli, t.sampleRate)
+	}
+}
+
 func (t *Timer) ReceiveControlValue(value any, index int) {
 	if index == 0 {
-		if intervalMilli, ok := value.(float64); ok {
-			if intervalMilli > 0 {
-				t.intervalMilli = intervalMilli
-				t.interval = timing.MilliToSampsf(intervalMilli, t.sampleRate)
-			}
+		if intervalMilli, ok := muse.ControlFloat(t, index, value); ok {
+			t.setInterval(intervalMilli)
 		}
 	}
 }
 
 func (t *Timer) ReceiveMessage(msg any) []*muse.Message {
-	content := msg.(map[string]interface{})
+	content, ok := muse.DecodeMessage(t, msg)
+	if !ok {
+		return nil
+	}
 
-	if interval, ok := content["interval"]; ok {
-		intervalMilli := interval.(float64)
-		if intervalMilli > 0 {
-			t.intervalMilli = intervalMilli
-			t.interval = timing.MilliToSampsf(intervalMilli, t.sampleRate)
-		}
+	if intervalMilli, ok := content.Float("interval"); ok {
+		t.setInterval(intervalMilli)
 	}
 
 	return nil
 }
 
+// SyncToTransport lets the timer follow the transport, intervals are in beats instead of milliseconds and
+// the timer only bangs while the transport plays, starting at the play position
+func (t *Timer) SyncToTransport() *Timer {
+	t.synced = true
+
+	return t
+}
+
+// SetTransport is called by the patch the timer is added to
+func (t *Timer) SetTransport(transport *muse.Transport) {
+	t.transport = transport
+}
+
 func (t *Timer) Tick(timestamp int64, config *muse.Configuration) {
 	_ = t.Messages(timestamp, config)
 }
 
-func (t *Timer) Messages(timestamp int64, _ *muse.Configuration) []*muse.Message {
+// Messages bangs at the sample offset of every interval that ends within the current block
+func (t *Timer) Messages(timestamp int64, config *muse.Configuration) []*muse.Message {
+	if t.synced && t.transport != nil {
+		return t.syncedMessages(t.transport)
+	}
+
 	var (
 		messages       []*muse.Message
-		bang           bool
 		floatTimestamp = float64(timestamp)
+		end            = float64(timestamp + int64(config.BufferSize))
 	)
 
-	if floatTimestamp > t.accum {
-		bang = true
-		for t.accum <= floatTimestamp {
-			if t.gen != nil {
-				if t.gen.Done() {
-					t.gen.Reset()
-				}
-				t.intervalMilli = t.gen.Generate()
-				t.interval = timing.MilliToSampsf(t.intervalMilli, t.sampleRate)
+	for t.accum < end {
+		offset := 0
+		if t.accum > floatTimestamp {
+			offset = int(t.accum - floatTimestamp)
+		}
+
+		if t.gen != nil {
+			if t.gen.Done() {
+				t.gen.Reset()
 			}
-			t.accum += t.interval
+			t.intervalMilli = t.gen.Generate()
+			t.interval = timing.MilliToSampsf(t.intervalMilli, t.sampleRate)
 		}
+
+		t.accum += t.interval
+
+		messages = t.bang(offset, messages)
 	}
 
-	if bang {
-		t.SendControlValue(t.intervalMilli, 1)
-		t.SendControlValue(muse.Bang, 0)
+	return messages
+}
+
+// syncedMessages bangs at the sample offset of every interval that starts within the current block of the transport
+func (t *Timer) syncedMessages(transport *muse.Transport) []*muse.Message {
+	var messages []*muse.Message
 
-		for _, address := range t.addresses {
-			messages = append(messages, &muse.Message{
-				Address: address,
-				Content: muse.Bang,
-			})
+	t.nextBeat = transport.Steps(t.nextBeat, func(beat float64, offset int) float64 {
+		if t.gen != nil {
+			if t.gen.Done() {
+				t.gen.Reset()
+			}
+			t.intervalMilli = t.gen.Generate()
 		}
+
+		messages = t.bang(offset, messages)
+
+		return beat + t.intervalMilli
+	})
+
+	return messages
+}
+
+func (t *Timer) bang(offset int, messages []*muse.Message) []*muse.Message {
+	t.SendControlValueAt(t.intervalMilli, 1, offset)
+	t.SendControlValueAt(muse.Bang, 0, offset)
+
+	for _, address := range t.addresses {
+		messages = append(messages, &muse.Message{
+			Address: address,
+			Content: muse.Bang,
+			Offset:  offset,
+		})
 	}
 
 	return messages
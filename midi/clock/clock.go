@@ -1,86 +1,373 @@
 package clock
 
 import (
-	"container/list"
-	"context"
-	"gitlab.com/gomidi/midi/v2"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"gitlab.com/gomidi/midi/v2"
 )
 
+// TicksPerQuarter is the resolution of MIDI clock
+const TicksPerQuarter = 24
+
+// ticksPerSixteenth is the resolution of the song position pointer
+const ticksPerSixteenth = TicksPerQuarter / 4
+
+// Listener receives clock ticks on the audio thread
 type Listener interface {
 	ClockTick(tickCnt int64)
 }
 
+// TimedListener is implemented by listeners that need the sample offset of the tick within the current block,
+// they receive ClockTickAt instead of ClockTick
+type TimedListener interface {
+	ClockTickAt(tickCnt int64, offset int)
+}
+
+// Clock is a MIDI clock driven by the sample time of the audio stream, so it never drifts from the audio. Advance
+// is called once per block on the audio thread, all other methods except Receive must be called on the audio
+// thread as well, from other goroutines post them with Muse.Post. Outgoing MIDI is sent from a separate goroutine
+// at the wall clock time of the sample offset of each tick.
+//
+// In slave mode the clock follows incoming MIDI clock passed to Receive: the tempo is estimated from the incoming
+// ticks and the generated ticks are kept in step with the number of received ticks
 type Clock struct {
-	startTime    time.Time
-	nextTickTime float64
-	timePerTick  float64
-	tickCntr     int64
-	ctx          context.Context
-	stop         context.CancelFunc
+	bpm          float64
+	sampleRate   float64
+	running      bool
+	tickCnt      int64
+	nextTick     float64
 	midiSendFunc func(msg midi.Message) error
-	listeners    list.List
+	listeners    []Listener
+	output       chan outgoing
+	outputDone   chan struct{}
+	follower     atomic.Pointer[follower]
 }
 
-func New(bpm int, midiSendFunc func(msg midi.Message) error) *Clock {
-	timePerTick := 60.0 / (float64(bpm) * 24.0)
+type outgoing struct {
+	msg midi.Message
+	due time.Time
+}
 
-	return &Clock{
+func New(bpm float64, sampleRate float64, midiSendFunc func(msg midi.Message) error) *Clock {
+	c := &Clock{
+		bpm:          bpm,
+		sampleRate:   sampleRate,
 		midiSendFunc: midiSendFunc,
-		timePerTick:  timePerTick,
-		nextTickTime: timePerTick,
 	}
+
+	if midiSendFunc != nil {
+		c.output = make(chan outgoing, 256)
+		c.outputDone = make(chan struct{})
+
+		go c.sendOutput()
+	}
+
+	return c
 }
 
-func (c *Clock) send() error {
-	if c.midiSendFunc != nil {
-		return c.midiSendFunc(midi.Start())
+// Close stops sending MIDI, the clock must not be advanced after Close
+func (c *Clock) Close() {
+	if c.output != nil {
+		close(c.output)
+		<-c.outputDone
+		c.output = nil
 	}
+}
 
-	return nil
+func (c *Clock) AddListener(listener Listener) {
+	c.listeners = append(c.listeners, listener)
 }
 
-func (c *Clock) Start() error {
-	if c.midiSendFunc != nil {
-		err := c.midiSendFunc(midi.Start())
-		if err != nil {
-			return err
+func (c *Clock) RemoveListener(listener Listener) {
+	for i, other := range c.listeners {
+		if other == listener {
+			c.listeners = append(c.listeners[:i], c.listeners[i+1:]...)
+			return
 		}
 	}
+}
 
-	c.startTime = time.Now()
-	c.ctx, c.stop = context.WithCancel(context.Background())
+func (c *Clock) BPM() float64 {
+	return c.bpm
+}
 
-	go c.run()
+// SetBPM changes the tempo, the time until the next tick is scaled so the change is smooth
+func (c *Clock) SetBPM(bpm float64) {
+	if bpm <= 0 {
+		return
+	}
 
-	return nil
+	c.nextTick *= c.bpm / bpm
+	c.bpm = bpm
+}
+
+func (c *Clock) Running() bool {
+	return c.running
+}
+
+// Ticks returns the number of ticks since the start of the song
+func (c *Clock) Ticks() int64 {
+	return c.tickCnt
+}
+
+// Position returns the song position in sixteenth notes
+func (c *Clock) Position() int {
+	return int(c.tickCnt / ticksPerSixteenth)
+}
+
+// Start starts from the beginning of the song, the first tick is sent at the start of the next block
+func (c *Clock) Start() error {
+	c.tickCnt = 0
+	c.nextTick = 0
+	c.running = true
+
+	return c.send(midi.Start(), 0)
+}
+
+// Continue continues from the current song position
+func (c *Clock) Continue() error {
+	c.nextTick = 0
+	c.running = true
+
+	return c.send(midi.Continue(), 0)
 }
 
 func (c *Clock) Stop() error {
-	c.stop()
+	c.running = false
+
+	return c.send(midi.Stop(), 0)
+}
+
+// SetPosition moves the song position to sixteenths and sends a song position pointer, the position should
+// only change while the clock is stopped
+func (c *Clock) SetPosition(sixteenths int) error {
+	c.tickCnt = int64(sixteenths) * ticksPerSixteenth
+
+	return c.send(midi.SPP(uint16(sixteenths)), 0)
+}
+
+// Advance runs the clock for a block of numFrames samples
+func (c *Clock) Advance(numFrames int) {
+	f := c.follower.Load()
+	if f != nil {
+		c.follow(f)
+	}
+
+	if !c.running {
+		return
+	}
+
+	samplesPerTick := c.samplesPerTick()
+
+	if f != nil {
+		// Catch up when the master is more than a tick ahead
+		for n := f.behind(c.tickCnt); n > 0; n-- {
+			c.tick(0)
+		}
+	}
+
+	for c.nextTick < float64(numFrames) {
+		offset := int(c.nextTick)
+
+		if f == nil || f.allowTick(c.tickCnt) {
+			c.tick(offset)
+		}
+
+		c.nextTick += samplesPerTick
+	}
+
+	c.nextTick -= float64(numFrames)
+}
+
+func (c *Clock) samplesPerTick() float64 {
+	return c.sampleRate * 60.0 / (c.bpm * TicksPerQuarter)
+}
+
+func (c *Clock) tick(offset int) {
+	_ = c.send(midi.TimingClock(), offset)
 
-	if c.midiSendFunc != nil {
-		return c.midiSendFunc(midi.Stop())
+	for _, listener := range c.listeners {
+		if timed, ok := listener.(TimedListener); ok {
+			timed.ClockTickAt(c.tickCnt, offset)
+		} else {
+			listener.ClockTick(c.tickCnt)
+		}
+	}
+
+	c.tickCnt++
+}
+
+// send queues msg for the output goroutine, messages are dropped if the output can not keep up
+func (c *Clock) send(msg midi.Message, offset int) error {
+	if c.output == nil {
+		return nil
+	}
+
+	due := time.Now().Add(time.Duration(float64(offset) / c.sampleRate * float64(time.Second)))
+
+	select {
+	case c.output <- outgoing{msg: msg, due: due}:
+	default:
 	}
 
 	return nil
 }
 
-func (c *Clock) run() {
-	for {
-		select {
-		case <-c.ctx.Done():
-			return
-		case t := <-time.After(time.Microsecond * 50):
-			if t.Sub(c.startTime).Seconds() >= c.nextTickTime {
-				err := c.send()
-				if err != nil {
-					c.stop()
-					return
+func (c *Clock) sendOutput() {
+	defer close(c.outputDone)
+
+	for out := range c.output {
+		if wait := time.Until(out.due); wait > 0 {
+			time.Sleep(wait)
+		}
+
+		_ = c.midiSendFunc(out.msg)
+	}
+}
+
+// SetSlave switches slave mode on or off, in slave mode the clock follows the MIDI clock passed to Receive. It
+// can be called from any goroutine
+func (c *Clock) SetSlave(slave bool) {
+	if slave {
+		c.follower.CompareAndSwap(nil, &follower{})
+	} else {
+		c.follower.Store(nil)
+	}
+}
+
+// Receive passes incoming MIDI to a clock in slave mode, it can be called from any goroutine
+func (c *Clock) Receive(msg midi.Message) {
+	if f := c.follower.Load(); f != nil {
+		f.receive(msg, time.Now())
+	}
+}
+
+// follow applies the transport changes and tempo estimate of the follower
+func (c *Clock) follow(f *follower) {
+	state := f.take()
+
+	if state.position >= 0 {
+		_ = c.SetPosition(state.position)
+	}
+
+	switch state.transport {
+	case transportStart:
+		_ = c.Start()
+	case transportContinue:
+		_ = c.Continue()
+	case transportStop:
+		_ = c.Stop()
+	}
+
+	if state.bpm > 0 {
+		c.SetBPM(state.bpm)
+	}
+}
+
+type transport int
+
+const (
+	transportNone transport = iota
+	transportStart
+	transportContinue
+	transportStop
+)
+
+// followerSmoothing is the weight of a new tick interval in the tempo estimate
+const followerSmoothing = 0.1
+
+// followerMaxInterval is the longest tick interval that is considered part of a running clock, 24 ticks
+// per quarter at 10 bpm
+const followerMaxInterval = 250 * time.Millisecond
+
+// follower estimates the tempo of incoming MIDI clock
+type follower struct {
+	lock      sync.Mutex
+	lastTick  time.Time
+	interval  float64
+	received  int64
+	transport transport
+	position  int
+	hasPos    bool
+}
+
+type followerState struct {
+	transport transport
+	position  int
+	bpm       float64
+}
+
+func (f *follower) receive(msg midi.Message, now time.Time) {
+	var spp uint16
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	switch {
+	case msg.Is(midi.TimingClockMsg):
+		if !f.lastTick.IsZero() {
+			if dt := now.Sub(f.lastTick); dt < followerMaxInterval {
+				if f.interval == 0 {
+					f.interval = dt.Seconds()
+				} else {
+					f.interval += followerSmoothing * (dt.Seconds() - f.interval)
 				}
-				c.tickCntr++
-				c.nextTickTime += c.timePerTick
 			}
 		}
+
+		f.lastTick = now
+		f.received++
+	case msg.Is(midi.StartMsg):
+		f.transport = transportStart
+		f.received = 0
+	case msg.Is(midi.ContinueMsg):
+		f.transport = transportContinue
+	case msg.Is(midi.StopMsg):
+		f.transport = transportStop
+	case msg.GetSPP(&spp):
+		f.position = int(spp)
+		f.hasPos = true
+		f.received = int64(spp) * ticksPerSixteenth
+	}
+}
+
+// take returns and clears the pending transport changes together with the current tempo estimate
+func (f *follower) take() followerState {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	state := followerState{transport: f.transport, position: -1}
+
+	if f.hasPos {
+		state.position = f.position
 	}
+
+	if f.interval > 0 {
+		state.bpm = 60.0 / (f.interval * TicksPerQuarter)
+	}
+
+	f.transport = transportNone
+	f.hasPos = false
+
+	return state
+}
+
+// behind returns the number of ticks the generated ticks are behind the received ticks, not counting
+// the tick that is expected next
+func (f *follower) behind(tickCnt int64) int64 {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return max(0, f.received-tickCnt-1)
+}
+
+// allowTick keeps the generated ticks within one tick of the received ticks, a tick ahead of the master is
+// held back until the master catches up
+func (f *follower) allowTick(tickCnt int64) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return tickCnt <= f.received
 }
@@ -0,0 +1,441 @@
+package input
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/almerlucke/muse"
+	"github.com/almerlucke/muse/utils/notes"
+	"github.com/almerlucke/muse/utils/queue"
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// Poster delivers messages on the audio thread, it is implemented by *muse.Muse
+type Poster interface {
+	PostMessage(msg *muse.Message) error
+}
+
+// ControlTarget is a user interface control a controller can be mapped to, like a ui/controls Slider
+type ControlTarget interface {
+	Identifier() string
+	Min() float64
+	Max() float64
+	Get() float64
+	Set(value float64, setter any)
+}
+
+// Takeover decides what happens when a controller does not match the value of the parameter it is mapped to,
+// for instance after loading a patch or when the value was changed from a user interface
+type Takeover int
+
+const (
+	// TakeoverJump sets the value of the controller immediately
+	TakeoverJump Takeover = iota
+	// TakeoverPickup ignores the controller until it passes the current value
+	TakeoverPickup
+	// TakeoverScale moves the value in the direction of the controller so both reach the end of the range together
+	TakeoverScale
+)
+
+func (t Takeover) String() string {
+	switch t {
+	case TakeoverPickup:
+		return "pickup"
+	case TakeoverScale:
+		return "scale"
+	default:
+		return "jump"
+	}
+}
+
+func (t Takeover) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *Takeover) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "jump":
+		*t = TakeoverJump
+	case "pickup":
+		*t = TakeoverPickup
+	case "scale":
+		*t = TakeoverScale
+	default:
+		return fmt.Errorf("unknown takeover %q", text)
+	}
+
+	return nil
+}
+
+// Mapping sends a controller value to Key of the receiver at Address, or sets the registered control with
+// identifier Control. The 7-bit controller value or the 14-bit pitch bend is mapped to the range Min - Max
+// with Scaling
+type Mapping struct {
+	Address  string       `json:"address,omitempty"`
+	Key      string       `json:"key,omitempty"`
+	Control  string       `json:"control,omitempty"`
+	Min      float64      `json:"min"`
+	Max      float64      `json:"max"`
+	Scaling  muse.Scaling `json:"scaling"`
+	Takeover Takeover     `json:"takeover"`
+}
+
+// NewMapping returns a mapping that sets param of the receiver at address to its full range
+func NewMapping(address string, param muse.Parameter) Mapping {
+	return Mapping{
+		Address: address,
+		Key:     param.MessageKey,
+		Min:     param.Min,
+		Max:     param.Max,
+		Scaling: param.Scaling,
+	}
+}
+
+// NewControlMapping returns a mapping that sets ctrl to its full range
+func NewControlMapping(ctrl ControlTarget) Mapping {
+	return Mapping{
+		Control: ctrl.Identifier(),
+		Min:     ctrl.Min(),
+		Max:     ctrl.Max(),
+	}
+}
+
+func (m Mapping) parameter() muse.Parameter {
+	return muse.Parameter{Min: m.Min, Max: m.Max, Scaling: m.Scaling}
+}
+
+// Value maps x in the range 0 - 1 to the range of the mapping
+func (m Mapping) Value(x float64) float64 {
+	return m.parameter().Denormalize(x)
+}
+
+// sameTarget returns true if m and other set the same parameter or control
+func (m Mapping) sameTarget(other Mapping) bool {
+	if m.Control != "" || other.Control != "" {
+		return m.Control == other.Control
+	}
+
+	return m.Address == other.Address && m.Key == other.Key
+}
+
+// binding is a mapping with the state needed for takeover, positions are in the range 0 - 1 and
+// negative if unknown
+type binding struct {
+	Mapping
+	knob    float64
+	current float64
+	picked  bool
+}
+
+func newBinding(m Mapping, current float64) *binding {
+	return &binding{
+		Mapping: m,
+		knob:    -1,
+		current: current,
+	}
+}
+
+// move returns the new position for the parameter when the controller moves to x, false if the
+// parameter should not change
+func (b *binding) move(x float64, current float64) (float64, bool) {
+	knob := b.knob
+	b.knob = x
+
+	if current < 0 {
+		b.picked = true
+		return x, true
+	}
+
+	switch b.Takeover {
+	case TakeoverPickup:
+		if !b.picked {
+			// Picked up when the controller is at or has passed the current value
+			b.picked = math.Abs(x-current) <= 1.0/127.0 || (knob >= 0 && (knob-current)*(x-current) <= 0)
+		}
+
+		return x, b.picked
+	case TakeoverScale:
+		switch {
+		case knob < 0 || x == knob:
+			return 0, false
+		case x > knob:
+			return current + (1-current)*(x-knob)/(1-knob), true
+		default:
+			return current - current*(knob-x)/knob, true
+		}
+	}
+
+	return x, true
+}
+
+// replyQueueSize is the number of reply values the audio thread can hand to the router before the next MIDI message
+const replyQueueSize = 256
+
+// Router converts incoming MIDI messages to muse messages and posts them to the audio thread. Note on and off
+// become trigger messages for a polyphony, controllers and pitch bend are sent to the receivers they are mapped to.
+// Messages on channels that are not listened to are ignored
+type Router struct {
+	lock         sync.Mutex
+	replies      *queue.Ring[replyValue]
+	poster       Poster
+	channels     []uint8
+	noteAddress  string
+	controllers  map[uint8]*binding
+	pitchBend    *binding
+	controls     map[string]ControlTarget
+	learn        *learnState
+	learnHandler func(controller uint8, mapping Mapping)
+}
+
+func NewRouter(poster Poster) *Router {
+	return &Router{
+		poster:      poster,
+		replies:     queue.NewRing[replyValue](replyQueueSize),
+		controllers: map[uint8]*binding{},
+		controls:    map[string]ControlTarget{},
+	}
+}
+
+// SetChannels only listens to channels 0 - 15, without channels the router listens to all channels
+func (r *Router) SetChannels(channels ...uint8) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.channels = slices.Clone(channels)
+}
+
+// RouteNotes sends notes as trigger messages to the polyphony at address, an empty address ignores notes. See
+// NoteOnContent for the trigger content
+func (r *Router) RouteNotes(address string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.noteAddress = address
+}
+
+// RegisterControl makes ctrl available to mappings with its identifier as Control
+func (r *Router) RegisterControl(ctrl ControlTarget) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.controls[ctrl.Identifier()] = ctrl
+}
+
+// MapController maps control change messages for controller
+func (r *Router) MapController(controller uint8, mapping Mapping) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.controllers[controller] = newBinding(mapping, -1)
+}
+
+func (r *Router) UnmapController(controller uint8) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.controllers, controller)
+}
+
+// Controller returns the mapping of controller
+func (r *Router) Controller(controller uint8) (Mapping, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if b, ok := r.controllers[controller]; ok {
+		return b.Mapping, true
+	}
+
+	return Mapping{}, false
+}
+
+// MapPitchBend maps pitch bend messages, the center maps to the middle of the range
+func (r *Router) MapPitchBend(mapping Mapping) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.pitchBend = newBinding(mapping, -1)
+}
+
+func (r *Router) UnmapPitchBend() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.pitchBend = nil
+}
+
+// SetValue tells the router the current value of the parameter at address with key, takeover modes compare
+// the controller with this value. Values set by the router itself are tracked automatically
+func (r *Router) SetValue(address string, key string, value float64) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	// Replies received before are older than this value
+	r.applyReplies()
+
+	target := Mapping{Address: address, Key: key}
+
+	for _, b := range r.bindings() {
+		if b.sameTarget(target) {
+			b.current = b.parameter().Normalize(value)
+			b.picked = false
+		}
+	}
+}
+
+func (r *Router) bindings() []*binding {
+	bindings := make([]*binding, 0, len(r.controllers)+1)
+
+	for _, b := range r.controllers {
+		bindings = append(bindings, b)
+	}
+
+	if r.pitchBend != nil {
+		bindings = append(bindings, r.pitchBend)
+	}
+
+	return bindings
+}
+
+// Listen routes all messages from port until stop is called
+func (r *Router) Listen(port Port) (stop func(), err error) {
+	return port.Listen(r.Receive)
+}
+
+// Receive routes msg, it can be called from any goroutine
+func (r *Router) Receive(msg midi.Message) {
+	m, after := r.convert(msg)
+	if after != nil {
+		after()
+	}
+
+	if m != nil {
+		if err := r.poster.PostMessage(m); err != nil {
+			log.Printf("midi input: %v", err)
+		}
+	}
+}
+
+// convert returns the message for msg and a call to make after the router is unlocked, like the learn handler
+// if msg completed learning or setting a control
+func (r *Router) convert(msg midi.Message) (*muse.Message, func()) {
+	var channel, key, velocity, controller, value uint8
+	var relative int16
+	var absolute uint16
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.applyReplies()
+
+	switch {
+	case msg.GetNoteStart(&channel, &key, &velocity):
+		if r.noteAddress == "" || !r.listensTo(channel) {
+			return nil, nil
+		}
+
+		return muse.NewMessage(r.noteAddress, NoteOnContent(NoteIdentifier(channel, key), channel, key, velocity)), nil
+	case msg.GetNoteEnd(&channel, &key):
+		if r.noteAddress == "" || !r.listensTo(channel) {
+			return nil, nil
+		}
+
+		return muse.NewMessage(r.noteAddress, NoteOffContent(NoteIdentifier(channel, key))), nil
+	case msg.GetControlChange(&channel, &controller, &value):
+		if !r.listensTo(channel) {
+			return nil, nil
+		}
+
+		if r.learn != nil {
+			return nil, r.learnController(controller, float64(value)/127.0)
+		}
+
+		if b, ok := r.controllers[controller]; ok {
+			return r.move(b, float64(value)/127.0)
+		}
+	case msg.GetPitchBend(&channel, &relative, &absolute):
+		if r.pitchBend == nil || !r.listensTo(channel) {
+			return nil, nil
+		}
+
+		return r.move(r.pitchBend, BendPosition(relative))
+	}
+
+	return nil, nil
+}
+
+// move moves the binding to controller position x, a message is returned for parameters and a call that sets
+// the control for controls. The control is set after the router is unlocked so its listeners can use the router
+func (r *Router) move(b *binding, x float64) (*muse.Message, func()) {
+	if b.Control != "" {
+		ctrl, ok := r.controls[b.Control]
+		if !ok {
+			return nil, nil
+		}
+
+		y, ok := b.move(x, b.parameter().Normalize(ctrl.Get()))
+		if !ok {
+			return nil, nil
+		}
+
+		value := b.Value(y)
+
+		return nil, func() {
+			ctrl.Set(value, r)
+		}
+	}
+
+	y, ok := b.move(x, b.current)
+	if !ok {
+		return nil, nil
+	}
+
+	b.current = y
+
+	return muse.NewMessage(b.Address, map[string]any{b.Key: b.Value(y)}), nil
+}
+
+// BendPosition maps a relative pitch bend to the range 0 - 1 with the center exactly at 0.5
+func BendPosition(relative int16) float64 {
+	if relative >= 0 {
+		return 0.5 + float64(relative)/8191.0*0.5
+	}
+
+	return 0.5 + float64(relative)/8192.0*0.5
+}
+
+func (r *Router) listensTo(channel uint8) bool {
+	return len(r.channels) == 0 || slices.Contains(r.channels, channel)
+}
+
+// NoteOnContent returns the polyphony trigger that starts a note with identifier, the message passed to the
+// voice contains frequency, note, velocity and channel
+func NoteOnContent(identifier string, channel uint8, key uint8, velocity uint8) map[string]any {
+	return map[string]any{
+		"command":   "trigger",
+		"noteOn":    identifier,
+		"amplitude": float64(velocity) / 127.0,
+		"message": map[string]any{
+			"frequency": notes.Mtof(int(key)),
+			"note":      int(key),
+			"velocity":  int(velocity),
+			"channel":   int(channel),
+		},
+	}
+}
+
+// NoteOffContent returns the polyphony trigger that stops the note with identifier
+func NoteOffContent(identifier string) map[string]any {
+	return map[string]any{
+		"command": "trigger",
+		"noteOff": identifier,
+	}
+}
+
+// NoteIdentifier returns the polyphony voice identifier for a note, notes with the same key on different
+// channels are separate voices
+func NoteIdentifier(channel uint8, key uint8) string {
+	return fmt.Sprintf("%d:%d", channel, key)
+}
@@ -0,0 +1,339 @@
+package input
+
+import (
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/almerlucke/muse"
+	"github.com/almerlucke/muse/utils/notes"
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// recorder is a poster that records the posted messages
+type recorder struct {
+	lock sync.Mutex
+	msgs []*muse.Message
+}
+
+func (r *recorder) PostMessage(msg *muse.Message) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.msgs = append(r.msgs, msg)
+
+	return nil
+}
+
+// take returns and clears the recorded messages
+func (r *recorder) take() []*muse.Message {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	msgs := r.msgs
+	r.msgs = nil
+
+	return msgs
+}
+
+// newTestRouter returns a router listening to a memory port
+func newTestRouter(t *testing.T) (*Router, *MemoryPort, *recorder) {
+	posted := &recorder{}
+	router := NewRouter(posted)
+	port := NewMemoryPort()
+
+	stop, err := router.Listen(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(stop)
+
+	return router, port, posted
+}
+
+// value returns the value of key in the single posted message to address
+func value(t *testing.T, posted *recorder, address string, key string) (float64, bool) {
+	t.Helper()
+
+	msgs := posted.take()
+	if len(msgs) == 0 {
+		return 0, false
+	}
+
+	if len(msgs) != 1 || msgs[0].Address != address {
+		t.Fatalf("expected a single message to %s, got %v", address, msgs)
+	}
+
+	v, ok := msgs[0].Content.(map[string]any)[key].(float64)
+	if !ok {
+		t.Fatalf("expected a float for %s, got %v", key, msgs[0].Content)
+	}
+
+	return v, true
+}
+
+func expectValue(t *testing.T, posted *recorder, expected float64) {
+	t.Helper()
+
+	v, ok := value(t, posted, "synth.filter", "cutoff")
+	if !ok {
+		t.Fatalf("expected value %v, nothing was posted", expected)
+	}
+
+	if math.Abs(v-expected) > 1e-9 {
+		t.Fatalf("expected value %v, got %v", expected, v)
+	}
+}
+
+func expectNothing(t *testing.T, posted *recorder) {
+	t.Helper()
+
+	if msgs := posted.take(); len(msgs) != 0 {
+		t.Fatalf("expected nothing to be posted, got %v", msgs[0].Content)
+	}
+}
+
+var cutoffMapping = Mapping{Address: "synth.filter", Key: "cutoff", Min: 100, Max: 1100}
+
+func TestNotes(t *testing.T) {
+	router, port, posted := newTestRouter(t)
+	router.RouteNotes("synth")
+
+	port.Send(midi.NoteOn(2, 60, 100))
+	port.Send(midi.NoteOff(2, 60))
+	// Note on with velocity 0 is a note off
+	port.Send(midi.NoteOn(2, 62, 0))
+
+	expected := []*muse.Message{
+		muse.NewMessage("synth", map[string]any{
+			"command":   "trigger",
+			"noteOn":    "2:60",
+			"amplitude": 100.0 / 127.0,
+			"message": map[string]any{
+				"frequency": notes.Mtof(60),
+				"note":      60,
+				"velocity":  100,
+				"channel":   2,
+			},
+		}),
+		muse.NewMessage("synth", map[string]any{"command": "trigger", "noteOff": "2:60"}),
+		muse.NewMessage("synth", map[string]any{"command": "trigger", "noteOff": "2:62"}),
+	}
+
+	if msgs := posted.take(); !reflect.DeepEqual(msgs, expected) {
+		t.Fatalf("posted %v, expected %v", msgs, expected)
+	}
+
+	router.RouteNotes("")
+	port.Send(midi.NoteOn(2, 60, 100))
+	expectNothing(t, posted)
+}
+
+func TestChannels(t *testing.T) {
+	router, port, posted := newTestRouter(t)
+	router.RouteNotes("synth")
+	router.MapController(7, cutoffMapping)
+	router.MapPitchBend(cutoffMapping)
+	router.SetChannels(1, 3)
+
+	port.Send(midi.NoteOn(0, 60, 100))
+	port.Send(midi.ControlChange(2, 7, 127))
+	port.Send(midi.Pitchbend(15, 0))
+	expectNothing(t, posted)
+
+	port.Send(midi.NoteOn(3, 60, 100))
+	if msgs := posted.take(); len(msgs) != 1 {
+		t.Fatalf("expected a note on channel 3, got %v", msgs)
+	}
+
+	port.Send(midi.ControlChange(1, 7, 127))
+	expectValue(t, posted, 1100)
+
+	// Without channels the router listens to all channels
+	router.SetChannels()
+	port.Send(midi.ControlChange(9, 7, 0))
+	expectValue(t, posted, 100)
+}
+
+func TestControllerRange(t *testing.T) {
+	router, port, posted := newTestRouter(t)
+	router.MapController(7, cutoffMapping)
+
+	for _, cc := range []uint8{0, 1, 64, 126, 127} {
+		port.Send(midi.ControlChange(0, 7, cc))
+		expectValue(t, posted, 100+1000*float64(cc)/127.0)
+	}
+
+	// Unmapped controllers are ignored
+	port.Send(midi.ControlChange(0, 8, 64))
+	expectNothing(t, posted)
+
+	exponential := Mapping{Address: "synth.filter", Key: "cutoff", Min: 100, Max: 10000, Scaling: muse.ScalingExponential}
+	router.MapController(7, exponential)
+
+	port.Send(midi.ControlChange(0, 7, 0))
+	expectValue(t, posted, 100)
+	port.Send(midi.ControlChange(0, 7, 127))
+	expectValue(t, posted, 10000)
+}
+
+func TestPitchBendRange(t *testing.T) {
+	router, port, posted := newTestRouter(t)
+	router.MapPitchBend(cutoffMapping)
+
+	bends := map[int16]float64{
+		-8192: 100,
+		-4096: 350,
+		0:     600,
+		8191:  1100,
+	}
+
+	for bend, expected := range bends {
+		port.Send(midi.Pitchbend(0, bend))
+		expectValue(t, posted, expected)
+	}
+
+	router.UnmapPitchBend()
+	port.Send(midi.Pitchbend(0, 0))
+	expectNothing(t, posted)
+}
+
+func TestPickupTakeover(t *testing.T) {
+	router, port, posted := newTestRouter(t)
+
+	mapping := cutoffMapping
+	mapping.Takeover = TakeoverPickup
+	router.MapController(7, mapping)
+	router.SetValue("synth.filter", "cutoff", 600)
+
+	// The controller is ignored until it passes the current value
+	port.Send(midi.ControlChange(0, 7, 0))
+	port.Send(midi.ControlChange(0, 7, 32))
+	expectNothing(t, posted)
+
+	port.Send(midi.ControlChange(0, 7, 80))
+	expectValue(t, posted, 100+1000*80.0/127.0)
+
+	port.Send(midi.ControlChange(0, 7, 10))
+	expectValue(t, posted, 100+1000*10.0/127.0)
+
+	// A controller at the current value picks up right away
+	router.SetValue("synth.filter", "cutoff", 100+1000*100.0/127.0)
+	port.Send(midi.ControlChange(0, 7, 100))
+	expectValue(t, posted, 100+1000*100.0/127.0)
+
+	// A reply from the audio thread drops the pickup again
+	router.ReceiveMessage(map[string]any{
+		"command": muse.CommandReply,
+		"address": "synth.filter",
+		"values":  map[string]any{"cutoff": 1100.0},
+	})
+
+	port.Send(midi.ControlChange(0, 7, 120))
+	expectNothing(t, posted)
+
+	port.Send(midi.ControlChange(0, 7, 127))
+	expectValue(t, posted, 1100)
+}
+
+func TestScaleTakeover(t *testing.T) {
+	router, port, posted := newTestRouter(t)
+
+	mapping := cutoffMapping
+	mapping.Takeover = TakeoverScale
+	router.MapController(7, mapping)
+	router.SetValue("synth.filter", "cutoff", 600)
+
+	// The first message only tells the position of the controller
+	port.Send(midi.ControlChange(0, 7, 32))
+	expectNothing(t, posted)
+
+	knob := 32.0 / 127.0
+	x := 64.0 / 127.0
+	current := 0.5 + 0.5*(x-knob)/(1-knob)
+
+	port.Send(midi.ControlChange(0, 7, 64))
+	expectValue(t, posted, 100+1000*current)
+
+	// The value reaches the end of the range together with the controller
+	port.Send(midi.ControlChange(0, 7, 127))
+	expectValue(t, posted, 1100)
+
+	port.Send(midi.ControlChange(0, 7, 0))
+	expectValue(t, posted, 100)
+}
+
+// testControl is a control target that uses the router from its setter like a ui listener could
+type testControl struct {
+	router *Router
+	value  float64
+	setter any
+}
+
+func (c *testControl) Identifier() string { return "cutoff" }
+func (c *testControl) Min() float64       { return 0 }
+func (c *testControl) Max() float64       { return 10 }
+func (c *testControl) Get() float64       { return c.value }
+
+func (c *testControl) Set(value float64, setter any) {
+	// The router must be unlocked when the control is set
+	c.router.Controller(7)
+
+	c.value = value
+	c.setter = setter
+}
+
+func TestControlMapping(t *testing.T) {
+	router, port, posted := newTestRouter(t)
+
+	ctrl := &testControl{router: router, value: 5}
+	router.RegisterControl(ctrl)
+
+	mapping := NewControlMapping(ctrl)
+	mapping.Takeover = TakeoverPickup
+	router.MapController(7, mapping)
+
+	port.Send(midi.ControlChange(0, 7, 0))
+	if ctrl.value != 5 {
+		t.Fatalf("control was set to %v before pickup", ctrl.value)
+	}
+
+	port.Send(midi.ControlChange(0, 7, 127))
+	if ctrl.value != 10 || ctrl.setter != router {
+		t.Fatalf("control was set to %v by %v, expected 10 by the router", ctrl.value, ctrl.setter)
+	}
+
+	expectNothing(t, posted)
+}
+
+func TestLearn(t *testing.T) {
+	router, port, posted := newTestRouter(t)
+
+	var learned uint8
+
+	router.SetLearnHandler(func(controller uint8, mapping Mapping) {
+		// The router must be unlocked when the handler is called
+		router.Controller(controller)
+		learned = controller
+	})
+
+	router.MapController(3, cutoffMapping)
+	router.Arm(cutoffMapping)
+
+	port.Send(midi.ControlChange(0, 9, 64))
+	expectNothing(t, posted)
+
+	if learned != 9 || router.Armed() {
+		t.Fatalf("controller %d learned, armed %v", learned, router.Armed())
+	}
+
+	// The learned controller replaces the controller mapped to the same target
+	if _, ok := router.Controller(3); ok {
+		t.Fatal("controller 3 is still mapped")
+	}
+
+	port.Send(midi.ControlChange(0, 9, 127))
+	expectValue(t, posted, 1100)
+}
@@ -0,0 +1,273 @@
+package input
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+
+	"github.com/almerlucke/muse"
+	"github.com/almerlucke/muse/utils"
+)
+
+// learnState is the mapping waiting for a controller in learn mode
+type learnState struct {
+	mapping Mapping
+	current float64
+}
+
+// Arm starts learn mode, the next controller that moves on a listened channel is mapped with mapping and
+// replaces any other controller mapped to the same target. Controllers are not routed while armed
+func (r *Router) Arm(mapping Mapping) {
+	r.arm(mapping, -1)
+}
+
+// ArmParameter arms the parameter of the receiver at address, the current value of param is used for takeover
+func (r *Router) ArmParameter(address string, param muse.Parameter, takeover Takeover) {
+	mapping := NewMapping(address, param)
+	mapping.Takeover = takeover
+
+	current := -1.0
+	if v, ok := utils.ToFloat(param.Value); ok {
+		current = mapping.parameter().Normalize(v)
+	}
+
+	r.arm(mapping, current)
+}
+
+// ArmControl registers and arms ctrl
+func (r *Router) ArmControl(ctrl ControlTarget, takeover Takeover) {
+	r.RegisterControl(ctrl)
+
+	mapping := NewControlMapping(ctrl)
+	mapping.Takeover = takeover
+
+	r.arm(mapping, -1)
+}
+
+func (r *Router) arm(mapping Mapping, current float64) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.learn = &learnState{mapping: mapping, current: current}
+}
+
+// Disarm stops learn mode without mapping a controller
+func (r *Router) Disarm() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.learn = nil
+}
+
+func (r *Router) Armed() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.learn != nil
+}
+
+// SetLearnHandler sets the function that is called with the learned controller and mapping when learn
+// mode completes, it is called from the goroutine that delivers MIDI
+func (r *Router) SetLearnHandler(handler func(controller uint8, mapping Mapping)) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.learnHandler = handler
+}
+
+// learnController maps controller at position x to the armed mapping and returns the learn handler call
+func (r *Router) learnController(controller uint8, x float64) func() {
+	mapping := r.learn.mapping
+
+	for other, b := range r.controllers {
+		if b.sameTarget(mapping) {
+			delete(r.controllers, other)
+		}
+	}
+
+	b := newBinding(mapping, r.learn.current)
+	b.knob = x
+	r.controllers[controller] = b
+	r.learn = nil
+
+	if handler := r.learnHandler; handler != nil {
+		return func() {
+			handler(controller, mapping)
+		}
+	}
+
+	return nil
+}
+
+// Mappings are the controller and pitch bend mappings of a router, they can be stored as JSON
+type Mappings struct {
+	Controllers map[uint8]Mapping `json:"controllers"`
+	PitchBend   *Mapping          `json:"pitchBend,omitempty"`
+}
+
+// Mappings returns a copy of the current mappings
+func (r *Router) Mappings() Mappings {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	ms := Mappings{Controllers: make(map[uint8]Mapping, len(r.controllers))}
+
+	for controller, b := range r.controllers {
+		ms.Controllers[controller] = b.Mapping
+	}
+
+	if r.pitchBend != nil {
+		pitchBend := r.pitchBend.Mapping
+		ms.PitchBend = &pitchBend
+	}
+
+	return ms
+}
+
+// SetMappings replaces all mappings, the current values of parameters are unknown until they are set with
+// SetValue or synced with SyncMessages
+func (r *Router) SetMappings(ms Mappings) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.controllers = make(map[uint8]*binding, len(ms.Controllers))
+
+	for controller, mapping := range ms.Controllers {
+		r.controllers[controller] = newBinding(mapping, -1)
+	}
+
+	r.pitchBend = nil
+
+	if ms.PitchBend != nil {
+		r.pitchBend = newBinding(*ms.PitchBend, -1)
+	}
+}
+
+// SaveMappings writes the mappings as JSON to filePath
+func (r *Router) SaveMappings(filePath string) error {
+	data, err := json.MarshalIndent(r.Mappings(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filePath, data, 0666)
+}
+
+// LoadMappings replaces all mappings with the mappings stored in filePath
+func (r *Router) LoadMappings(filePath string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	var ms Mappings
+
+	if err := json.Unmarshal(data, &ms); err != nil {
+		return err
+	}
+
+	r.SetMappings(ms)
+
+	return nil
+}
+
+// SyncMessages returns get queries for the parameters of all message mappings, add the router to the patch as
+// receiver at replyTo and post the queries so takeover modes know the current values
+func (r *Router) SyncMessages(replyTo string) []*muse.Message {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	keys := map[string][]any{}
+
+	for _, b := range r.bindings() {
+		if b.Control == "" && !slices.Contains(keys[b.Address], any(b.Key)) {
+			keys[b.Address] = append(keys[b.Address], b.Key)
+		}
+	}
+
+	addresses := make([]string, 0, len(keys))
+	for address := range keys {
+		addresses = append(addresses, address)
+	}
+
+	slices.Sort(addresses)
+
+	msgs := make([]*muse.Message, len(addresses))
+
+	for i, address := range addresses {
+		msgs[i] = muse.NewMessage(address, map[string]any{
+			"command":    muse.CommandGet,
+			"replyTo":    replyTo,
+			"parameters": keys[address],
+		})
+	}
+
+	return msgs
+}
+
+// replyValue is a parameter value from a reply, it is handed from the audio thread to the MIDI side of the router
+type replyValue struct {
+	address string
+	name    string
+	value   float64
+}
+
+// ReceiveMessage receives the replies to get and dump queries on the audio thread. The values are queued without
+// locking the router, they set the current values of mapped parameters before the next MIDI message is routed
+func (r *Router) ReceiveMessage(msg any) []*muse.Message {
+	content, ok := muse.DecodeMessage(r, msg)
+	if !ok {
+		return nil
+	}
+
+	if command, _ := content.Value("command"); command != muse.CommandReply {
+		return nil
+	}
+
+	address, _ := content.String("address")
+
+	values, ok := content.Value("values")
+	if !ok {
+		return nil
+	}
+
+	valueMap, ok := values.(map[string]any)
+	if !ok {
+		content.Invalid("values", "expected map content")
+		return nil
+	}
+
+	for name, value := range valueMap {
+		if f, ok := utils.ToFloat(value); ok {
+			// Values are dropped if the MIDI side does not keep up, a later reply sets them again
+			r.replies.Push(replyValue{address: address, name: name, value: f})
+		}
+	}
+
+	return nil
+}
+
+// applyReplies sets the current values of mapped parameters from the queued replies, the router must be locked
+func (r *Router) applyReplies() {
+	for {
+		reply, ok := r.replies.Pop()
+		if !ok {
+			return
+		}
+
+		for _, b := range r.bindings() {
+			if b.Control == "" && joinAddress(b.Address, b.Key) == joinAddress(reply.address, reply.name) {
+				b.current = b.parameter().Normalize(reply.value)
+				b.picked = false
+			}
+		}
+	}
+}
+
+func joinAddress(address string, name string) string {
+	if address == "" {
+		return name
+	}
+
+	return address + "." + name
+}
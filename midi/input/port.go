@@ -0,0 +1,73 @@
+package input
+
+import (
+	"sync"
+
+	"gitlab.com/gomidi/midi/v2"
+	"gitlab.com/gomidi/midi/v2/drivers"
+)
+
+// Port delivers incoming MIDI messages to a callback until stop is called
+type Port interface {
+	Listen(recv func(msg midi.Message)) (stop func(), err error)
+}
+
+// DriverPort listens to an input port of a MIDI driver like rtmididrv
+type DriverPort struct {
+	In drivers.In
+}
+
+// FindPort returns the driver input port with name, a driver must be registered with a blank import
+func FindPort(name string) (*DriverPort, error) {
+	in, err := midi.FindInPort(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DriverPort{In: in}, nil
+}
+
+func (p *DriverPort) Listen(recv func(msg midi.Message)) (func(), error) {
+	return midi.ListenTo(p.In, func(msg midi.Message, _ int32) {
+		recv(msg)
+	})
+}
+
+// MemoryPort is an in-memory port, messages passed to Send are delivered to all listeners
+type MemoryPort struct {
+	lock      sync.Mutex
+	listeners map[int]func(msg midi.Message)
+	nextID    int
+}
+
+func NewMemoryPort() *MemoryPort {
+	return &MemoryPort{
+		listeners: map[int]func(msg midi.Message){},
+	}
+}
+
+func (p *MemoryPort) Listen(recv func(msg midi.Message)) (func(), error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	id := p.nextID
+	p.nextID++
+	p.listeners[id] = recv
+
+	return func() {
+		p.lock.Lock()
+		defer p.lock.Unlock()
+
+		delete(p.listeners, id)
+	}, nil
+}
+
+// Send delivers msg to all listeners
+func (p *MemoryPort) Send(msg midi.Message) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, recv := range p.listeners {
+		recv(msg)
+	}
+}
@@ -0,0 +1,172 @@
+package midifile
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/almerlucke/muse/messengers/scheduler"
+	"github.com/almerlucke/muse/midi/input"
+	"gitlab.com/gomidi/midi/v2"
+	"gitlab.com/gomidi/midi/v2/smf"
+)
+
+const testSampleRate = 48000.0
+
+// testClock is a clock that is set by the test
+type testClock struct {
+	timestamp int64
+}
+
+func (c *testClock) Timestamp() int64 {
+	return c.timestamp
+}
+
+// describe returns a line for each message of the events with the time in milliseconds
+func describe(events []*scheduler.Event) []string {
+	var lines []string
+
+	for _, event := range events {
+		for _, msg := range event.Messages {
+			content := msg.Content.(map[string]any)
+
+			var line string
+
+			switch {
+			case content["noteOn"] != nil:
+				line = fmt.Sprintf("on %v %v %.2f", content["noteOn"], content["message"].(map[string]any)["note"], content["amplitude"])
+			case content["noteOff"] != nil:
+				line = fmt.Sprintf("off %v", content["noteOff"])
+			default:
+				line = fmt.Sprint(content)
+			}
+
+			lines = append(lines, fmt.Sprintf("%.0f %s %s", event.When, msg.Address, line))
+		}
+	}
+
+	return lines
+}
+
+func expectEvents(t *testing.T, events []*scheduler.Event, expected []string) {
+	t.Helper()
+
+	if lines := describe(events); !slices.Equal(lines, expected) {
+		t.Fatalf("read\n%v\nexpected\n%v", lines, expected)
+	}
+}
+
+// trigger returns a polyphony trigger message
+func trigger(values map[string]any) map[string]any {
+	values["command"] = "trigger"
+	return values
+}
+
+func TestRecordRoundTrip(t *testing.T) {
+	clock := &testClock{}
+	rec := NewRecorder(clock, testSampleRate, 120, 960)
+	lead := rec.AddTrack("lead", 2)
+	bass := rec.AddTrack("bass", 3)
+
+	// Times are in samples, a beat at 120 bpm lasts 500 ms
+	at := func(ms float64) {
+		clock.timestamp = int64(ms * testSampleRate / 1000.0)
+	}
+
+	lead.ReceiveMessageAt(trigger(map[string]any{"noteOn": "a", "amplitude": 1.0, "message": map[string]any{"note": 60}}), 0)
+	// Notes of a voice are found from the frequency when there is no note
+	bass.ReceiveMessageAt(trigger(map[string]any{"duration": 250.0, "amplitude": 0.5, "message": map[string]any{"frequency": 110.0}}), 0)
+
+	at(500)
+	lead.ReceiveMessageAt(trigger(map[string]any{"noteOff": "a"}), 0)
+	// Offsets are samples within the current block
+	lead.ReceiveMessageAt(trigger(map[string]any{"noteOn": "b", "amplitude": 1.0, "message": map[string]any{"note": 64}}), int(testSampleRate/4))
+
+	at(1000)
+	// Retriggering a voice ends its previous note
+	lead.ReceiveMessageAt(trigger(map[string]any{"noteOn": "b", "amplitude": 1.0, "message": map[string]any{"note": 67}}), 0)
+
+	// The sounding note is ended at the time the file is written
+	at(1500)
+
+	var buf bytes.Buffer
+	if err := rec.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// Track 0 holds the tempo
+	events, err := Read(&buf, Routing{Tracks: map[int]string{1: "lead", 2: "bass"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectEvents(t, events, []string{
+		"0 lead on 1:2:60:0 60 1.00",
+		"0 bass on 2:3:45:3 45 0.50",
+		"250 bass off 2:3:45:3",
+		"500 lead off 1:2:60:0",
+		"750 lead on 1:2:64:1 64 1.00",
+		"1000 lead off 1:2:64:1",
+		"1000 lead on 1:2:67:2 67 1.00",
+		"1500 lead off 1:2:67:2",
+	})
+}
+
+func TestReadTempoAndRouting(t *testing.T) {
+	file := smf.NewSMF1()
+	file.TimeFormat = smf.MetricTicks(960)
+
+	var tempo smf.Track
+	tempo.Add(0, smf.MetaTempo(120))
+	// Half time after the first beat
+	tempo.Add(960, smf.MetaTempo(60))
+	tempo.Close(0)
+
+	var notes smf.Track
+	notes.Add(0, midi.NoteOn(0, 60, 127))
+	// A second note with the same key while the first one sounds
+	notes.Add(960, midi.NoteOn(0, 60, 64))
+	notes.Add(960, midi.NoteOff(0, 60))
+	notes.Add(0, midi.ControlChange(0, 7, 127))
+	notes.Add(0, midi.NoteOn(9, 36, 127))
+	// The second note is still on at the end of the track
+	notes.Close(960)
+
+	for _, track := range []smf.Track{tempo, notes} {
+		if err := file.Add(track); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := file.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	routing := Routing{
+		Channels:    map[uint8]string{9: "drums"},
+		Default:     "synth",
+		Controllers: map[uint8]input.Mapping{7: {Address: "synth.filter", Key: "cutoff", Min: 100, Max: 1100}},
+	}
+
+	events, err := Read(&buf, routing)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	velocity := 64.0 / 127.0
+	velocity = math.Round(velocity*100) / 100
+
+	// The oldest note with the same key ends first
+	expectEvents(t, events, []string{
+		"0 synth on 1:0:60:0 60 1.00",
+		fmt.Sprintf("500 synth on 1:0:60:1 60 %.2f", velocity),
+		"1500 synth off 1:0:60:0",
+		"1500 synth.filter map[cutoff:1100]",
+		"1500 drums on 1:9:36:2 36 1.00",
+		"2500 synth off 1:0:60:1",
+		"2500 drums off 1:9:36:2",
+	})
+}
@@ -0,0 +1,203 @@
+package midifile
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/almerlucke/muse"
+	"github.com/almerlucke/muse/messengers/scheduler"
+	"github.com/almerlucke/muse/midi/input"
+	"gitlab.com/gomidi/midi/v2/smf"
+)
+
+// Routing decides where the notes and controllers of a MIDI file are sent. Notes go to the polyphony address of
+// their track, if the track is not routed to the address of their channel and otherwise to Default. Notes without
+// address are skipped. Controllers and pitch bend are sent with their mapping regardless of track and channel
+type Routing struct {
+	Tracks      map[int]string
+	Channels    map[uint8]string
+	Default     string
+	Controllers map[uint8]input.Mapping
+	PitchBend   *input.Mapping
+}
+
+func (r Routing) noteAddress(track int, channel uint8) string {
+	if address, ok := r.Tracks[track]; ok {
+		return address
+	}
+
+	if address, ok := r.Channels[channel]; ok {
+		return address
+	}
+
+	return r.Default
+}
+
+// noteKey identifies the sounding notes that a note off can end
+type noteKey struct {
+	track   int
+	channel uint8
+	key     uint8
+}
+
+// soundingNote is a note that is on, serial orders the notes by their start
+type soundingNote struct {
+	identifier string
+	address    string
+	serial     int
+}
+
+// ReadFile reads a standard MIDI file and returns the scheduler events, see Read
+func ReadFile(filePath string, routing Routing) ([]*scheduler.Event, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return Read(f, routing)
+}
+
+// Read reads a standard MIDI file of format 0 or 1 and returns the scheduler events ordered by time, times follow
+// the tempo map of the file. Every note gets a unique identifier so repeated and overlapping notes with the same
+// key are ended by their own note off. Notes that are still on at the end of their track are ended there
+func Read(r io.Reader, routing Routing) ([]*scheduler.Event, error) {
+	file, err := smf.ReadFrom(r)
+	if err != nil {
+		return nil, err
+	}
+
+	timeAt, err := timeFunction(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		events   = map[float64]*scheduler.Event{}
+		sounding = map[noteKey][]soundingNote{}
+		serial   int
+	)
+
+	schedule := func(ticks int64, address string, content any) {
+		when := timeAt(ticks)
+
+		event, ok := events[when]
+		if !ok {
+			event = &scheduler.Event{When: when}
+			events[when] = event
+		}
+
+		event.Messages = append(event.Messages, muse.NewMessage(address, content))
+	}
+
+	for trackIndex, track := range file.Tracks {
+		var ticks int64
+
+		for _, ev := range track {
+			var channel, key, velocity, controller, value uint8
+			var relative int16
+			var absolute uint16
+
+			ticks += int64(ev.Delta)
+			msg := ev.Message
+
+			switch {
+			case msg.GetNoteStart(&channel, &key, &velocity):
+				address := routing.noteAddress(trackIndex, channel)
+				if address == "" {
+					continue
+				}
+
+				nk := noteKey{track: trackIndex, channel: channel, key: key}
+				identifier := fmt.Sprintf("%d:%d:%d:%d", trackIndex, channel, key, serial)
+				sounding[nk] = append(sounding[nk], soundingNote{identifier: identifier, address: address, serial: serial})
+				serial++
+
+				schedule(ticks, address, input.NoteOnContent(identifier, channel, key, velocity))
+			case msg.GetNoteEnd(&channel, &key):
+				nk := noteKey{track: trackIndex, channel: channel, key: key}
+				if len(sounding[nk]) == 0 {
+					continue
+				}
+
+				// The oldest sounding note with the same key ends first
+				note := sounding[nk][0]
+				sounding[nk] = sounding[nk][1:]
+
+				schedule(ticks, note.address, input.NoteOffContent(note.identifier))
+			case msg.GetControlChange(&channel, &controller, &value):
+				if mapping, ok := routing.Controllers[controller]; ok && mapping.Address != "" {
+					schedule(ticks, mapping.Address, map[string]any{mapping.Key: mapping.Value(float64(value) / 127.0)})
+				}
+			case msg.GetPitchBend(&channel, &relative, &absolute):
+				if mapping := routing.PitchBend; mapping != nil && mapping.Address != "" {
+					schedule(ticks, mapping.Address, map[string]any{mapping.Key: mapping.Value(input.BendPosition(relative))})
+				}
+			}
+		}
+
+		// End the notes in the order they started so a file is always read the same
+		var remaining []soundingNote
+
+		for nk, notes := range sounding {
+			remaining = append(remaining, notes...)
+			delete(sounding, nk)
+		}
+
+		slices.SortFunc(remaining, func(a, b soundingNote) int {
+			return cmp.Compare(a.serial, b.serial)
+		})
+
+		for _, note := range remaining {
+			schedule(ticks, note.address, input.NoteOffContent(note.identifier))
+		}
+	}
+
+	sorted := make([]*scheduler.Event, 0, len(events))
+	for _, event := range events {
+		sorted = append(sorted, event)
+	}
+
+	slices.SortFunc(sorted, func(a, b *scheduler.Event) int {
+		switch {
+		case a.When < b.When:
+			return -1
+		case a.When > b.When:
+			return 1
+		}
+
+		return 0
+	})
+
+	return sorted, nil
+}
+
+// timeFunction returns the function that converts absolute ticks to milliseconds
+func timeFunction(file *smf.SMF) (func(ticks int64) float64, error) {
+	switch tf := file.TimeFormat.(type) {
+	case smf.MetricTicks:
+		return func(ticks int64) float64 {
+			return float64(file.TimeAt(ticks)) / 1000.0
+		}, nil
+	case smf.TimeCode:
+		fps := float64(tf.FramesPerSecond)
+		if tf.FramesPerSecond == 29 {
+			fps = 29.97
+		}
+
+		ticksPerSecond := fps * float64(tf.SubFrames)
+		if ticksPerSecond == 0 {
+			return nil, fmt.Errorf("invalid time code %v", tf)
+		}
+
+		return func(ticks int64) float64 {
+			return float64(ticks) / ticksPerSecond * 1000.0
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported time format %v", file.TimeFormat)
+}
@@ -0,0 +1,332 @@
+package midifile
+
+import (
+	"cmp"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/almerlucke/muse"
+	"github.com/almerlucke/muse/utils"
+	"gitlab.com/gomidi/midi/v2"
+	"gitlab.com/gomidi/midi/v2/smf"
+)
+
+// Clock returns the sample time of the current block, it is implemented by patches
+type Clock interface {
+	Timestamp() int64
+}
+
+// recordedEvent is a MIDI message at a sample time
+type recordedEvent struct {
+	samples int64
+	msg     midi.Message
+}
+
+// Recorder records notes with sample timestamps during live or offline rendering and writes them as a multi-track
+// standard MIDI file. Tracks record polyphony trigger messages they receive or MIDI messages from a NoteGen
+type Recorder struct {
+	lock       sync.Mutex
+	clock      Clock
+	sampleRate float64
+	bpm        float64
+	resolution smf.MetricTicks
+	tracks     []*Track
+}
+
+// NewRecorder returns a recorder that reads the time from clock, the file is written at bpm with resolution
+// ticks per quarter note
+func NewRecorder(clock Clock, sampleRate float64, bpm float64, resolution uint16) *Recorder {
+	return &Recorder{
+		clock:      clock,
+		sampleRate: sampleRate,
+		bpm:        bpm,
+		resolution: smf.MetricTicks(resolution),
+	}
+}
+
+// AddTrack adds a track that records on channel
+func (r *Recorder) AddTrack(name string, channel uint8) *Track {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	t := &Track{
+		recorder: r,
+		name:     name,
+		channel:  channel,
+		active:   map[string]uint8{},
+	}
+
+	r.tracks = append(r.tracks, t)
+
+	return t
+}
+
+// Clear removes all recorded events
+func (r *Recorder) Clear() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, t := range r.tracks {
+		t.events = nil
+		clear(t.active)
+	}
+}
+
+// WriteFile writes the recording to filePath, see Write
+func (r *Recorder) WriteFile(filePath string) error {
+	f, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+
+	if err := r.Write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// trackSnapshot is a copy of the events of a track taken while the recorder is locked
+type trackSnapshot struct {
+	name   string
+	events []recordedEvent
+}
+
+// snapshot copies the recorded events, the tracks keep recording while the file is written
+func (r *Recorder) snapshot() ([]trackSnapshot, int64) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	tracks := make([]trackSnapshot, len(r.tracks))
+	for i, t := range r.tracks {
+		tracks[i] = trackSnapshot{name: t.name, events: slices.Clone(t.events)}
+	}
+
+	return tracks, r.clock.Timestamp()
+}
+
+// Write writes the recording as format 1 file with a tempo track followed by a track for each recorded track,
+// notes that are still on are ended at the current time. The recorder is only locked to copy the events, so
+// writing while recording does not block the audio thread
+func (r *Recorder) Write(w io.Writer) error {
+	tracks, end := r.snapshot()
+
+	file := smf.NewSMF1()
+	file.TimeFormat = r.resolution
+
+	var tempo smf.Track
+	tempo.Add(0, smf.MetaTempo(r.bpm))
+	tempo.Close(0)
+
+	if err := file.Add(tempo); err != nil {
+		return err
+	}
+
+	for _, t := range tracks {
+		events := t.events
+
+		// Keep the recorded order for events at the same time, a note off before a note on with the same key
+		slices.SortStableFunc(events, func(a, b recordedEvent) int {
+			return cmp.Compare(a.samples, b.samples)
+		})
+
+		events = endNotes(events, end)
+
+		var track smf.Track
+		var last int64
+
+		track.Add(0, smf.MetaTrackSequenceName(t.name))
+
+		for _, event := range events {
+			ticks := r.ticks(event.samples)
+			track.Add(uint32(ticks-last), event.msg)
+			last = ticks
+		}
+
+		track.Close(0)
+
+		if err := file.Add(track); err != nil {
+			return err
+		}
+	}
+
+	_, err := file.WriteTo(w)
+
+	return err
+}
+
+// endNotes appends note offs at end for all notes in events that are still on
+func endNotes(events []recordedEvent, end int64) []recordedEvent {
+	type note struct{ channel, key uint8 }
+
+	sounding := map[note]int{}
+
+	for _, event := range events {
+		var channel, key, velocity uint8
+
+		switch {
+		case event.msg.GetNoteStart(&channel, &key, &velocity):
+			sounding[note{channel, key}]++
+		case event.msg.GetNoteEnd(&channel, &key):
+			if sounding[note{channel, key}] > 0 {
+				sounding[note{channel, key}]--
+			}
+		}
+	}
+
+	if len(sounding) == 0 {
+		return events
+	}
+
+	end = max(end, events[len(events)-1].samples)
+
+	for channel := 0; channel < 16; channel++ {
+		for key := 0; key < 128; key++ {
+			for count := sounding[note{uint8(channel), uint8(key)}]; count > 0; count-- {
+				events = append(events, recordedEvent{samples: end, msg: midi.NoteOff(uint8(channel), uint8(key))})
+			}
+		}
+	}
+
+	return events
+}
+
+func (r *Recorder) ticks(samples int64) int64 {
+	return int64(math.Round(float64(samples) / r.sampleRate * r.bpm / 60.0 * float64(r.resolution)))
+}
+
+// Track records the notes of one instrument. Add it to a patch as message receiver and send it the same trigger
+// messages as the polyphony, or let it forward them. Its Send method can be used as send function of a NoteGen
+type Track struct {
+	recorder *Recorder
+	name     string
+	channel  uint8
+	forward  []string
+	events   []recordedEvent
+	// active maps the identifiers of sounding notes to their key
+	active map[string]uint8
+}
+
+// Forward passes all received messages on to addresses
+func (t *Track) Forward(addresses ...string) *Track {
+	t.forward = addresses
+
+	return t
+}
+
+func (t *Track) record(offset int, msg midi.Message) {
+	t.events = append(t.events, recordedEvent{samples: t.recorder.clock.Timestamp() + int64(offset), msg: msg})
+}
+
+// Send records a MIDI message, other messages than notes are recorded as well
+func (t *Track) Send(msg midi.Message) error {
+	t.recorder.lock.Lock()
+	defer t.recorder.lock.Unlock()
+
+	t.record(0, msg)
+
+	return nil
+}
+
+// SendTo returns a send function that records messages before passing them to send
+func (t *Track) SendTo(send func(msg midi.Message) error) func(msg midi.Message) error {
+	return func(msg midi.Message) error {
+		_ = t.Send(msg)
+		return send(msg)
+	}
+}
+
+func (t *Track) ReceiveMessage(msg any) []*muse.Message {
+	return t.ReceiveMessageAt(msg, 0)
+}
+
+// ReceiveMessageAt records a polyphony trigger at a sample offset within the current block, the key is taken
+// from the note or frequency in the message for the voice and the velocity from the amplitude
+func (t *Track) ReceiveMessageAt(msg any, offset int) []*muse.Message {
+	t.recordTrigger(msg, offset)
+
+	if len(t.forward) == 0 {
+		return nil
+	}
+
+	msgs := make([]*muse.Message, len(t.forward))
+	for i, address := range t.forward {
+		msgs[i] = muse.NewMessage(address, msg)
+	}
+
+	return msgs
+}
+
+func (t *Track) recordTrigger(msg any, offset int) {
+	content, ok := muse.DecodeMessage(t, msg)
+	if !ok {
+		return
+	}
+
+	if command, _ := content.Value("command"); command != "trigger" {
+		return
+	}
+
+	t.recorder.lock.Lock()
+	defer t.recorder.lock.Unlock()
+
+	if identifier, ok := content.String("noteOff"); ok {
+		if key, ok := t.active[identifier]; ok {
+			delete(t.active, identifier)
+			t.record(offset, midi.NoteOff(t.channel, key))
+		}
+
+		return
+	}
+
+	amplitude, _ := content.Float("amplitude")
+	velocity := uint8(math.Max(1, math.Min(127, math.Round(amplitude*127.0))))
+
+	raw, _ := content.Value("message")
+
+	key, ok := noteKeyOf(raw)
+	if !ok {
+		return
+	}
+
+	if identifier, ok := content.String("noteOn"); ok {
+		if previous, ok := t.active[identifier]; ok {
+			// A voice retriggered with the same identifier ends its previous note
+			t.record(offset, midi.NoteOff(t.channel, previous))
+		}
+
+		t.active[identifier] = key
+		t.record(offset, midi.NoteOn(t.channel, key, velocity))
+	} else if duration, ok := content.Float("duration"); ok {
+		t.record(offset, midi.NoteOn(t.channel, key, velocity))
+		t.events = append(t.events, recordedEvent{
+			samples: t.recorder.clock.Timestamp() + int64(offset) + int64(duration*0.001*t.recorder.sampleRate),
+			msg:     midi.NoteOff(t.channel, key),
+		})
+	}
+}
+
+// noteKeyOf returns the MIDI key of the message for a voice from its note or frequency
+func noteKeyOf(msg any) (uint8, bool) {
+	values, ok := msg.(map[string]any)
+	if !ok {
+		return 0, false
+	}
+
+	if note, ok := utils.ToInt(values["note"]); ok && note >= 0 && note < 128 {
+		return uint8(note), true
+	}
+
+	if frequency, ok := utils.ToFloat(values["frequency"]); ok && frequency > 0 {
+		note := int(math.Round(12.0*math.Log2(frequency/440.0))) + 69
+		if note >= 0 && note < 128 {
+			return uint8(note), true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,45 @@
+package muse
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrUnknownParameter = errors.New("unknown modulation parameter")
+
+// ModulationInput names the audio input socket that modulates a parameter
+type ModulationInput struct {
+	Name  string
+	Index int
+}
+
+// Modulatable is implemented by modules that expose parameters as audio input sockets, a module
+// reads the parameter per sample from the socket when it is connected and falls back to the
+// control value otherwise (see Socket.Value)
+type Modulatable interface {
+	ModulationInputs() []ModulationInput
+}
+
+// ModulationInputIndex returns the input index of the modulation input of parameter name
+func ModulationInputIndex(m Module, name string) (int, bool) {
+	if mod, ok := m.(Modulatable); ok {
+		for _, input := range mod.ModulationInputs() {
+			if input.Name == name {
+				return input.Index, true
+			}
+		}
+	}
+
+	return -1, false
+}
+
+// Modulate connects output outIndex of from to the modulation input of parameter name of to, an
+// error wrapping ErrUnknownParameter is returned if to has no modulation input for the parameter
+func Modulate(from Module, outIndex int, to Module, name string) error {
+	inIndex, ok := ModulationInputIndex(to, name)
+	if !ok {
+		return fmt.Errorf("modulate %s parameter %q: %w", describeModule(to), name, ErrUnknownParameter)
+	}
+
+	return from.TryConnect(outIndex, to, inIndex)
+}
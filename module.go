@@ -1,5 +1,12 @@
 package muse
 
+import (
+	"fmt"
+	"log"
+
+	"github.com/almerlucke/muse/buffer"
+)
+
 type Module interface {
 	Control
 	Named(string) Module
@@ -21,6 +28,8 @@ type Module interface {
 	In(...any) Module
 	IConns([]*IConn) Module
 	Connect(int, Module, int)
+	TryConnect(int, Module, int) error
+	ConnectFeedback(int, Module, int) error
 	Disconnect()
 	Exec(func(any)) Module
 }
@@ -31,6 +40,7 @@ type BaseModule struct {
 	Outputs       []*Socket
 	Config        *Configuration
 	didSynthesize bool
+	parent        *BasePatch
 }
 
 func NewBaseModule(numInputs int, numOutputs int) *BaseModule {
@@ -78,10 +88,12 @@ func (m *BaseModule) Configuration() *Configuration {
 
 func (m *BaseModule) AddInputConnection(inputIndex int, conn *Connection) {
 	m.Inputs[inputIndex].AddConnection(conn)
+	m.graphChanged()
 }
 
 func (m *BaseModule) AddOutputConnection(outputIndex int, conn *Connection) {
 	m.Outputs[outputIndex].AddConnection(conn)
+	m.graphChanged()
 }
 
 func (m *BaseModule) RemoveInputConnection(inputIndex int, sender Module, outputIndex int) {
@@ -95,6 +107,7 @@ func (m *BaseModule) RemoveInputConnection(inputIndex int, sender Module, output
 	}
 	if removeIndex > -1 {
 		m.Inputs[inputIndex].Connections = append(conns[:removeIndex], conns[removeIndex+1:]...)
+		m.graphChanged()
 	}
 }
 
@@ -109,6 +122,7 @@ func (m *BaseModule) RemoveOutputConnection(outputIndex int, receiver Module, in
 	}
 	if removeIndex > -1 {
 		m.Outputs[outputIndex].Connections = append(conns[:removeIndex], conns[removeIndex+1:]...)
+		m.graphChanged()
 	}
 }
 
@@ -122,6 +136,8 @@ func (m *BaseModule) AddTo(p Patch) Module {
 	return p.AddModule(m.Self().(Module))
 }
 
+// IConns connects all given module outputs to the inputs of this module, connections that would
+// create a cycle are logged and skipped. Use TryConnect to handle the error
 func (m *BaseModule) IConns(iConns []*IConn) Module {
 	self := m.Self().(Module)
 	for _, iConn := range iConns {
@@ -130,11 +146,34 @@ func (m *BaseModule) IConns(iConns []*IConn) Module {
 	return self
 }
 
+// In connects module outputs to the inputs of this module like IConns, cycles are logged and skipped
 func (m *BaseModule) In(rawIconns ...any) Module {
 	return m.Self().(Module).IConns(IConns(rawIconns...))
 }
 
+// Connect connects output outIndex to input inIndex of module to, a connection that would create
+// a cycle is logged and skipped. Use TryConnect to handle the error
 func (m *BaseModule) Connect(outIndex int, to Module, inIndex int) {
+	err := m.connect(outIndex, to, inIndex, false)
+	if err != nil {
+		log.Printf("muse: %v", err)
+	}
+}
+
+// TryConnect connects output outIndex to input inIndex of module to, an error wrapping ErrCycle
+// is returned if the connection would create a cycle, in that case nothing is connected
+func (m *BaseModule) TryConnect(outIndex int, to Module, inIndex int) error {
+	return m.connect(outIndex, to, inIndex, false)
+}
+
+// ConnectFeedback connects output outIndex to input inIndex of module to with a delay of
+// one block, feedback connections are allowed to form cycles. The receiving module reads
+// the output the sending module produced in the previous block
+func (m *BaseModule) ConnectFeedback(outIndex int, to Module, inIndex int) error {
+	return m.connect(outIndex, to, inIndex, true)
+}
+
+func (m *BaseModule) connect(outIndex int, to Module, inIndex int, feedback bool) error {
 	from := m.Self().(Module)
 
 	p, ok := from.(Patch)
@@ -156,8 +195,24 @@ func (m *BaseModule) Connect(outIndex int, to Module, inIndex int) {
 		}
 	}
 
-	from.AddOutputConnection(outIndex, &Connection{Module: to, Index: inIndex})
-	to.AddInputConnection(inIndex, &Connection{Module: from, Index: outIndex})
+	// Output connections of a patch start at one of its output modules
+	source := from
+	if p, ok := from.(Patch); ok {
+		source = p.OutputModuleAtIndex(outIndex)
+	}
+
+	var tap buffer.Buffer
+
+	if feedback {
+		tap = make(buffer.Buffer, source.Configuration().BufferSize)
+	} else if isDownstream(to, source) {
+		return fmt.Errorf("connect %s output %d to %s input %d: %w", describeModule(from), outIndex, describeModule(to), inIndex, ErrCycle)
+	}
+
+	from.AddOutputConnection(outIndex, &Connection{Module: to, Index: inIndex, Tap: tap})
+	to.AddInputConnection(inIndex, &Connection{Module: from, Index: outIndex, Tap: tap})
+
+	return nil
 }
 
 func (m *BaseModule) Disconnect() {
@@ -193,6 +248,8 @@ func (m *BaseModule) PrepareSynthesis() {
 	}
 }
 
+// Synthesize accumulates the outputs of all connected modules in the input buffers, the
+// connected modules are already synthesized because the patch schedule runs them first
 func (m *BaseModule) Synthesize() bool {
 	if m.didSynthesize {
 		return false
@@ -205,9 +262,12 @@ func (m *BaseModule) Synthesize() bool {
 		inputBuffer := input.Buffer
 
 		for _, conn := range input.Connections {
-			conn.Module.Synthesize()
+			output := conn.Tap
+			if output == nil {
+				output = conn.Module.OutputAtIndex(conn.Index).Buffer
+			}
 
-			for bufIndex, sample := range conn.Module.OutputAtIndex(conn.Index).Buffer {
+			for bufIndex, sample := range output {
 				inputBuffer[bufIndex] += sample
 			}
 		}
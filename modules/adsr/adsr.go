@@ -5,11 +5,31 @@ import (
 	"github.com/almerlucke/muse"
 )
 
+type eventKind int
+
+const (
+	controlEvent eventKind = iota
+	triggerEvent
+	releaseEvent
+)
+
+// event is a control value, message, trigger or release scheduled on the timeline
+type event struct {
+	kind        eventKind
+	control     muse.ControlEvent
+	duration    float64
+	level       float64
+	setting     *adsr.Setting
+	releaseMode adsr.ReleaseMode
+}
+
 type ADSR struct {
 	*muse.BaseModule
 	adsr     *adsr.ADSR
+	setting  *adsr.Setting
 	level    float64
 	duration float64
+	timeline muse.Timeline[event]
 }
 
 func New(setting *adsr.Setting, releaseMode adsr.ReleaseMode, level float64) *ADSR {
@@ -17,19 +37,43 @@ func New(setting *adsr.Setting, releaseMode adsr.ReleaseMode, level float64) *AD
 		BaseModule: muse.NewBaseModule(0, 1),
 	}
 
+	a.setting = setting
 	a.level = level
 	a.duration = 250.0
 	a.adsr = adsr.New(setting, releaseMode, muse.SampleRate())
+	a.timeline = muse.NewTimeline(a.applyEvent)
 
 	a.SetSelf(a)
 
 	return a
 }
 
+func (a *ADSR) Duration() float64 {
+	return a.duration
+}
+
 func (a *ADSR) SetDuration(duration float64) {
 	a.duration = duration
 }
 
+// Setting returns the setting of the envelope, a full trigger replaces the setting
+func (a *ADSR) Setting() *adsr.Setting {
+	return a.setting
+}
+
+// ReleaseMode returns the release mode of the envelope, a full trigger replaces the release mode
+func (a *ADSR) ReleaseMode() adsr.ReleaseMode {
+	return a.adsr.ReleaseMode()
+}
+
+func (a *ADSR) Level() float64 {
+	return a.level
+}
+
+func (a *ADSR) SetLevel(level float64) {
+	a.level = level
+}
+
 func (a *ADSR) Bang() {
 	switch a.adsr.ReleaseMode() {
 	case adsr.Duration:
@@ -41,6 +85,15 @@ func (a *ADSR) Bang() {
 	}
 }
 
+// Parameters describes the parameters of the envelope, level and duration are used by the next bang
+func (a *ADSR) Parameters() []muse.Parameter {
+	return []muse.Parameter{
+		{Name: "bang", ControlIndex: 0, MessageKey: "bang", Type: muse.ParameterBang},
+		{Name: "level", ControlIndex: 1, MessageKey: "level", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 1.0, Value: a.level},
+		{Name: "duration", ControlIndex: 2, MessageKey: "duration", Type: muse.ParameterFloat, Min: 1, Max: 10000, Default: 250.0, Unit: "ms", Scaling: muse.ScalingExponential, Value: a.duration},
+	}
+}
+
 func (a *ADSR) ReceiveControlValue(value any, index int) {
 	switch index {
 	case 0: // Bang
@@ -48,20 +101,45 @@ func (a *ADSR) ReceiveControlValue(value any, index int) {
 			a.Bang()
 		}
 	case 1: // MaxLevel
-		a.level = value.(float64)
+		if v, ok := muse.ControlFloat(a, index, value); ok {
+			a.level = v
+		}
 	case 2: // Duration
-		a.duration = value.(float64)
+		if v, ok := muse.ControlFloat(a, index, value); ok {
+			a.duration = v
+		}
+	}
+}
+
+func (a *ADSR) ReceiveControlValueAt(value any, index int, offset int) {
+	a.timeline.At(offset, event{control: muse.ControlEvent{Index: index, Value: value}})
+}
+
+func (a *ADSR) ReceiveMessageAt(msg any, offset int) []*muse.Message {
+	a.timeline.At(offset, event{control: muse.ControlEvent{Index: muse.MessageIndex, Value: msg}})
+
+	return nil
+}
+
+func (a *ADSR) applyEvent(e event) {
+	switch e.kind {
+	case controlEvent:
+		muse.ReceiveControlEvent(a, e.control)
+	case triggerEvent:
+		a.TriggerFull(e.duration, e.level, e.setting, e.releaseMode)
+	case releaseEvent:
+		a.adsr.Release()
 	}
 }
 
 func (a *ADSR) ReceiveMessage(msg any) []*muse.Message {
-	if content, ok := msg.(map[string]any); ok {
-		if duration, ok := content["duration"]; ok {
-			a.duration = duration.(float64)
+	if content, ok := muse.DecodeMessage(a, msg); ok {
+		if duration, ok := content.Float("duration"); ok {
+			a.duration = duration
 		}
 
-		if level, ok := content["level"]; ok {
-			a.level = level.(float64)
+		if level, ok := content.Float("level"); ok {
+			a.level = level
 		}
 	}
 
@@ -77,23 +155,41 @@ func (a *ADSR) Trigger(level float64) {
 }
 
 func (a *ADSR) TriggerFull(duration float64, level float64, setting *adsr.Setting, releaseMode adsr.ReleaseMode) {
+	a.setting = setting
 	a.adsr.TriggerFull(duration, level, setting, releaseMode)
 }
 
+// TriggerFullAt triggers the envelope at a sample offset within the next block
+func (a *ADSR) TriggerFullAt(offset int, duration float64, level float64, setting *adsr.Setting, releaseMode adsr.ReleaseMode) {
+	a.timeline.At(offset, event{
+		kind:        triggerEvent,
+		duration:    duration,
+		level:       level,
+		setting:     setting,
+		releaseMode: releaseMode,
+	})
+}
+
 func (a *ADSR) TriggerWithDuration(duration float64, maxLevel float64) {
 	a.adsr.TriggerWithDuration(duration, maxLevel)
 }
 
 func (a *ADSR) IsActive() bool {
-	return !a.adsr.Done()
+	return !a.adsr.Done() || a.timeline.Pending()
 }
 
 func (a *ADSR) Release() {
 	a.adsr.Release()
 }
 
+// ReleaseAt releases the envelope at a sample offset within the next block
+func (a *ADSR) ReleaseAt(offset int) {
+	a.timeline.At(offset, event{kind: releaseEvent})
+}
+
 func (a *ADSR) Clear() {
 	a.adsr.Clear()
+	a.timeline.Clear()
 }
 
 func (a *ADSR) Synthesize() bool {
@@ -103,9 +199,11 @@ func (a *ADSR) Synthesize() bool {
 
 	out := a.Outputs[0].Buffer
 
-	for i := 0; i < a.Config.BufferSize; i++ {
-		out[i] = a.adsr.Generate()
-	}
+	a.timeline.Render(a.Config.BufferSize, func(start int, end int) {
+		for i := start; i < end; i++ {
+			out[i] = a.adsr.Generate()
+		}
+	})
 
 	return true
 }
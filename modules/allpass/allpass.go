@@ -44,18 +44,31 @@ func (a *Allpass) SetReadLocation(readLocation float64) {
 	a.readLocation = readLocation * a.Config.SampleRate * 0.001
 }
 
+// Parameters describes the parameters of the allpass filter, the read location can not exceed the length
+// of the delay line
+func (a *Allpass) Parameters() []muse.Parameter {
+	return []muse.Parameter{
+		{Name: "location", ControlIndex: 0, MessageKey: "location", Type: muse.ParameterFloat, Min: 0, Max: float64(a.allpass.Length) / a.Config.SampleRate * 1000.0, Default: 0.0, Unit: "ms", Value: a.readLocationMS},
+	}
+}
+
 func (a *Allpass) ReceiveControlValue(value any, index int) {
 	switch index {
 	case 0: // Read Location
-		a.SetReadLocation(value.(float64))
+		if v, ok := muse.ControlFloat(a, index, value); ok {
+			a.SetReadLocation(v)
+		}
 	}
 }
 
 func (a *Allpass) ReceiveMessage(msg any) []*muse.Message {
-	content := msg.(map[string]any)
+	content, ok := muse.DecodeMessage(a, msg)
+	if !ok {
+		return nil
+	}
 
-	if readLocMS, ok := content["location"]; ok {
-		a.SetReadLocation(readLocMS.(float64))
+	if readLocMS, ok := content.Float("location"); ok {
+		a.SetReadLocation(readLocMS)
 	}
 
 	return nil
@@ -35,18 +35,31 @@ func (d *Delay) SetReadLocation(readLocation float64) {
 	d.readLocation = readLocation * d.Config.SampleRate * 0.001
 }
 
+// Parameters describes the parameters of the delay, the read location can not exceed the length
+// of the delay line
+func (d *Delay) Parameters() []muse.Parameter {
+	return []muse.Parameter{
+		{Name: "location", ControlIndex: 0, MessageKey: "location", Type: muse.ParameterFloat, Min: 0, Max: float64(d.delay.Length) / d.Config.SampleRate * 1000.0, Default: 0.0, Unit: "ms", Value: d.readLocationMS},
+	}
+}
+
 func (d *Delay) ReceiveControlValue(value any, index int) {
 	switch index {
 	case 0: // Read Location
-		d.SetReadLocation(value.(float64))
+		if v, ok := muse.ControlFloat(d, index, value); ok {
+			d.SetReadLocation(v)
+		}
 	}
 }
 
 func (d *Delay) ReceiveMessage(msg any) []*muse.Message {
-	content := msg.(map[string]any)
+	content, ok := muse.DecodeMessage(d, msg)
+	if !ok {
+		return nil
+	}
 
-	if readLocation, ok := content["location"]; ok {
-		d.SetReadLocation(readLocation.(float64))
+	if readLocation, ok := content.Float("location"); ok {
+		d.SetReadLocation(readLocation)
 	}
 
 	return nil
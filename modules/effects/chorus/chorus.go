@@ -6,6 +6,7 @@ import (
 	"github.com/almerlucke/genny/float/shape/shapers/lookup"
 	"github.com/almerlucke/muse"
 	"github.com/almerlucke/muse/components/delay"
+	"github.com/almerlucke/muse/components/smooth"
 	"github.com/almerlucke/muse/utils/mmath"
 	"github.com/almerlucke/muse/utils/timing"
 	"math"
@@ -52,43 +53,49 @@ func (f *lpFilter) filter(x float64) float64 {
 	return out
 }
 
+var modulationInputs = []muse.ModulationInput{
+	{Name: "amount", Index: 2},
+	{Name: "delay", Index: 3},
+	{Name: "feedback", Index: 4},
+	{Name: "width", Index: 5},
+	{Name: "mix", Index: 6},
+}
+
 type Chorus struct {
 	muse.BaseModule
 	delayLineLeft  *delay.Delay
 	delayLineRight *delay.Delay
-	delayCenter    float64
-	delayRange     float64
 	modShaper      shape.Shaper
 	mods           [4]*phasor.Phasor
-	amount         float64 // 0 - 1
-	delay          float64 // 0 - 1
-	rate           float64
-	width          float64
-	mix            float64
-	fb             float64
+	amount         *smooth.Param // 0 - 1
+	delay          *smooth.Param // 0 - 1
+	rate           *smooth.Param
+	width          *smooth.Param
+	mix            *smooth.Param
+	fb             *smooth.Param
 	lp1            lpFilter
 	lp2            lpFilter
 }
 
 func New(rate float64, amount float64, delayAmount float64, feedback float64, width float64, mix float64, modShaper shape.Shaper) *Chorus {
-	delayLengthSamps := int(timing.MilliToSamps(_maxDelay+_amountRange, muse.SampleRate()) + 1)
+	sr := muse.SampleRate()
+	delayLengthSamps := int(timing.MilliToSamps(_maxDelay+_amountRange, sr) + 1)
 
 	c := &Chorus{
-		BaseModule:     *muse.NewBaseModule(2, 2),
+		BaseModule:     *muse.NewBaseModule(7, 2),
 		delayLineLeft:  delay.New(delayLengthSamps),
 		delayLineRight: delay.New(delayLengthSamps),
 		modShaper:      modShaper,
-		amount:         amount,
-		delay:          delayAmount,
-		rate:           rate,
-		mix:            mix,
-		width:          width,
-		fb:             feedback,
+		amount:         smooth.New(amount, smooth.DefaultTime, smooth.Linear, sr),
+		delay:          smooth.New(delayAmount, smooth.DefaultTime, smooth.Linear, sr),
+		rate:           smooth.New(rate, smooth.DefaultTime, smooth.Linear, sr),
+		mix:            smooth.New(mix, smooth.DefaultTime, smooth.Linear, sr),
+		width:          smooth.New(width, smooth.DefaultTime, smooth.Linear, sr),
+		fb:             smooth.New(feedback, smooth.DefaultTime, smooth.Linear, sr),
 	}
 
-	c.lp1.set(2000.0, muse.SampleRate())
-	c.lp2.set(2000.0, muse.SampleRate())
-	c.updateCalculations()
+	c.lp1.set(2000.0, sr)
+	c.lp2.set(2000.0, sr)
 
 	if modShaper == nil {
 		c.modShaper = defaultModTable
@@ -98,7 +105,7 @@ func New(rate float64, amount float64, delayAmount float64, feedback float64, wi
 	phase := [4]float64{0, _mod2Phase, _mod3Phase, _mod4Phase}
 
 	for i := 0; i < 4; i++ {
-		c.mods[i] = phasor.New(speed[i], muse.SampleRate(), phase[i])
+		c.mods[i] = phasor.New(speed[i], sr, phase[i])
 	}
 
 	c.SetSelf(c)
@@ -106,114 +113,161 @@ func New(rate float64, amount float64, delayAmount float64, feedback float64, wi
 	return c
 }
 
-func (c *Chorus) updateCalculations() {
-	c.delayCenter, c.delayRange = c.delay*(_maxDelay-_minDelay)+_minDelay, c.amount*_amountRange
-}
+// receiver returns a function that sets a smoothed parameter from a message or control value, limited to 0 - 1
+func receiver(p *smooth.Param) func(any) bool {
+	return func(value any) bool {
+		target, ramp, ok := smooth.Parse(value)
+		if !ok {
+			return false
+		}
+
+		target = mmath.Limit(target, 0, 1)
+		if ramp < 0 {
+			p.Set(target)
+		} else {
+			p.Ramp(target, ramp)
+		}
 
-func (c *Chorus) Rate() float64 {
-	return c.rate
+		return true
+	}
 }
 
-func (c *Chorus) SetRate(rate float64) {
-	rate = mmath.Limit(rate, 0, 1)
-	c.rate = rate
+func (c *Chorus) updateRate(rate float64) {
 	c.mods[0].SetFrequency(rate, c.Config.SampleRate)
 	c.mods[1].SetFrequency(rate/_mod2SpeedDiv, c.Config.SampleRate)
 	c.mods[2].SetFrequency(rate/_mod3SpeedDiv, c.Config.SampleRate)
 	c.mods[3].SetFrequency(rate/_mod4SpeedDiv, c.Config.SampleRate)
 }
 
+// delayLocations advances the smoothed delay and amount one sample and returns the center and range
+// of the delay in ms, modulation inputs replace the smoothed values when connected
+func (c *Chorus) delayLocations(i int) (float64, float64) {
+	if c.rate.IsSmoothing() {
+		c.updateRate(c.rate.Next())
+	}
+
+	delayAmount := c.Inputs[3].Value(i, c.delay.Next())
+	amount := c.Inputs[2].Value(i, c.amount.Next())
+
+	return delayAmount*(_maxDelay-_minDelay) + _minDelay, amount * _amountRange
+}
+
+func (c *Chorus) Rate() float64 {
+	return c.rate.Target()
+}
+
+func (c *Chorus) SetRate(rate float64) {
+	rate = mmath.Limit(rate, 0, 1)
+	c.rate.Jump(rate)
+	c.updateRate(rate)
+}
+
 func (c *Chorus) Amount() float64 {
-	return c.amount
+	return c.amount.Target()
 }
 
 func (c *Chorus) SetAmount(amount float64) {
-	amount = mmath.Limit(amount, 0, 1)
-	c.amount = amount
-	c.updateCalculations()
+	c.amount.Jump(mmath.Limit(amount, 0, 1))
 }
 
 func (c *Chorus) Delay() float64 {
-	return c.delay
+	return c.delay.Target()
 }
 
 func (c *Chorus) SetDelay(delayAmount float64) {
-	delayAmount = mmath.Limit(delayAmount, 0, 1)
-	c.delay = delayAmount
-	c.updateCalculations()
+	c.delay.Jump(mmath.Limit(delayAmount, 0, 1))
 }
 
 func (c *Chorus) Mix() float64 {
-	return c.mix
+	return c.mix.Target()
 }
 
 func (c *Chorus) SetMix(mix float64) {
-	mix = mmath.Limit(mix, 0, 1)
-	c.mix = mix
+	c.mix.Jump(mmath.Limit(mix, 0, 1))
 }
 
 func (c *Chorus) Feedback() float64 {
-	return c.fb
+	return c.fb.Target()
 }
 
 func (c *Chorus) SetFeedback(fb float64) {
-	fb = mmath.Limit(fb, 0, 1)
-	c.fb = fb
+	c.fb.Jump(mmath.Limit(fb, 0, 1))
 }
 
 func (c *Chorus) Width() float64 {
-	return c.width
+	return c.width.Target()
 }
 
 func (c *Chorus) SetWidth(w float64) {
-	w = mmath.Limit(w, 0, 1)
-	c.width = w
+	c.width.Jump(mmath.Limit(w, 0, 1))
 }
 
-func (c *Chorus) ReceiveControlValue(value any, index int) {
-	switch index {
-	case 0:
-		c.SetRate(value.(float64))
-	case 1:
-		c.SetAmount(value.(float64))
-	case 2:
-		c.SetDelay(value.(float64))
-	case 3:
-		c.SetFeedback(value.(float64))
-	case 4:
-		c.SetWidth(value.(float64))
-	case 5:
-		c.SetMix(value.(float64))
+func (c *Chorus) SetSmoothing(time float64, mode smooth.Mode) {
+	for _, p := range []*smooth.Param{c.rate, c.amount, c.delay, c.fb, c.width, c.mix} {
+		p.SetTime(time)
+		p.SetMode(mode)
 	}
 }
 
-func (c *Chorus) ReceiveMessage(msg any) []*muse.Message {
-	m := msg.(map[string]any)
+// ModulationInputs returns the audio inputs that modulate parameters per sample
+func (c *Chorus) ModulationInputs() []muse.ModulationInput {
+	return modulationInputs
+}
 
-	if rate, ok := m["rate"].(float64); ok {
-		c.SetRate(rate)
+func (c *Chorus) receiveRate(value any) bool {
+	if !receiver(c.rate)(value) {
+		return false
 	}
 
-	if amount, ok := m["amount"].(float64); ok {
-		c.SetAmount(amount)
+	if !c.rate.IsSmoothing() {
+		c.updateRate(c.rate.Value())
 	}
 
-	if d, ok := m["delay"].(float64); ok {
-		c.SetDelay(d)
-	}
+	return true
+}
 
-	if fb, ok := m["feedback"].(float64); ok {
-		c.SetFeedback(fb)
+// Parameters describes the parameters of the chorus, received values are limited to the range 0 - 1
+func (c *Chorus) Parameters() []muse.Parameter {
+	return []muse.Parameter{
+		{Name: "rate", ControlIndex: 0, MessageKey: "rate", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.25, Unit: "Hz", Value: c.Rate()},
+		{Name: "amount", ControlIndex: 1, MessageKey: "amount", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.4, Value: c.Amount()},
+		{Name: "delay", ControlIndex: 2, MessageKey: "delay", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.4, Value: c.Delay()},
+		{Name: "feedback", ControlIndex: 3, MessageKey: "feedback", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.2, Value: c.Feedback()},
+		{Name: "width", ControlIndex: 4, MessageKey: "width", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 1.0, Value: c.Width()},
+		{Name: "mix", ControlIndex: 5, MessageKey: "mix", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.5, Value: c.Mix()},
 	}
+}
 
-	if w, ok := m["width"].(float64); ok {
-		c.SetWidth(w)
+func (c *Chorus) ReceiveControlValue(value any, index int) {
+	switch index {
+	case 0:
+		muse.ReceiveControl(c, index, value, c.receiveRate)
+	case 1:
+		muse.ReceiveControl(c, index, value, receiver(c.amount))
+	case 2:
+		muse.ReceiveControl(c, index, value, receiver(c.delay))
+	case 3:
+		muse.ReceiveControl(c, index, value, receiver(c.fb))
+	case 4:
+		muse.ReceiveControl(c, index, value, receiver(c.width))
+	case 5:
+		muse.ReceiveControl(c, index, value, receiver(c.mix))
 	}
+}
 
-	if mix, ok := m["mix"].(float64); ok {
-		c.SetMix(mix)
+func (c *Chorus) ReceiveMessage(msg any) []*muse.Message {
+	content, ok := muse.DecodeMessage(c, msg)
+	if !ok {
+		return nil
 	}
 
+	content.Receive("rate", c.receiveRate)
+	content.Receive("amount", receiver(c.amount))
+	content.Receive("delay", receiver(c.delay))
+	content.Receive("feedback", receiver(c.fb))
+	content.Receive("width", receiver(c.width))
+	content.Receive("mix", receiver(c.mix))
+
 	return nil
 }
 
@@ -226,10 +280,15 @@ func (c *Chorus) synthesizeStereoInput() {
 	msSamps := c.Config.SampleRate * 0.001
 
 	for i := 0; i < c.Config.BufferSize; i++ {
-		d1Loc := math.Abs(msSamps * (c.delayCenter + c.delayRange*c.modShaper.Shape(c.mods[0].Generate())))
-		d2Loc := math.Abs(msSamps * (c.delayCenter + c.delayRange*c.modShaper.Shape(c.mods[1].Generate())))
-		d3Loc := math.Abs(msSamps * (c.delayCenter + c.delayRange*c.modShaper.Shape(c.mods[2].Generate())))
-		d4Loc := math.Abs(msSamps * (c.delayCenter + c.delayRange*c.modShaper.Shape(c.mods[3].Generate())))
+		delayCenter, delayRange := c.delayLocations(i)
+		fb := c.Inputs[4].Value(i, c.fb.Next())
+		width := c.Inputs[5].Value(i, c.width.Next())
+		mix := c.Inputs[6].Value(i, c.mix.Next())
+
+		d1Loc := math.Abs(msSamps * (delayCenter + delayRange*c.modShaper.Shape(c.mods[0].Generate())))
+		d2Loc := math.Abs(msSamps * (delayCenter + delayRange*c.modShaper.Shape(c.mods[1].Generate())))
+		d3Loc := math.Abs(msSamps * (delayCenter + delayRange*c.modShaper.Shape(c.mods[2].Generate())))
+		d4Loc := math.Abs(msSamps * (delayCenter + delayRange*c.modShaper.Shape(c.mods[3].Generate())))
 
 		d1Left := c.delayLineLeft.ReadLinear(d1Loc)
 		d2Left := c.delayLineLeft.ReadLinear(d2Loc)
@@ -247,16 +306,16 @@ func (c *Chorus) synthesizeStereoInput() {
 		im1Right := d1Right + d3Right
 		im2Right := d2Right + d4Right
 
-		c.delayLineLeft.Write(inLeft[i] + c.fb*c.lp1.filter((im1Left+im2Left)*0.25))
-		c.delayLineRight.Write(inRight[i] + c.fb*c.lp2.filter((im1Right+im2Right)*0.25))
+		c.delayLineLeft.Write(inLeft[i] + fb*c.lp1.filter((im1Left+im2Left)*0.25))
+		c.delayLineRight.Write(inRight[i] + fb*c.lp2.filter((im1Right+im2Right)*0.25))
 
-		out1 := im1Left + im2Left*(1.0-c.width)
-		out2 := im1Left*(1.0-c.width) + im2Left
-		out1 += im1Right + im2Right*(1.0-c.width)
-		out2 += im1Right*(1.0-c.width) + im2Right
+		out1 := im1Left + im2Left*(1.0-width)
+		out2 := im1Left*(1.0-width) + im2Left
+		out1 += im1Right + im2Right*(1.0-width)
+		out2 += im1Right*(1.0-width) + im2Right
 
-		outLeft[i] = inLeft[i]*(1.0-c.mix) + c.mix*out1
-		outRight[i] = inRight[i]*(1.0-c.mix) + c.mix*out2
+		outLeft[i] = inLeft[i]*(1.0-mix) + mix*out1
+		outRight[i] = inRight[i]*(1.0-mix) + mix*out2
 	}
 }
 
@@ -277,10 +336,15 @@ func (c *Chorus) Synthesize() bool {
 	msSamps := c.Config.SampleRate * 0.001
 
 	for i := 0; i < c.Config.BufferSize; i++ {
-		d1Loc := math.Abs(msSamps * (c.delayCenter + c.delayRange*c.modShaper.Shape(c.mods[0].Generate())))
-		d2Loc := math.Abs(msSamps * (c.delayCenter + c.delayRange*c.modShaper.Shape(c.mods[1].Generate())))
-		d3Loc := math.Abs(msSamps * (c.delayCenter + c.delayRange*c.modShaper.Shape(c.mods[2].Generate())))
-		d4Loc := math.Abs(msSamps * (c.delayCenter + c.delayRange*c.modShaper.Shape(c.mods[3].Generate())))
+		delayCenter, delayRange := c.delayLocations(i)
+		fb := c.Inputs[4].Value(i, c.fb.Next())
+		width := c.Inputs[5].Value(i, c.width.Next())
+		mix := c.Inputs[6].Value(i, c.mix.Next())
+
+		d1Loc := math.Abs(msSamps * (delayCenter + delayRange*c.modShaper.Shape(c.mods[0].Generate())))
+		d2Loc := math.Abs(msSamps * (delayCenter + delayRange*c.modShaper.Shape(c.mods[1].Generate())))
+		d3Loc := math.Abs(msSamps * (delayCenter + delayRange*c.modShaper.Shape(c.mods[2].Generate())))
+		d4Loc := math.Abs(msSamps * (delayCenter + delayRange*c.modShaper.Shape(c.mods[3].Generate())))
 
 		d1 := c.delayLineLeft.ReadLinear(d1Loc)
 		d2 := c.delayLineLeft.ReadLinear(d2Loc)
@@ -290,13 +354,13 @@ func (c *Chorus) Synthesize() bool {
 		im1 := d1 + d3
 		im2 := d2 + d4
 
-		out1 := im1 + im2*(1.0-c.width)
-		out2 := im1*(1.0-c.width) + im2
+		out1 := im1 + im2*(1.0-width)
+		out2 := im1*(1.0-width) + im2
 
-		c.delayLineLeft.Write(inLeft[i] + c.fb*c.lp1.filter((im1+im2)*0.25))
+		c.delayLineLeft.Write(inLeft[i] + fb*c.lp1.filter((im1+im2)*0.25))
 
-		outLeft[i] = inLeft[i]*(1.0-c.mix) + c.mix*out1
-		outRight[i] = inLeft[i]*(1.0-c.mix) + c.mix*out2
+		outLeft[i] = inLeft[i]*(1.0-mix) + mix*out1
+		outRight[i] = inLeft[i]*(1.0-mix) + mix*out2
 	}
 
 	return true
@@ -1,9 +1,9 @@
 package flanger
 
 import (
-	"github.com/almerlucke/genny/float/line"
 	"github.com/almerlucke/muse"
 	"github.com/almerlucke/muse/components/delay"
+	"github.com/almerlucke/muse/components/smooth"
 	"github.com/almerlucke/muse/utils/timing"
 	"math"
 )
@@ -13,26 +13,32 @@ const (
 	FlangeMaxPos = 20.0
 )
 
+var modulationInputs = []muse.ModulationInput{
+	{Name: "depth", Index: 2},
+	{Name: "feedback", Index: 3},
+	{Name: "mix", Index: 4},
+}
+
 type Flanger struct {
 	*muse.BaseModule
 	delayLeft  *delay.Delay
 	delayRight *delay.Delay
-	fb         float64
-	depth      float64
-	newDepth   float64
-	mix        float64
+	fb         *smooth.Param
+	depth      *smooth.Param
+	mix        *smooth.Param
 }
 
 func New(depth float64, feedback float64, mix float64) *Flanger {
-	delaySize := int(math.Ceil(timing.MilliToSampsf(FlangeMaxPos, muse.CurrentConfiguration().SampleRate)))
+	sr := muse.CurrentConfiguration().SampleRate
+	delaySize := int(math.Ceil(timing.MilliToSampsf(FlangeMaxPos, sr)))
 
 	f := &Flanger{
-		BaseModule: muse.NewBaseModule(2, 2),
+		BaseModule: muse.NewBaseModule(5, 2),
 		delayLeft:  delay.New(delaySize),
 		delayRight: delay.New(delaySize),
-		depth:      depth,
-		fb:         feedback,
-		mix:        mix,
+		depth:      smooth.New(depth, smooth.DefaultTime, smooth.Linear, sr),
+		fb:         smooth.New(feedback, smooth.DefaultTime, smooth.Linear, sr),
+		mix:        smooth.New(mix, smooth.DefaultTime, smooth.Linear, sr),
 	}
 
 	f.SetSelf(f)
@@ -40,73 +46,101 @@ func New(depth float64, feedback float64, mix float64) *Flanger {
 	return f
 }
 
+func (f *Flanger) Depth() float64 {
+	return f.depth.Target()
+}
+
 func (f *Flanger) SetDepth(depth float64) {
-	f.newDepth = depth
+	f.depth.Set(depth)
+}
+
+func (f *Flanger) Feedback() float64 {
+	return f.fb.Target()
 }
 
 func (f *Flanger) SetFeedback(fb float64) {
-	f.fb = fb
+	f.fb.Jump(fb)
+}
+
+func (f *Flanger) Mix() float64 {
+	return f.mix.Target()
 }
 
 func (f *Flanger) SetMix(mix float64) {
-	f.mix = mix
+	f.mix.Jump(mix)
+}
+
+func (f *Flanger) SetSmoothing(time float64, mode smooth.Mode) {
+	for _, p := range []*smooth.Param{f.depth, f.fb, f.mix} {
+		p.SetTime(time)
+		p.SetMode(mode)
+	}
+}
+
+// ModulationInputs returns the audio inputs that modulate parameters per sample
+func (f *Flanger) ModulationInputs() []muse.ModulationInput {
+	return modulationInputs
+}
+
+// Parameters describes the parameters of the flanger
+func (f *Flanger) Parameters() []muse.Parameter {
+	return []muse.Parameter{
+		{Name: "depth", ControlIndex: 0, MessageKey: "depth", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.5, Value: f.Depth()},
+		{Name: "feedback", ControlIndex: 1, MessageKey: "feedback", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.5, Value: f.Feedback()},
+		{Name: "mix", ControlIndex: 2, MessageKey: "mix", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.5, Value: f.Mix()},
+	}
 }
 
 func (f *Flanger) ReceiveControlValue(value any, index int) {
 	switch index {
 	case 0:
-		f.SetDepth(value.(float64))
+		muse.ReceiveControl(f, index, value, f.depth.Receive)
 	case 1:
-		f.SetFeedback(value.(float64))
+		muse.ReceiveControl(f, index, value, f.fb.Receive)
 	case 2:
-		f.SetMix(value.(float64))
+		muse.ReceiveControl(f, index, value, f.mix.Receive)
 	}
 }
 
 func (f *Flanger) ReceiveMessage(msg any) []*muse.Message {
-	content := msg.(map[string]any)
-
-	if depth, ok := content["depth"]; ok {
-		f.SetDepth(depth.(float64))
+	content, ok := muse.DecodeMessage(f, msg)
+	if !ok {
+		return nil
 	}
 
-	if fb, ok := content["feedback"]; ok {
-		f.SetFeedback(fb.(float64))
-	}
-
-	if fb, ok := content["mix"]; ok {
-		f.SetMix(fb.(float64))
-	}
+	content.Receive("depth", f.depth.Receive)
+	content.Receive("feedback", f.fb.Receive)
+	content.Receive("mix", f.mix.Receive)
 
 	return nil
 }
 
+// params advances the smoothed parameters one sample and returns the read position, feedback and
+// mix, modulation inputs replace the smoothed values when connected
+func (f *Flanger) params(i int) (float64, float64, float64) {
+	depth := f.Inputs[2].Value(i, f.depth.Next())
+	readPos := timing.MilliToSampsf(depth*(FlangeMaxPos-FlangeMinPos)+FlangeMinPos, f.Config.SampleRate)
+
+	return readPos, f.Inputs[3].Value(i, f.fb.Next()), f.Inputs[4].Value(i, f.mix.Next())
+}
+
 func (f *Flanger) synthesizeStereo() {
 	var (
 		inBufLeft   = f.Inputs[0].Buffer
 		inBufRight  = f.Inputs[1].Buffer
 		outBufLeft  = f.Outputs[0].Buffer
 		outBufRight = f.Outputs[1].Buffer
-		readLine    line.Line
 	)
 
-	readLine.From(timing.MilliToSampsf(f.depth*(FlangeMaxPos-FlangeMinPos)+FlangeMinPos, f.Config.SampleRate))
-	if f.depth != f.newDepth {
-		readLine.To(timing.MilliToSampsf(f.newDepth*(FlangeMaxPos-FlangeMinPos)+FlangeMinPos, f.Config.SampleRate), f.Config.BufferSize)
-		f.depth = f.newDepth
-	}
-
-	dry := 1.0 - f.mix
-	wet := f.mix
-
 	for i := 0; i < f.Config.BufferSize; i++ {
 		inLeft := inBufLeft[i]
 		inRight := inBufRight[i]
-		readPos := readLine.Generate()
+		readPos, fb, wet := f.params(i)
+		dry := 1.0 - wet
 		delOutLeft := f.delayLeft.ReadLinear(readPos)
 		delOutRight := f.delayRight.ReadLinear(readPos)
-		f.delayLeft.Write(inLeft + delOutLeft*f.fb)
-		f.delayRight.Write(inRight + delOutRight*f.fb)
+		f.delayLeft.Write(inLeft + delOutLeft*fb)
+		f.delayRight.Write(inRight + delOutRight*fb)
 		flangOutLeft := inLeft + delOutLeft
 		flangOutRight := inRight + delOutRight
 		outBufLeft[i] = inLeft*dry + flangOutLeft*wet
@@ -128,25 +162,15 @@ func (f *Flanger) Synthesize() bool {
 		inBuf       = f.Inputs[0].Buffer
 		outBufLeft  = f.Outputs[0].Buffer
 		outBufRight = f.Outputs[1].Buffer
-		readLine    line.Line
 	)
 
-	readLine.From(timing.MilliToSampsf(f.depth*(FlangeMaxPos-FlangeMinPos)+FlangeMinPos, f.Config.SampleRate))
-	if f.depth != f.newDepth {
-		readLine.To(timing.MilliToSampsf(f.newDepth*(FlangeMaxPos-FlangeMinPos)+FlangeMinPos, f.Config.SampleRate), f.Config.BufferSize)
-		f.depth = f.newDepth
-	}
-
-	dry := 1.0 - f.mix
-	wet := f.mix
-
 	for i := range f.Config.BufferSize {
 		in := inBuf[i]
-		readPos := readLine.Generate()
+		readPos, fb, wet := f.params(i)
 		delOut := f.delayLeft.ReadLinear(readPos)
-		f.delayLeft.Write(in + delOut*f.fb)
+		f.delayLeft.Write(in + delOut*fb)
 		flangOut := in + delOut
-		outBufLeft[i] = in*dry + flangOut*wet
+		outBufLeft[i] = in*(1.0-wet) + flangOut*wet
 		outBufRight[i] = outBufLeft[i]
 	}
 
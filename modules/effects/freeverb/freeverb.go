@@ -2,6 +2,7 @@ package freeverb
 
 import (
 	"github.com/almerlucke/muse"
+	"github.com/almerlucke/muse/components/smooth"
 	"github.com/almerlucke/muse/utils/float"
 )
 
@@ -137,6 +138,15 @@ func (c *fvComb) process(input float64) float64 {
    Module
 */
 
+// freeVerbModulationInputs are the audio inputs of FreeVerb that modulate parameters per sample
+var freeVerbModulationInputs = []muse.ModulationInput{
+	{Name: "wet", Index: 2},
+	{Name: "dry", Index: 3},
+	{Name: "roomSize", Index: 4},
+	{Name: "damp", Index: 5},
+	{Name: "width", Index: 6},
+}
+
 // FreeVerb module
 type FreeVerb struct {
 	*muse.BaseModule
@@ -155,6 +165,13 @@ type FreeVerb struct {
 	dry       float64
 	width     float64
 	mode      float64
+
+	wetParam      *smooth.Param
+	dryParam      *smooth.Param
+	roomSizeParam *smooth.Param
+	dampParam     *smooth.Param
+	widthParam    *smooth.Param
+	modulated     bool
 }
 
 // NewFreeVerbModule generate new freeverb module
@@ -162,8 +179,15 @@ func New() *FreeVerb {
 
 	scale := muse.SampleRate() / 44100.0
 
+	sr := muse.SampleRate()
+
 	fv := &FreeVerb{
-		BaseModule: muse.NewBaseModule(2, 2),
+		BaseModule:    muse.NewBaseModule(7, 2),
+		wetParam:      smooth.New(initialwet, smooth.DefaultTime, smooth.Linear, sr),
+		dryParam:      smooth.New(initialdry, smooth.DefaultTime, smooth.Linear, sr),
+		roomSizeParam: smooth.New(initialroom, smooth.DefaultTime, smooth.Linear, sr),
+		dampParam:     smooth.New(initialdamp, smooth.DefaultTime, smooth.Linear, sr),
+		widthParam:    smooth.New(initialwidth, smooth.DefaultTime, smooth.Linear, sr),
 	}
 
 	fv.combL = make([]*fvComb, numcombs)
@@ -209,34 +233,139 @@ func New() *FreeVerb {
 }
 
 func (fv *FreeVerb) SetWet(wet float64) {
+	fv.wetParam.Jump(wet)
 	fv.wet = wet * scalewet
 	fv.update()
 }
 
 func (fv *FreeVerb) SetRoomSize(roomsize float64) {
+	fv.roomSizeParam.Jump(roomsize)
 	fv.roomsize = (roomsize * scaleroom) + offsetroom
 	fv.update()
 }
 
 func (fv *FreeVerb) SetDry(dry float64) {
+	fv.dryParam.Jump(dry)
 	fv.dry = dry * scaledry
 }
 
 func (fv *FreeVerb) SetDamp(damp float64) {
+	fv.dampParam.Jump(damp)
 	fv.damp = damp * scaledamp
 	fv.update()
 }
 
 func (fv *FreeVerb) SetWidth(width float64) {
+	fv.widthParam.Jump(width)
 	fv.width = width
 	fv.update()
 }
 
+// SetSmoothing sets the smoothing of all parameters except mode
+func (fv *FreeVerb) SetSmoothing(time float64, mode smooth.Mode) {
+	for _, p := range fv.params() {
+		p.SetTime(time)
+		p.SetMode(mode)
+	}
+}
+
+// ModulationInputs returns the audio inputs that modulate parameters per sample
+func (fv *FreeVerb) ModulationInputs() []muse.ModulationInput {
+	return freeVerbModulationInputs
+}
+
 func (fv *FreeVerb) SetMode(mode float64) {
 	fv.mode = mode
 	fv.update()
 }
 
+func (fv *FreeVerb) Wet() float64 {
+	return fv.wetParam.Target()
+}
+
+func (fv *FreeVerb) RoomSize() float64 {
+	return fv.roomSizeParam.Target()
+}
+
+func (fv *FreeVerb) Dry() float64 {
+	return fv.dryParam.Target()
+}
+
+func (fv *FreeVerb) Damp() float64 {
+	return fv.dampParam.Target()
+}
+
+func (fv *FreeVerb) Width() float64 {
+	return fv.widthParam.Target()
+}
+
+func (fv *FreeVerb) params() [5]*smooth.Param {
+	return [5]*smooth.Param{fv.wetParam, fv.dryParam, fv.roomSizeParam, fv.dampParam, fv.widthParam}
+}
+
+func (fv *FreeVerb) isSmoothing() bool {
+	for _, p := range fv.params() {
+		if p.IsSmoothing() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// receiver returns a function that sets a smoothed parameter from a message or control value and
+// applies the current values
+func (fv *FreeVerb) receiver(p *smooth.Param) func(any) bool {
+	return func(value any) bool {
+		if !p.Receive(value) {
+			return false
+		}
+
+		fv.apply()
+
+		return true
+	}
+}
+
+func (fv *FreeVerb) isModulated() bool {
+	for _, input := range fv.Inputs[2:] {
+		if input.IsConnected() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// apply sets the scaled values from the current values of the smoothed parameters
+func (fv *FreeVerb) apply() {
+	fv.set(fv.wetParam.Value(), fv.dryParam.Value(), fv.roomSizeParam.Value(), fv.dampParam.Value(), fv.widthParam.Value())
+}
+
+// applyAt sets the scaled values for sample i, modulation inputs replace the smoothed values when connected
+func (fv *FreeVerb) applyAt(i int) {
+	fv.set(
+		fv.Inputs[2].Value(i, fv.wetParam.Value()),
+		fv.Inputs[3].Value(i, fv.dryParam.Value()),
+		fv.Inputs[4].Value(i, fv.roomSizeParam.Value()),
+		fv.Inputs[5].Value(i, fv.dampParam.Value()),
+		fv.Inputs[6].Value(i, fv.widthParam.Value()),
+	)
+}
+
+func (fv *FreeVerb) set(wet float64, dry float64, roomsize float64, damp float64, width float64) {
+	fv.wet = wet * scalewet
+	fv.dry = dry * scaledry
+	fv.roomsize = (roomsize * scaleroom) + offsetroom
+	fv.damp = damp * scaledamp
+	fv.width = width
+	fv.update()
+}
+
+func (fv *FreeVerb) Mode() float64 {
+	return fv.mode
+}
+
 func (fv *FreeVerb) update() {
 	fv.wet1 = fv.wet * (fv.width/2.0 + 0.5)
 	fv.wet2 = fv.wet * ((1.0 - fv.width) / 2.0)
@@ -259,41 +388,46 @@ func (fv *FreeVerb) update() {
 	}
 }
 
+// Parameters describes the parameters of the reverb, a mode of 0.5 or higher freezes the reverb
+func (fv *FreeVerb) Parameters() []muse.Parameter {
+	return []muse.Parameter{
+		{Name: "wet", ControlIndex: 0, MessageKey: "wet", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: initialwet, Value: fv.Wet()},
+		{Name: "dry", ControlIndex: 1, MessageKey: "dry", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: initialdry, Value: fv.Dry()},
+		{Name: "roomSize", ControlIndex: 2, MessageKey: "roomSize", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: initialroom, Value: fv.RoomSize()},
+		{Name: "damp", ControlIndex: 3, MessageKey: "damp", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: initialdamp, Value: fv.Damp()},
+		{Name: "width", ControlIndex: 4, MessageKey: "width", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: initialwidth, Value: fv.Width()},
+		{Name: "mode", ControlIndex: 5, MessageKey: "mode", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: float64(initialmode), Value: fv.Mode()},
+	}
+}
+
 func (fv *FreeVerb) ReceiveControlValue(value any, index int) {
 	switch index {
 	case 0: // Wet
-		fv.SetWet(value.(float64))
+		muse.ReceiveControl(fv, index, value, fv.receiver(fv.wetParam))
 	case 1: // Dry
-		fv.SetDry(value.(float64))
+		muse.ReceiveControl(fv, index, value, fv.receiver(fv.dryParam))
 	case 2: // RoomSize
-		fv.SetRoomSize(value.(float64))
+		muse.ReceiveControl(fv, index, value, fv.receiver(fv.roomSizeParam))
 	case 3: // Damp
-		fv.SetDamp(value.(float64))
+		muse.ReceiveControl(fv, index, value, fv.receiver(fv.dampParam))
 	case 4: // Width
-		fv.SetWidth(value.(float64))
+		muse.ReceiveControl(fv, index, value, fv.receiver(fv.widthParam))
 	case 5: // Mode
-		fv.SetMode(value.(float64))
+		if mode, ok := muse.ControlFloat(fv, index, value); ok {
+			fv.SetMode(mode)
+		}
 	}
 }
 
 func (fv *FreeVerb) ReceiveMessage(msg any) []*muse.Message {
-	if valueMap, ok := msg.(map[string]any); ok {
-		if wet, ok := valueMap["wet"].(float64); ok {
-			fv.SetWet(wet)
-		}
-		if roomSize, ok := valueMap["roomSize"].(float64); ok {
-			fv.SetRoomSize(roomSize)
-		}
-		if dry, ok := valueMap["dry"].(float64); ok {
-			fv.SetDry(dry)
-		}
-		if damp, ok := valueMap["damp"].(float64); ok {
-			fv.SetDamp(damp)
-		}
-		if width, ok := valueMap["width"].(float64); ok {
-			fv.SetWidth(width)
-		}
-		if mode, ok := valueMap["mode"].(float64); ok {
+	if content, ok := muse.DecodeMessage(fv, msg); ok {
+		content.Receive("wet", fv.receiver(fv.wetParam))
+		content.Receive("roomSize", fv.receiver(fv.roomSizeParam))
+		content.Receive("dry", fv.receiver(fv.dryParam))
+		content.Receive("damp", fv.receiver(fv.dampParam))
+		content.Receive("width", fv.receiver(fv.widthParam))
+
+		if mode, ok := content.Float("mode"); ok {
 			fv.SetMode(mode)
 		}
 	}
@@ -322,9 +456,25 @@ func (fv *FreeVerb) Synthesize() bool {
 		inBuffer2 = fv.Inputs[1].Buffer
 	}
 
+	modulated := fv.isModulated()
+	if fv.modulated && !modulated {
+		// Modulation inputs were disconnected, fall back to the parameter values
+		fv.apply()
+	}
+
+	fv.modulated = modulated
+
 	for i := 0; i < buflen; i++ {
 		outL, outR, inputL, inputR, input := 0.0, 0.0, 0.0, 0.0, 0.0
 
+		if modulated || fv.isSmoothing() {
+			for _, p := range fv.params() {
+				p.Next()
+			}
+
+			fv.applyAt(i)
+		}
+
 		if inBuffer1 != nil {
 			inputL = inBuffer1[i]
 		}
@@ -1,34 +1,39 @@
 package pingpong
 
 import (
-	"github.com/almerlucke/genny/float/line"
 	"github.com/almerlucke/muse"
 	"github.com/almerlucke/muse/components/delay"
+	"github.com/almerlucke/muse/components/smooth"
 	"github.com/almerlucke/muse/utils/timing"
 	"math"
 )
 
+var modulationInputs = []muse.ModulationInput{
+	{Name: "location", Index: 2},
+	{Name: "feedback", Index: 3},
+	{Name: "mix", Index: 4},
+}
+
 type PingPong struct {
 	*muse.BaseModule
-	left    *delay.Delay
-	right   *delay.Delay
-	read    float64
-	newRead float64
-	mix     float64
-	fb      float64
+	left  *delay.Delay
+	right *delay.Delay
+	read  *smooth.Param
+	mix   *smooth.Param
+	fb    *smooth.Param
 }
 
 func New(delayLengthMs float64, readLocMs float64, feedback float64, mix float64) *PingPong {
-	delayLengthSamps := int(math.Ceil(timing.MilliToSampsf(delayLengthMs, muse.CurrentConfiguration().SampleRate)))
+	sr := muse.CurrentConfiguration().SampleRate
+	delayLengthSamps := int(math.Ceil(timing.MilliToSampsf(delayLengthMs, sr)))
 
 	pp := &PingPong{
-		BaseModule: muse.NewBaseModule(2, 2),
+		BaseModule: muse.NewBaseModule(5, 2),
 		left:       delay.New(delayLengthSamps),
 		right:      delay.New(delayLengthSamps),
-		read:       readLocMs,
-		newRead:    readLocMs,
-		mix:        mix,
-		fb:         feedback,
+		read:       smooth.New(readLocMs, smooth.DefaultTime, smooth.Linear, sr),
+		mix:        smooth.New(mix, smooth.DefaultTime, smooth.Linear, sr),
+		fb:         smooth.New(feedback, smooth.DefaultTime, smooth.Linear, sr),
 	}
 
 	pp.SetSelf(pp)
@@ -36,72 +41,99 @@ func New(delayLengthMs float64, readLocMs float64, feedback float64, mix float64
 	return pp
 }
 
+func (pp *PingPong) Read() float64 {
+	return pp.read.Target()
+}
+
 func (pp *PingPong) SetRead(read float64) {
-	pp.newRead = read
+	pp.read.Set(read)
+}
+
+func (pp *PingPong) Feedback() float64 {
+	return pp.fb.Target()
 }
 
 func (pp *PingPong) SetFeedback(fb float64) {
-	pp.fb = fb
+	pp.fb.Jump(fb)
+}
+
+func (pp *PingPong) Mix() float64 {
+	return pp.mix.Target()
 }
 
 func (pp *PingPong) SetMix(mix float64) {
-	pp.mix = mix
+	pp.mix.Jump(mix)
+}
+
+func (pp *PingPong) SetSmoothing(time float64, mode smooth.Mode) {
+	for _, p := range []*smooth.Param{pp.read, pp.fb, pp.mix} {
+		p.SetTime(time)
+		p.SetMode(mode)
+	}
+}
+
+// ModulationInputs returns the audio inputs that modulate parameters per sample
+func (pp *PingPong) ModulationInputs() []muse.ModulationInput {
+	return modulationInputs
+}
+
+// Parameters describes the parameters of the ping pong delay, the read location can not exceed the
+// length of the delay lines
+func (pp *PingPong) Parameters() []muse.Parameter {
+	return []muse.Parameter{
+		{Name: "location", ControlIndex: 0, MessageKey: "location", Type: muse.ParameterFloat, Min: 0, Max: float64(pp.left.Length) / pp.Config.SampleRate * 1000.0, Default: 250.0, Unit: "ms", Value: pp.Read()},
+		{Name: "feedback", ControlIndex: 1, MessageKey: "feedback", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.5, Value: pp.Feedback()},
+		{Name: "mix", ControlIndex: 2, MessageKey: "mix", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.5, Value: pp.Mix()},
+	}
 }
 
 func (pp *PingPong) ReceiveControlValue(value any, index int) {
 	switch index {
 	case 0: // Read Location in ms
-		pp.SetRead(value.(float64))
+		muse.ReceiveControl(pp, index, value, pp.read.Receive)
 	case 1:
-		pp.SetFeedback(value.(float64))
+		muse.ReceiveControl(pp, index, value, pp.fb.Receive)
 	case 2:
-		pp.SetMix(value.(float64))
+		muse.ReceiveControl(pp, index, value, pp.mix.Receive)
 	}
 }
 
 func (pp *PingPong) ReceiveMessage(msg any) []*muse.Message {
-	content := msg.(map[string]any)
-
-	if readLocation, ok := content["location"]; ok {
-		pp.SetRead(readLocation.(float64))
-	}
-
-	if fb, ok := content["feedback"]; ok {
-		pp.SetFeedback(fb.(float64))
+	content, ok := muse.DecodeMessage(pp, msg)
+	if !ok {
+		return nil
 	}
 
-	if dry, ok := content["mix"]; ok {
-		pp.SetMix(dry.(float64))
-	}
+	content.Receive("location", pp.read.Receive)
+	content.Receive("feedback", pp.fb.Receive)
+	content.Receive("mix", pp.mix.Receive)
 
 	return nil
 }
 
+// params advances the smoothed parameters one sample and returns the read location in samples,
+// feedback and mix, modulation inputs replace the smoothed values when connected
+func (pp *PingPong) params(i int) (float64, float64, float64) {
+	lookup := timing.MilliToSampsf(pp.Inputs[2].Value(i, pp.read.Next()), pp.Config.SampleRate)
+
+	return lookup, pp.Inputs[3].Value(i, pp.fb.Next()), pp.Inputs[4].Value(i, pp.mix.Next())
+}
+
 func (pp *PingPong) synthesizeStereo() {
 	var (
-		inLeft        = pp.Inputs[0].Buffer
-		inRight       = pp.Inputs[1].Buffer
-		outLeft       = pp.Outputs[0].Buffer
-		outRight      = pp.Outputs[1].Buffer
-		delayTimeLine line.Line
+		inLeft   = pp.Inputs[0].Buffer
+		inRight  = pp.Inputs[1].Buffer
+		outLeft  = pp.Outputs[0].Buffer
+		outRight = pp.Outputs[1].Buffer
 	)
 
-	delayTimeLine.From(timing.MilliToSampsf(pp.read, pp.Config.SampleRate))
-
-	if pp.newRead != pp.read {
-		delayTimeLine.To(timing.MilliToSampsf(pp.newRead, pp.Config.SampleRate), pp.Config.BufferSize)
-		pp.read = pp.newRead
-	}
-
-	dry := 1.0 - pp.mix
-	wet := pp.mix
-
 	for i := 0; i < pp.Config.BufferSize; i++ {
-		lookup := delayTimeLine.Generate()
+		lookup, fb, wet := pp.params(i)
+		dry := 1.0 - wet
 		left := pp.left.ReadLinear(lookup)
 		right := pp.right.ReadLinear(lookup)
 
-		pp.left.Write(inLeft[i] + right*pp.fb)
+		pp.left.Write(inLeft[i] + right*fb)
 		pp.right.Write(inRight[i] + left)
 
 		outLeft[i] = inLeft[i]*dry + left*wet
@@ -120,28 +152,18 @@ func (pp *PingPong) Synthesize() bool {
 	}
 
 	var (
-		in            = pp.Inputs[0].Buffer
-		outLeft       = pp.Outputs[0].Buffer
-		outRight      = pp.Outputs[1].Buffer
-		delayTimeLine line.Line
+		in       = pp.Inputs[0].Buffer
+		outLeft  = pp.Outputs[0].Buffer
+		outRight = pp.Outputs[1].Buffer
 	)
 
-	delayTimeLine.From(timing.MilliToSampsf(pp.read, pp.Config.SampleRate))
-
-	if pp.newRead != pp.read {
-		delayTimeLine.To(timing.MilliToSampsf(pp.newRead, pp.Config.SampleRate), pp.Config.BufferSize)
-		pp.read = pp.newRead
-	}
-
-	dry := 1.0 - pp.mix
-	wet := pp.mix
-
 	for i := 0; i < pp.Config.BufferSize; i++ {
-		lookup := delayTimeLine.Generate()
+		lookup, fb, wet := pp.params(i)
+		dry := 1.0 - wet
 		left := pp.left.ReadLinear(lookup)
 		right := pp.right.ReadLinear(lookup)
 
-		pp.left.Write(in[i] + right*pp.fb)
+		pp.left.Write(in[i] + right*fb)
 		pp.right.Write(left)
 
 		outLeft[i] = in[i]*dry + left*wet
@@ -3,14 +3,23 @@ package butterworth
 import (
 	"github.com/almerlucke/muse"
 	butterworthc "github.com/almerlucke/muse/components/filters/butterworth"
+	"github.com/almerlucke/muse/components/smooth"
 	"github.com/almerlucke/muse/modules/filters"
 )
 
+var modulationInputs = []muse.ModulationInput{
+	{Name: "frequency", Index: 1},
+	{Name: "resonance", Index: 2},
+}
+
 type Butterworth struct {
 	*muse.BaseModule
 	filter butterworthc.Butterworth
 	fc     float64
 	q      float64
+
+	frequency *smooth.Param
+	resonance *smooth.Param
 }
 
 type Factory struct{}
@@ -29,6 +38,8 @@ func New(fc float64, q float64) *Butterworth {
 		BaseModule: muse.NewBaseModule(3, 1),
 		fc:         fc,
 		q:          q,
+		frequency:  smooth.New(fc, smooth.DefaultTime, smooth.Exponential, muse.SampleRate()),
+		resonance:  smooth.New(q, smooth.DefaultTime, smooth.Linear, muse.SampleRate()),
 	}
 
 	b.filter.Set(fc, q, muse.SampleRate())
@@ -39,23 +50,37 @@ func New(fc float64, q float64) *Butterworth {
 }
 
 func (b *Butterworth) Frequency() float64 {
-	return b.fc
+	return b.frequency.Target()
 }
 
 func (b *Butterworth) SetFrequency(fc float64) {
+	b.frequency.Jump(fc)
 	b.fc = fc
 	b.filter.Set(fc, b.q, b.Config.SampleRate)
 }
 
 func (b *Butterworth) Resonance() float64 {
-	return b.q
+	return b.resonance.Target()
 }
 
 func (b *Butterworth) SetResonance(q float64) {
+	b.resonance.Jump(q)
 	b.q = q
 	b.filter.Set(b.fc, b.q, b.Config.SampleRate)
 }
 
+func (b *Butterworth) SetSmoothing(time float64, mode smooth.Mode) {
+	b.frequency.SetTime(time)
+	b.frequency.SetMode(mode)
+	b.resonance.SetTime(time)
+	b.resonance.SetMode(mode)
+}
+
+// ModulationInputs returns the audio inputs that modulate parameters per sample
+func (b *Butterworth) ModulationInputs() []muse.ModulationInput {
+	return modulationInputs
+}
+
 func (b *Butterworth) Drive() float64 { return 0.0 }
 
 func (b *Butterworth) SetDrive(_ float64) {}
@@ -64,25 +89,31 @@ func (b *Butterworth) Type() int { return 0 }
 
 func (b *Butterworth) SetType(_ int) {}
 
+// Parameters describes the parameters of the filter
+func (b *Butterworth) Parameters() []muse.Parameter {
+	return []muse.Parameter{
+		{Name: "frequency", ControlIndex: 0, MessageKey: "frequency", Type: muse.ParameterFloat, Min: 20, Max: 20000, Default: 1500.0, Unit: "Hz", Scaling: muse.ScalingExponential, Value: b.Frequency()},
+		{Name: "resonance", ControlIndex: 1, MessageKey: "resonance", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.06, Value: b.Resonance()},
+	}
+}
+
 func (b *Butterworth) ReceiveControlValue(value any, index int) {
 	switch index {
 	case 0: // Cutoff Frequency
-		b.SetFrequency(value.(float64))
+		muse.ReceiveControl(b, index, value, b.frequency.Receive)
 	case 1: // Resonance
-		b.SetResonance(value.(float64))
+		muse.ReceiveControl(b, index, value, b.resonance.Receive)
 	}
 }
 
 func (b *Butterworth) ReceiveMessage(msg any) []*muse.Message {
-	content := msg.(map[string]any)
-
-	if fc, ok := content["frequency"]; ok {
-		b.SetFrequency(fc.(float64))
+	content, ok := muse.DecodeMessage(b, msg)
+	if !ok {
+		return nil
 	}
 
-	if res, ok := content["resonance"]; ok {
-		b.SetResonance(res.(float64))
-	}
+	content.Receive("frequency", b.frequency.Receive)
+	content.Receive("resonance", b.resonance.Receive)
 
 	return nil
 }
@@ -97,21 +128,25 @@ func (b *Butterworth) Synthesize() bool {
 
 	for i := 0; i < b.Config.BufferSize; i++ {
 		needUpdate := false
-		fc := b.fc
-		q := b.q
 
 		if b.Inputs[1].IsConnected() {
 			needUpdate = true
-			fc = b.Inputs[1].Buffer[i]
+			b.fc = b.Inputs[1].Buffer[i]
+		} else if b.frequency.IsSmoothing() || b.fc != b.frequency.Value() {
+			needUpdate = true
+			b.fc = b.frequency.Next()
 		}
 
 		if b.Inputs[2].IsConnected() {
 			needUpdate = true
-			q = b.Inputs[2].Buffer[i]
+			b.q = b.Inputs[2].Buffer[i]
+		} else if b.resonance.IsSmoothing() || b.q != b.resonance.Value() {
+			needUpdate = true
+			b.q = b.resonance.Next()
 		}
 
 		if needUpdate {
-			b.filter.Set(fc, q, b.Config.SampleRate)
+			b.filter.Set(b.fc, b.q, b.Config.SampleRate)
 		}
 
 		out[i] = b.filter.Process(in[i])
@@ -1,6 +1,7 @@
 package korg35
 
 import (
+	"github.com/almerlucke/muse/components/smooth"
 	"github.com/almerlucke/muse/modules/filters"
 	"math"
 
@@ -82,6 +83,12 @@ func (op *onePole) apTick(xn float64) float64 {
 	return apOut
 }
 
+var modulationInputs = []muse.ModulationInput{
+	{Name: "frequency", Index: 1},
+	{Name: "resonance", Index: 2},
+	{Name: "drive", Index: 3},
+}
+
 type LPF struct {
 	*muse.BaseModule
 	lpf1 *onePole
@@ -92,6 +99,10 @@ type LPF struct {
 	k    float64
 	sat  float64
 	nlp  bool
+
+	frequency  *smooth.Param
+	resonance  *smooth.Param
+	saturation *smooth.Param
 }
 
 type Factory struct{}
@@ -116,6 +127,9 @@ func New(fc float64, res float64, sat float64) *LPF {
 		k:          res,
 		sat:        sat,
 		nlp:        true,
+		frequency:  smooth.New(fc, smooth.DefaultTime, smooth.Exponential, sr),
+		resonance:  smooth.New(res, smooth.DefaultTime, smooth.Linear, sr),
+		saturation: smooth.New(sat, smooth.DefaultTime, smooth.Linear, sr),
 	}
 
 	korg.SetSelf(korg)
@@ -145,60 +159,79 @@ func (klpf *LPF) update() {
 }
 
 func (klpf *LPF) Frequency() float64 {
-	return klpf.fc
+	return klpf.frequency.Target()
 }
 
 func (klpf *LPF) SetFrequency(fc float64) {
+	klpf.frequency.Jump(fc)
 	klpf.fc = fc
 	klpf.update()
 }
 
 func (klpf *LPF) Resonance() float64 {
-	return klpf.k
+	return klpf.resonance.Target()
 }
 
 func (klpf *LPF) SetResonance(res float64) {
+	klpf.resonance.Jump(res)
 	klpf.k = res
 	klpf.update()
 }
 
 func (klpf *LPF) Drive() float64 {
-	return klpf.sat
+	return klpf.saturation.Target()
 }
 
 func (klpf *LPF) SetDrive(drive float64) {
+	klpf.saturation.Jump(drive)
 	klpf.sat = drive
 }
 
+func (klpf *LPF) SetSmoothing(time float64, mode smooth.Mode) {
+	for _, p := range []*smooth.Param{klpf.frequency, klpf.resonance, klpf.saturation} {
+		p.SetTime(time)
+		p.SetMode(mode)
+	}
+}
+
+// ModulationInputs returns the audio inputs that modulate parameters per sample
+func (klpf *LPF) ModulationInputs() []muse.ModulationInput {
+	return modulationInputs
+}
+
 func (klpf *LPF) Type() int { return 0 }
 
 func (klpf *LPF) SetType(_ int) {}
 
+// Parameters describes the parameters of the filter
+func (klpf *LPF) Parameters() []muse.Parameter {
+	return []muse.Parameter{
+		{Name: "frequency", ControlIndex: 0, MessageKey: "frequency", Type: muse.ParameterFloat, Min: 20, Max: 20000, Default: 1500.0, Unit: "Hz", Scaling: muse.ScalingExponential, Value: klpf.Frequency()},
+		{Name: "resonance", ControlIndex: 1, MessageKey: "resonance", Type: muse.ParameterFloat, Min: 0.01, Max: 2, Default: 0.9, Value: klpf.Resonance()},
+		{Name: "drive", ControlIndex: 2, MessageKey: "drive", Type: muse.ParameterFloat, Min: 0, Max: 10, Default: 2.0, Value: klpf.Drive()},
+	}
+}
+
 func (klpf *LPF) ReceiveControlValue(value any, index int) {
 	switch index {
 	case 0: // Cutoff Frequency
-		klpf.SetFrequency(value.(float64))
+		muse.ReceiveControl(klpf, index, value, klpf.frequency.Receive)
 	case 1: // Resonance (0.01 - 2.0)
-		klpf.SetResonance(value.(float64))
+		muse.ReceiveControl(klpf, index, value, klpf.resonance.Receive)
 	case 2: // Saturation
-		klpf.SetDrive(value.(float64))
+		muse.ReceiveControl(klpf, index, value, klpf.saturation.Receive)
 	}
 }
 
 func (klpf *LPF) ReceiveMessage(msg any) []*muse.Message {
-	content := msg.(map[string]any)
-
-	if fc, ok := content["frequency"]; ok {
-		klpf.SetFrequency(fc.(float64))
+	content, ok := muse.DecodeMessage(klpf, msg)
+	if !ok {
+		return nil
 	}
 
-	if res, ok := content["resonance"]; ok {
-		klpf.SetResonance(res.(float64))
-	}
-
-	if sat, ok := content["drive"]; ok {
-		klpf.SetDrive(sat.(float64))
-	}
+	content.Receive("frequency", klpf.frequency.Receive)
+	content.Receive("resonance", klpf.resonance.Receive)
+	content.Receive("drive", klpf.saturation.Receive)
 
 	return nil
 }
@@ -212,16 +245,32 @@ func (klpf *LPF) Synthesize() bool {
 	in := klpf.Inputs[0].Buffer
 
 	for i := 0; i < klpf.Config.BufferSize; i++ {
+		needUpdate := false
+
 		if klpf.Inputs[1].IsConnected() {
-			klpf.SetFrequency(klpf.Inputs[1].Buffer[i])
+			needUpdate = true
+			klpf.fc = klpf.Inputs[1].Buffer[i]
+		} else if klpf.frequency.IsSmoothing() || klpf.fc != klpf.frequency.Value() {
+			needUpdate = true
+			klpf.fc = klpf.frequency.Next()
 		}
 
 		if klpf.Inputs[2].IsConnected() {
-			klpf.SetResonance(klpf.Inputs[2].Buffer[i])
+			needUpdate = true
+			klpf.k = klpf.Inputs[2].Buffer[i]
+		} else if klpf.resonance.IsSmoothing() || klpf.k != klpf.resonance.Value() {
+			needUpdate = true
+			klpf.k = klpf.resonance.Next()
+		}
+
+		if needUpdate {
+			klpf.update()
 		}
 
 		if klpf.Inputs[3].IsConnected() {
-			klpf.SetDrive(klpf.Inputs[3].Buffer[i])
+			klpf.sat = klpf.Inputs[3].Buffer[i]
+		} else {
+			klpf.sat = klpf.saturation.Next()
 		}
 
 		y1 := klpf.lpf1.lpTick(in[i])
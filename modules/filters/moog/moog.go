@@ -1,6 +1,7 @@
 package moog
 
 import (
+	"github.com/almerlucke/muse/components/smooth"
 	"github.com/almerlucke/muse/modules/filters"
 	"math"
 
@@ -21,6 +22,12 @@ const (
 	VT = 0.312
 )
 
+var modulationInputs = []muse.ModulationInput{
+	{Name: "frequency", Index: 1},
+	{Name: "resonance", Index: 2},
+	{Name: "drive", Index: 3},
+}
+
 type Moog struct {
 	*muse.BaseModule
 	v     [4]float64
@@ -31,6 +38,10 @@ type Moog struct {
 	x     float64
 	g     float64
 	drive float64
+
+	frequency  *smooth.Param
+	resonance  *smooth.Param
+	driveParam *smooth.Param
 }
 
 type Factory struct{}
@@ -49,6 +60,12 @@ func New(fc float64, res float64, drive float64) *Moog {
 		BaseModule: muse.NewBaseModule(4, 1),
 	}
 
+	sr := m.Config.SampleRate
+
+	m.frequency = smooth.New(fc, smooth.DefaultTime, smooth.Exponential, sr)
+	m.resonance = smooth.New(res, smooth.DefaultTime, smooth.Linear, sr)
+	m.driveParam = smooth.New(drive, smooth.DefaultTime, smooth.Linear, sr)
+
 	m.SetDrive(drive)
 	m.SetResonance(res)
 	m.SetFrequency(fc)
@@ -59,60 +76,87 @@ func New(fc float64, res float64, drive float64) *Moog {
 }
 
 func (m *Moog) Frequency() float64 {
-	return m.fc
+	return m.frequency.Target()
 }
 
 func (m *Moog) SetFrequency(fc float64) {
+	m.frequency.Jump(fc)
+	m.setFrequency(fc)
+}
+
+func (m *Moog) setFrequency(fc float64) {
 	m.fc = fc
 	m.x = (math.Pi * fc) / m.Config.SampleRate
 	m.g = 4.0 * math.Pi * VT * fc * (1.0 - m.x) / (1.0 + m.x)
 }
 
 func (m *Moog) Resonance() float64 {
-	return m.res
+	return m.resonance.Target()
 }
 
 func (m *Moog) SetResonance(res float64) {
+	m.resonance.Jump(res)
+	m.setResonance(res)
+}
+
+func (m *Moog) setResonance(res float64) {
 	m.res = res * 4.0
 }
 
 func (m *Moog) Drive() float64 {
-	return m.drive
+	return m.driveParam.Target()
 }
 
 func (m *Moog) SetDrive(drive float64) {
+	m.driveParam.Jump(drive)
 	m.drive = drive
 }
 
+func (m *Moog) SetSmoothing(time float64, mode smooth.Mode) {
+	for _, p := range []*smooth.Param{m.frequency, m.resonance, m.driveParam} {
+		p.SetTime(time)
+		p.SetMode(mode)
+	}
+}
+
+// ModulationInputs returns the audio inputs that modulate parameters per sample
+func (m *Moog) ModulationInputs() []muse.ModulationInput {
+	return modulationInputs
+}
+
 func (m *Moog) Type() int { return 0 }
 
 func (m *Moog) SetType(_ int) {}
 
+// Parameters describes the parameters of the filter
+func (m *Moog) Parameters() []muse.Parameter {
+	return []muse.Parameter{
+		{Name: "frequency", ControlIndex: 0, MessageKey: "frequency", Type: muse.ParameterFloat, Min: 20, Max: 20000, Default: 1500.0, Unit: "Hz", Scaling: muse.ScalingExponential, Value: m.Frequency()},
+		{Name: "resonance", ControlIndex: 1, MessageKey: "resonance", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.3, Value: m.Resonance()},
+		{Name: "drive", ControlIndex: 2, MessageKey: "drive", Type: muse.ParameterFloat, Min: 0, Max: 10, Default: 1.0, Value: m.Drive()},
+	}
+}
+
 func (m *Moog) ReceiveControlValue(value any, index int) {
 	switch index {
 	case 0: // Cutoff Frequency
-		m.SetFrequency(value.(float64))
+		muse.ReceiveControl(m, index, value, m.frequency.Receive)
 	case 1: // Resonance
-		m.SetResonance(value.(float64))
+		muse.ReceiveControl(m, index, value, m.resonance.Receive)
 	case 2: // Drive
-		m.SetDrive(value.(float64))
+		muse.ReceiveControl(m, index, value, m.driveParam.Receive)
 	}
 }
 
 func (m *Moog) ReceiveMessage(msg any) []*muse.Message {
-	content := msg.(map[string]any)
-
-	if fc, ok := content["frequency"].(float64); ok {
-		m.SetFrequency(fc)
+	content, ok := muse.DecodeMessage(m, msg)
+	if !ok {
+		return nil
 	}
 
-	if res, ok := content["resonance"].(float64); ok {
-		m.SetResonance(res)
-	}
-
-	if drive, ok := content["drive"].(float64); ok {
-		m.SetDrive(drive)
-	}
+	content.Receive("frequency", m.frequency.Receive)
+	content.Receive("resonance", m.resonance.Receive)
+	content.Receive("drive", m.driveParam.Receive)
 
 	return nil
 }
@@ -130,15 +174,21 @@ func (m *Moog) Synthesize() bool {
 	for i := 0; i < m.Config.BufferSize; i++ {
 
 		if m.Inputs[1].IsConnected() {
-			m.SetFrequency(m.Inputs[1].Buffer[i])
+			m.setFrequency(m.Inputs[1].Buffer[i])
+		} else if m.frequency.IsSmoothing() || m.fc != m.frequency.Value() {
+			m.setFrequency(m.frequency.Next())
 		}
 
 		if m.Inputs[2].IsConnected() {
-			m.SetResonance(m.Inputs[2].Buffer[i])
+			m.setResonance(m.Inputs[2].Buffer[i])
+		} else {
+			m.setResonance(m.resonance.Next())
 		}
 
 		if m.Inputs[3].IsConnected() {
-			m.SetDrive(m.Inputs[3].Buffer[i])
+			m.drive = m.Inputs[3].Buffer[i]
+		} else {
+			m.drive = m.driveParam.Next()
 		}
 
 		dV0 = -m.g * (math.Tanh((m.drive*in[i]+m.res*m.v[3])/(2.0*VT)) + m.tV[0])
@@ -3,14 +3,20 @@ package rbj
 import (
 	"github.com/almerlucke/muse"
 	rbjc "github.com/almerlucke/muse/components/filters/rbj"
+	"github.com/almerlucke/muse/components/smooth"
 	"github.com/almerlucke/muse/modules/filters"
 )
 
+var modulationInputs = []muse.ModulationInput{
+	{Name: "frequency", Index: 1},
+	{Name: "resonance", Index: 2},
+}
+
 type Filter struct {
 	*muse.BaseModule
-	filter *rbjc.Filter
-	fc     float64
-	q      float64
+	filter    *rbjc.Filter
+	frequency *smooth.Param
+	resonance *smooth.Param
 }
 
 type Factory struct{}
@@ -28,8 +34,8 @@ func New(filterType rbjc.FilterType, fc float64, q float64) *Filter {
 	rbj := &Filter{
 		BaseModule: muse.NewBaseModule(3, 1),
 		filter:     rbjc.NewFilter(filterType, fc, q, 0, false, muse.SampleRate()),
-		fc:         fc,
-		q:          q,
+		frequency:  smooth.New(fc, smooth.DefaultTime, smooth.Exponential, muse.SampleRate()),
+		resonance:  smooth.New(q, smooth.DefaultTime, smooth.Linear, muse.SampleRate()),
 	}
 
 	rbj.SetSelf(rbj)
@@ -38,23 +44,37 @@ func New(filterType rbjc.FilterType, fc float64, q float64) *Filter {
 }
 
 func (r *Filter) Resonance() float64 {
-	return r.q
+	return r.resonance.Target()
 }
 
 func (r *Filter) SetResonance(q float64) {
+	r.resonance.Jump(q)
 	r.filter.Q = q
 	r.filter.Update(r.Config.SampleRate)
 }
 
 func (r *Filter) Frequency() float64 {
-	return r.fc
+	return r.frequency.Target()
 }
 
 func (r *Filter) SetFrequency(fc float64) {
+	r.frequency.Jump(fc)
 	r.filter.Frequency = fc
 	r.filter.Update(r.Config.SampleRate)
 }
 
+func (r *Filter) SetSmoothing(time float64, mode smooth.Mode) {
+	r.frequency.SetTime(time)
+	r.frequency.SetMode(mode)
+	r.resonance.SetTime(time)
+	r.resonance.SetMode(mode)
+}
+
+// ModulationInputs returns the audio inputs that modulate parameters per sample
+func (r *Filter) ModulationInputs() []muse.ModulationInput {
+	return modulationInputs
+}
+
 func (r *Filter) Drive() float64 { return 0 }
 
 func (r *Filter) SetDrive(_ float64) {}
@@ -68,38 +88,39 @@ func (r *Filter) Type() int {
 	return int(r.filter.FilterType)
 }
 
+// Parameters describes the parameters of the filter
+func (r *Filter) Parameters() []muse.Parameter {
+	return []muse.Parameter{
+		{Name: "frequency", ControlIndex: 0, MessageKey: "frequency", Type: muse.ParameterFloat, Min: 20, Max: 20000, Default: 1500.0, Unit: "Hz", Scaling: muse.ScalingExponential, Value: r.Frequency()},
+		{Name: "resonance", ControlIndex: 1, MessageKey: "resonance", Type: muse.ParameterFloat, Min: 0.1, Max: 20, Default: 1.0, Scaling: muse.ScalingExponential, Value: r.Resonance()},
+		{Name: "type", ControlIndex: 2, MessageKey: "type", Type: muse.ParameterInt, Min: 0, Max: 7, Default: 0, Value: r.Type()},
+	}
+}
+
 func (r *Filter) ReceiveControlValue(value any, index int) {
 	switch index {
 	case 0: // Cutoff Frequency
-		r.SetFrequency(value.(float64))
+		muse.ReceiveControl(r, index, value, r.frequency.Receive)
 	case 1: // Resonance
-		r.SetResonance(value.(float64))
+		muse.ReceiveControl(r, index, value, r.resonance.Receive)
 	case 2: // Filter Mode
-		if fltVal, ok := value.(float64); ok {
-			r.SetType(int(fltVal))
-		} else if intVal, ok := value.(int); ok {
-			r.SetType(intVal)
+		if t, ok := muse.ControlFloat(r, index, value); ok {
+			r.SetType(int(t))
 		}
 	}
 }
 
 func (r *Filter) ReceiveMessage(msg any) []*muse.Message {
-	content := msg.(map[string]any)
-
-	if fc, ok := content["frequency"]; ok {
-		r.SetFrequency(fc.(float64))
+	content, ok := muse.DecodeMessage(r, msg)
+	if !ok {
+		return nil
 	}
 
-	if res, ok := content["resonance"]; ok {
-		r.SetResonance(res.(float64))
-	}
+	content.Receive("frequency", r.frequency.Receive)
+	content.Receive("resonance", r.resonance.Receive)
 
-	if t, ok := content["type"]; ok {
-		if fltVal, ok := t.(float64); ok {
-			r.SetType(int(fltVal))
-		} else if intVal, ok := t.(int); ok {
-			r.SetType(intVal)
-		}
+	if t, ok := content.Float("type"); ok {
+		r.SetType(int(t))
 	}
 
 	return nil
@@ -112,17 +133,23 @@ func (r *Filter) Synthesize() bool {
 
 	rawIn := r.Inputs[0].Buffer
 	filterOut := r.Outputs[0].Buffer
-	recalculate := false
-
 	for i := 0; i < r.Config.BufferSize; i++ {
+		recalculate := false
+
 		if r.Inputs[1].IsConnected() {
 			recalculate = true
 			r.filter.Frequency = r.Inputs[1].Buffer[i]
+		} else if r.frequency.IsSmoothing() || r.filter.Frequency != r.frequency.Value() {
+			recalculate = true
+			r.filter.Frequency = r.frequency.Next()
 		}
 
 		if r.Inputs[2].IsConnected() {
 			recalculate = true
 			r.filter.Q = r.Inputs[2].Buffer[i]
+		} else if r.resonance.IsSmoothing() || r.filter.Q != r.resonance.Value() {
+			recalculate = true
+			r.filter.Q = r.resonance.Next()
 		}
 
 		if recalculate {
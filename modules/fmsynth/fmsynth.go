@@ -114,6 +114,17 @@ func (fm *FMSynth) noteOff(identifier int) {
 	}
 }
 
+// Parameters describes the note messages of the synth, noteOn and noteOff hold a MIDI pitch, level and
+// duration in milliseconds are read together with noteOn
+func (fm *FMSynth) Parameters() []muse.Parameter {
+	return []muse.Parameter{
+		{Name: "noteOn", ControlIndex: -1, MessageKey: "noteOn", Type: muse.ParameterInt, Min: 0, Max: 127, Default: 60},
+		{Name: "noteOff", ControlIndex: -1, MessageKey: "noteOff", Type: muse.ParameterInt, Min: 0, Max: 127, Default: 60},
+		{Name: "level", ControlIndex: -1, MessageKey: "level", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 1.0},
+		{Name: "duration", ControlIndex: -1, MessageKey: "duration", Type: muse.ParameterFloat, Min: 0, Max: 10000, Default: 0.0, Unit: "ms"},
+	}
+}
+
 func (fm *FMSynth) ReceiveControlValue(value any, index int) {
 	// switch index {
 	// case 0: // NoteOn
@@ -122,23 +133,33 @@ func (fm *FMSynth) ReceiveControlValue(value any, index int) {
 }
 
 func (fm *FMSynth) ReceiveMessage(msg any) []*muse.Message {
-	if params, ok := msg.(map[string]any); ok {
-		if noteOnIdentifier, ok := params["noteOn"]; ok {
-			duration := 0.0
-			if durationRaw, ok := params["duration"]; ok {
-				duration = durationRaw.(float64) / 1000.0
-			}
-			level := params["level"].(float64)
-			pitch := noteOnIdentifier.(int)
-			voice := fm.getVoice()
-			if voice != nil {
-				voice.identifier = pitch
-				voice.ops.NoteOn(notes.Mtof(pitch), level, duration)
-			}
-		} else if noteOffIdentifier, ok := params["noteOff"]; ok {
-			pitch := noteOffIdentifier.(int)
-			fm.noteOff(pitch)
+	content, ok := muse.DecodeMessage(fm, msg)
+	if !ok {
+		return nil
+	}
+
+	if pitch, ok := content.Int("noteOn"); ok {
+		duration := 0.0
+		if durationMilli, ok := content.Float("duration"); ok {
+			duration = durationMilli / 1000.0
+		}
+
+		if !content.Require("level") {
+			return nil
+		}
+
+		level, ok := content.Float("level")
+		if !ok {
+			return nil
+		}
+
+		voice := fm.getVoice()
+		if voice != nil {
+			voice.identifier = pitch
+			voice.ops.NoteOn(notes.Mtof(pitch), level, duration)
 		}
+	} else if pitch, ok := content.Int("noteOff"); ok {
+		fm.noteOff(pitch)
 	}
 
 	return nil
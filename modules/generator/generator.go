@@ -9,11 +9,14 @@ type ControlFunction func(any, int)
 
 type MessageFunction func(any) []*muse.Message
 
+type ParameterFunction func() []muse.Parameter
+
 type Generator struct {
 	*muse.BaseModule
 	gen             float.FrameGenerator
 	controlFunction ControlFunction
 	messageFunction MessageFunction
+	paramFunction   ParameterFunction
 }
 
 func NewBasic(gen float.FrameGenerator) *Generator {
@@ -33,6 +36,19 @@ func New(gen float.FrameGenerator, controlFunction ControlFunction, messageFunct
 	return gg
 }
 
+// SetParameterFunction sets the function describing the parameters handled by the control and message functions
+func (g *Generator) SetParameterFunction(paramFunction ParameterFunction) {
+	g.paramFunction = paramFunction
+}
+
+func (g *Generator) Parameters() []muse.Parameter {
+	if g.paramFunction != nil {
+		return g.paramFunction()
+	}
+
+	return nil
+}
+
 func (g *Generator) ReceiveControlValue(value any, index int) {
 	if g.controlFunction != nil {
 		g.controlFunction(value, index)
@@ -110,6 +110,16 @@ func New(numOutputs int, sf utils.Factory[Source], ef utils.Factory[Envelope], g
 	return gl
 }
 
+// Parameters returns the parameters of the parameter generator, controls and messages are passed on to it
+func (gl *Granulator) Parameters() []muse.Parameter {
+	return muse.ParametersOf(gl.paramGen)
+}
+
+// SetSeed seeds the parameter generator if it has a random source
+func (gl *Granulator) SetSeed(seed uint64) {
+	muse.SeedObjects(seed, gl.paramGen)
+}
+
 func (gl *Granulator) ReceiveControlValue(value any, index int) {
 	gl.paramGen.ReceiveControlValue(value, index)
 }
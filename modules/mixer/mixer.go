@@ -1,20 +1,25 @@
 package mixer
 
-import "github.com/almerlucke/muse"
+import (
+	"fmt"
+
+	"github.com/almerlucke/muse"
+	"github.com/almerlucke/muse/components/smooth"
+)
 
 type Mixer struct {
 	*muse.BaseModule
-	mix []float64
+	mix []*smooth.Param
 }
 
 func New(numInputs int) *Mixer {
 	m := &Mixer{
 		BaseModule: muse.NewBaseModule(numInputs, 1),
-		mix:        make([]float64, numInputs),
+		mix:        make([]*smooth.Param, numInputs),
 	}
 
 	for i := 0; i < numInputs; i++ {
-		m.mix[i] = 0.0
+		m.mix[i] = smooth.New(0.0, smooth.DefaultTime, smooth.Linear, m.Config.SampleRate)
 	}
 
 	m.SetSelf(m)
@@ -23,43 +28,74 @@ func New(numInputs int) *Mixer {
 }
 
 func (m *Mixer) MixAt(i int) float64 {
-	return m.mix[i]
+	return m.mix[i].Target()
 }
 
 func (m *Mixer) Mix() []float64 {
-	return m.mix
+	mix := make([]float64, len(m.mix))
+
+	for i, p := range m.mix {
+		mix[i] = p.Target()
+	}
+
+	return mix
 }
 
 func (m *Mixer) SetMixAt(i int, mix float64) {
-	m.mix[i] = mix
+	m.mix[i].Jump(mix)
 }
 
 func (m *Mixer) SetMix(mix []float64) {
-	m.mix = mix
+	for i, p := range m.mix {
+		if i < len(mix) {
+			p.Jump(mix[i])
+		}
+	}
 }
 
-func (m *Mixer) ReceiveControlValue(value any, index int) {
-	m.SetMixAt(index, value.(float64))
+func (m *Mixer) SetSmoothing(time float64, mode smooth.Mode) {
+	for _, p := range m.mix {
+		p.SetTime(time)
+		p.SetMode(mode)
+	}
 }
 
-func (m *Mixer) ReceiveMessage(msg any) []*muse.Message {
-	content := msg.(map[string]any)
+// Parameters describes the mix levels, one for each input. Messages set a level with an "index"
+// and a "mix" key
+func (m *Mixer) Parameters() []muse.Parameter {
+	params := make([]muse.Parameter, len(m.mix))
 
-	index := -1
-	mix := 0.0
+	for i, p := range m.mix {
+		params[i] = muse.Parameter{Name: fmt.Sprintf("mix%d", i+1), ControlIndex: i, MessageKey: "mix", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.0, Value: p.Target()}
+	}
+
+	return params
+}
 
-	if rawIndex, ok := content["index"]; ok {
-		index = rawIndex.(int)
+func (m *Mixer) ReceiveControlValue(value any, index int) {
+	if index >= 0 && index < len(m.mix) {
+		muse.ReceiveControl(m, index, value, m.mix[index].Receive)
 	}
+}
 
-	if rawMix, ok := content["mix"]; ok {
-		mix = rawMix.(float64)
+func (m *Mixer) ReceiveMessage(msg any) []*muse.Message {
+	content, ok := muse.DecodeMessage(m, msg)
+	if !ok {
+		return nil
+	}
+
+	index, ok := content.Int("index")
+	if !ok {
+		return nil
 	}
 
-	if index > -1 {
-		m.SetMixAt(index, mix)
+	if index < 0 || index >= len(m.mix) {
+		content.Invalid("index", "out of range")
+		return nil
 	}
 
+	content.Receive("mix", m.mix[index].Receive)
+
 	return nil
 }
 
@@ -74,8 +110,9 @@ func (m *Mixer) Synthesize() bool {
 		outSamp := 0.0
 
 		for j, in := range m.Inputs {
+			mix := m.mix[j].Next()
 			if in.IsConnected() {
-				outSamp += in.Buffer[i] * m.mix[j]
+				outSamp += in.Buffer[i] * mix
 			}
 		}
 
@@ -22,6 +22,10 @@ func New(seed uint64) *Noise {
 	return n
 }
 
+func (n *Noise) SetSeed(seed uint64) {
+	n.r.Seed(seed)
+}
+
 func (n *Noise) Synthesize() bool {
 	if !n.BaseModule.Synthesize() {
 		return false
@@ -4,16 +4,25 @@ import (
 	"math"
 
 	"github.com/almerlucke/muse"
+	"github.com/almerlucke/muse/components/smooth"
 )
 
+// oscModulationInputs are the audio inputs of Osc that modulate parameters per sample
+var oscModulationInputs = []muse.ModulationInput{
+	{Name: "frequency", Index: 0},
+	{Name: "phase", Index: 1},
+	{Name: "pulseWidth", Index: 2},
+}
+
 // Osc Band limited oscillator
 type Osc struct {
 	*muse.BaseModule
 	lastOutput float64
 	phase      float64
-	frequency  float64
-	pw         float64
-	mix        [4]float64
+	frequency  *smooth.Param
+	pw         *smooth.Param
+	mix        [4]*smooth.Param
+	timeline   muse.Timeline[muse.ControlEvent]
 }
 
 func New(frequency float64, phase float64) *Osc {
@@ -23,31 +32,50 @@ func New(frequency float64, phase float64) *Osc {
 func NewX(frequency float64, phase float64, pw float64, mix [4]float64) *Osc {
 	osc := &Osc{
 		BaseModule: muse.NewBaseModule(3, 5),
-		frequency:  frequency,
 		phase:      phase,
-		pw:         pw,
-		mix:        mix,
 	}
 
+	sr := osc.Config.SampleRate
+
+	// Frequency changes are immediate by default so notes do not glide, a ramp message still glides
+	osc.frequency = smooth.New(frequency, 0, smooth.Exponential, sr)
+	osc.pw = smooth.New(pw, smooth.DefaultTime, smooth.Linear, sr)
+
+	for i, m := range mix {
+		osc.mix[i] = smooth.New(m, smooth.DefaultTime, smooth.Linear, sr)
+	}
+
+	osc.timeline = muse.NewTimeline(func(event muse.ControlEvent) {
+		muse.ReceiveControlEvent(osc, event)
+	})
+
 	osc.SetSelf(osc)
 
 	return osc
 }
 
 func (o *Osc) MixAt(i int) float64 {
-	return o.mix[i]
+	return o.mix[i].Target()
 }
 
 func (o *Osc) Mix() [4]float64 {
-	return o.mix
+	var mix [4]float64
+
+	for i, m := range o.mix {
+		mix[i] = m.Target()
+	}
+
+	return mix
 }
 
 func (o *Osc) SetMixAt(i int, mix float64) {
-	o.mix[i] = mix
+	o.mix[i].Jump(mix)
 }
 
 func (o *Osc) SetMix(mix [4]float64) {
-	o.mix = mix
+	for i, m := range mix {
+		o.mix[i].Jump(m)
+	}
 }
 
 func (o *Osc) Phase() float64 {
@@ -59,77 +87,96 @@ func (o *Osc) SetPhase(ph float64) {
 }
 
 func (o *Osc) SetFrequency(fc float64) {
-	o.frequency = fc
+	o.frequency.Jump(fc)
 }
 
 func (o *Osc) Frequency() float64 {
-	return o.frequency
+	return o.frequency.Target()
 }
 
 func (o *Osc) SetPulseWidth(pw float64) {
-	o.pw = pw
+	o.pw.Jump(pw)
 }
 
 func (o *Osc) PulseWidth() float64 {
-	return o.pw
+	return o.pw.Target()
+}
+
+// SetSmoothing sets the smoothing of pulse width and mix, frequency is only smoothed by ramp messages
+func (o *Osc) SetSmoothing(time float64, mode smooth.Mode) {
+	o.pw.SetTime(time)
+	o.pw.SetMode(mode)
+
+	for _, m := range o.mix {
+		m.SetTime(time)
+		m.SetMode(mode)
+	}
+}
+
+// ModulationInputs returns the audio inputs that modulate parameters per sample
+func (o *Osc) ModulationInputs() []muse.ModulationInput {
+	return oscModulationInputs
+}
+
+// Parameters describes the parameters of the oscillator
+func (o *Osc) Parameters() []muse.Parameter {
+	mix := o.Mix()
+
+	return []muse.Parameter{
+		{Name: "frequency", ControlIndex: 0, MessageKey: "frequency", Type: muse.ParameterFloat, Min: 20, Max: 20000, Default: 440.0, Unit: "Hz", Scaling: muse.ScalingExponential, Value: o.Frequency()},
+		{Name: "phase", ControlIndex: 1, MessageKey: "phase", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.0, Value: o.Phase()},
+		{Name: "pulseWidth", ControlIndex: 2, MessageKey: "pulseWidth", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.5, Value: o.PulseWidth()},
+		{Name: "mix1", ControlIndex: 3, MessageKey: "mix1", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.5, Value: mix[0]},
+		{Name: "mix2", ControlIndex: 4, MessageKey: "mix2", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.01, Value: mix[1]},
+		{Name: "mix3", ControlIndex: 5, MessageKey: "mix3", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.1, Value: mix[2]},
+		{Name: "mix4", ControlIndex: 6, MessageKey: "mix4", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.5, Value: mix[3]},
+	}
 }
 
 func (o *Osc) ReceiveControlValue(value any, index int) {
 	switch index {
 	case 0: // Frequency
-		o.SetFrequency(value.(float64))
+		muse.ReceiveControl(o, index, value, o.frequency.Receive)
 	case 1: // Phase
-		o.SetPhase(value.(float64))
+		if ph, ok := muse.ControlFloat(o, index, value); ok {
+			o.SetPhase(ph)
+		}
 	case 2: // Pulse Width
-		o.SetPulseWidth(value.(float64))
+		muse.ReceiveControl(o, index, value, o.pw.Receive)
 	case 3: // Mix Sine
-		o.SetMixAt(0, value.(float64))
+		muse.ReceiveControl(o, index, value, o.mix[0].Receive)
 	case 4: // Mix Saw
-		o.SetMixAt(1, value.(float64))
+		muse.ReceiveControl(o, index, value, o.mix[1].Receive)
 	case 5: // Mix Pulse
-		o.SetMixAt(2, value.(float64))
+		muse.ReceiveControl(o, index, value, o.mix[2].Receive)
 	case 6: // Mix Tri
-		o.SetMixAt(3, value.(float64))
+		muse.ReceiveControl(o, index, value, o.mix[3].Receive)
 	}
 }
 
-func (o *Osc) ReceiveMessage(msg any) []*muse.Message {
-	params, ok := msg.(map[string]any)
-	if ok {
-		f, ok := params["frequency"]
-		if ok {
-			o.SetFrequency(f.(float64))
-		}
-
-		ph, ok := params["phase"]
-		if ok {
-			o.SetPhase(ph.(float64))
-		}
+func (o *Osc) ReceiveControlValueAt(value any, index int, offset int) {
+	o.timeline.At(offset, muse.ControlEvent{Index: index, Value: value})
+}
 
-		pw, ok := params["pulseWidth"]
-		if ok {
-			o.SetPulseWidth(pw.(float64))
-		}
+func (o *Osc) ReceiveMessageAt(msg any, offset int) []*muse.Message {
+	o.timeline.At(offset, muse.ControlEvent{Index: muse.MessageIndex, Value: msg})
 
-		mix1, ok := params["mix1"]
-		if ok {
-			o.SetMixAt(0, mix1.(float64))
-		}
+	return nil
+}
 
-		mix2, ok := params["mix2"]
-		if ok {
-			o.SetMixAt(1, mix2.(float64))
-		}
+func (o *Osc) ReceiveMessage(msg any) []*muse.Message {
+	if content, ok := muse.DecodeMessage(o, msg); ok {
+		content.Receive("frequency", o.frequency.Receive)
 
-		mix3, ok := params["mix3"]
-		if ok {
-			o.SetMixAt(2, mix3.(float64))
+		if ph, ok := content.Float("phase"); ok {
+			o.SetPhase(ph)
 		}
 
-		mix4, ok := params["mix4"]
-		if ok {
-			o.SetMixAt(3, mix4.(float64))
-		}
+		content.Receive("pulseWidth", o.pw.Receive)
+		content.Receive("mix1", o.mix[0].Receive)
+		content.Receive("mix2", o.mix[1].Receive)
+		content.Receive("mix3", o.mix[2].Receive)
+		content.Receive("mix4", o.mix[3].Receive)
 	}
 
 	return nil
@@ -152,9 +199,12 @@ func (o *Osc) Synthesize() bool {
 		return false
 	}
 
-	frequency := o.frequency
-	phaseOffset := 0.0
-	pw := o.pw
+	o.timeline.Render(o.Config.BufferSize, o.render)
+
+	return true
+}
+
+func (o *Osc) render(start int, end int) {
 
 	freqInput := o.InputAtIndex(0)
 	phaseOffsetInput := o.InputAtIndex(1)
@@ -166,23 +216,23 @@ func (o *Osc) Synthesize() bool {
 	triOut := o.OutputAtIndex(3).Buffer
 	mixOut := o.OutputAtIndex(4).Buffer
 
-	mixScale := o.mix[0] + o.mix[1] + o.mix[2] + o.mix[3]
-
-	if mixScale > 0 {
-		mixScale = 1.0 / mixScale
-	}
+	mix1, mix2, mix3, mix4 := o.mix[0], o.mix[1], o.mix[2], o.mix[3]
+	mixScale := 0.0
+	updateMixScale := true
 
-	for i := 0; i < o.Config.BufferSize; i++ {
+	for i := start; i < end; i++ {
 		var sinSamp, sawSamp, pwSamp, sqrSamp, triSamp float64
 
-		if freqInput.IsConnected() {
-			frequency = freqInput.Buffer[i]
-		}
-		if phaseOffsetInput.IsConnected() {
-			phaseOffset = phaseOffsetInput.Buffer[i]
-		}
-		if pwInput.IsConnected() {
-			pw = pwInput.Buffer[i]
+		frequency := freqInput.Value(i, o.frequency.Next())
+		phaseOffset := phaseOffsetInput.Value(i, 0)
+		pw := pwInput.Value(i, o.pw.Next())
+
+		if updateMixScale {
+			updateMixScale = mix1.IsSmoothing() || mix2.IsSmoothing() || mix3.IsSmoothing() || mix4.IsSmoothing()
+			mixScale = mix1.Next() + mix2.Next() + mix3.Next() + mix4.Next()
+			if mixScale > 0 {
+				mixScale = 1.0 / mixScale
+			}
 		}
 
 		dt := frequency / o.Config.SampleRate
@@ -240,7 +290,7 @@ func (o *Osc) Synthesize() bool {
 		triOut[i] = triSamp
 
 		// Mixed output
-		mixOut[i] = mixScale * (sinSamp*o.mix[0] + sawSamp*o.mix[1] + pwSamp*o.mix[2] + triSamp*o.mix[3])
+		mixOut[i] = mixScale * (sinSamp*mix1.Value() + sawSamp*mix2.Value() + pwSamp*mix3.Value() + triSamp*mix4.Value())
 
 		// Update phase
 		o.phase += dt
@@ -249,6 +299,4 @@ func (o *Osc) Synthesize() bool {
 			o.phase -= 1.0
 		}
 	}
-
-	return true
 }
@@ -4,6 +4,7 @@ import (
 	"math"
 
 	"github.com/almerlucke/muse"
+	"github.com/almerlucke/muse/components/smooth"
 )
 
 type Waveform int
@@ -60,6 +61,12 @@ func integerPart(x float64) float64 {
 	return ip
 }
 
+var osc2ModulationInputs = []muse.ModulationInput{
+	{Name: "frequency", Index: 0},
+	{Name: "pulseWidth", Index: 1},
+	{Name: "amplitude", Index: 2},
+}
+
 type Osc2 struct {
 	*muse.BaseModule
 
@@ -69,6 +76,12 @@ type Osc2 struct {
 	amp float64
 	pw  float64
 	t   float64
+
+	frequency  *smooth.Param
+	pulseWidth *smooth.Param
+	amplitude  *smooth.Param
+
+	timeline muse.Timeline[muse.ControlEvent]
 }
 
 func NewOsc2(fc float64, t float64, pw float64, amp float64, wf Waveform) *Osc2 {
@@ -77,6 +90,17 @@ func NewOsc2(fc float64, t float64, pw float64, amp float64, wf Waveform) *Osc2
 		t:          t,
 	}
 
+	sr := osc.Config.SampleRate
+
+	// Frequency changes are immediate by default so notes do not glide, a ramp message still glides
+	osc.frequency = smooth.New(fc, 0, smooth.Exponential, sr)
+	osc.pulseWidth = smooth.New(pw, smooth.DefaultTime, smooth.Linear, sr)
+	osc.amplitude = smooth.New(amp, smooth.DefaultTime, smooth.Linear, sr)
+
+	osc.timeline = muse.NewTimeline(func(event muse.ControlEvent) {
+		muse.ReceiveControlEvent(osc, event)
+	})
+
 	osc.SetSelf(osc)
 	osc.setFrequency(fc)
 	osc.setPulseWidth(pw)
@@ -86,40 +110,78 @@ func NewOsc2(fc float64, t float64, pw float64, amp float64, wf Waveform) *Osc2
 	return osc
 }
 
+func (osc *Osc2) Frequency() float64 {
+	return osc.frequency.Target()
+}
+
+func (osc *Osc2) PulseWidth() float64 {
+	return osc.pulseWidth.Target()
+}
+
+func (osc *Osc2) Amplitude() float64 {
+	return osc.amplitude.Target()
+}
+
+// SetSmoothing sets the smoothing of pulse width and amplitude, frequency is only smoothed by ramp messages
+func (osc *Osc2) SetSmoothing(time float64, mode smooth.Mode) {
+	osc.pulseWidth.SetTime(time)
+	osc.pulseWidth.SetMode(mode)
+	osc.amplitude.SetTime(time)
+	osc.amplitude.SetMode(mode)
+}
+
+// ModulationInputs returns the audio inputs that modulate parameters per sample
+func (osc *Osc2) ModulationInputs() []muse.ModulationInput {
+	return osc2ModulationInputs
+}
+
+func (osc *Osc2) Waveform() Waveform {
+	return osc.wf
+}
+
+// Parameters describes the parameters of the oscillator
+func (osc *Osc2) Parameters() []muse.Parameter {
+	return []muse.Parameter{
+		{Name: "frequency", ControlIndex: 0, MessageKey: "frequency", Type: muse.ParameterFloat, Min: 20, Max: 20000, Default: 440.0, Unit: "Hz", Scaling: muse.ScalingExponential, Value: osc.Frequency()},
+		{Name: "pulseWidth", ControlIndex: 1, MessageKey: "pulseWidth", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.5, Value: osc.PulseWidth()},
+		{Name: "amplitude", ControlIndex: 2, MessageKey: "amplitude", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 1.0, Value: osc.Amplitude()},
+		{Name: "waveform", ControlIndex: 3, MessageKey: "waveform", Type: muse.ParameterInt, Min: float64(SINE), Max: float64(TRAPEZOID_VARIABLE), Default: int(SINE), Value: int(osc.Waveform())},
+	}
+}
+
 func (osc *Osc2) ReceiveControlValue(value any, index int) {
 	switch index {
 	case 0: // Frequency
-		osc.setFrequency(value.(float64))
+		muse.ReceiveControl(osc, index, value, osc.receiveFrequency)
 	case 1: // Pulse Width
-		osc.setPulseWidth(value.(float64))
+		muse.ReceiveControl(osc, index, value, osc.receivePulseWidth)
 	case 2: // Amplitude
-		osc.setAmplitude(value.(float64))
+		muse.ReceiveControl(osc, index, value, osc.receiveAmplitude)
 	case 3: // Waveform
-		osc.setWaveform(Waveform(value.(int)))
+		if wf, ok := muse.ControlInt(osc, index, value); ok {
+			osc.setWaveform(Waveform(wf))
+		}
 	}
 }
 
-func (osc *Osc2) ReceiveMessage(msg any) []*muse.Message {
-	if params, ok := msg.(map[string]any); ok {
-		if f, ok := params["frequency"]; ok {
-			osc.setFrequency(f.(float64))
-		}
+func (osc *Osc2) ReceiveControlValueAt(value any, index int, offset int) {
+	osc.timeline.At(offset, muse.ControlEvent{Index: index, Value: value})
+}
 
-		if pw, ok := params["pulseWidth"]; ok {
-			osc.setPulseWidth(pw.(float64))
-		}
+func (osc *Osc2) ReceiveMessageAt(msg any, offset int) []*muse.Message {
+	osc.timeline.At(offset, muse.ControlEvent{Index: muse.MessageIndex, Value: msg})
 
-		if amp, ok := params["amplitude"]; ok {
-			osc.setAmplitude(amp.(float64))
-		}
+	return nil
+}
 
-		if wf, ok := params["waveform"]; ok {
-			if wff, ok := wf.(float64); ok {
-				osc.setWaveform(Waveform(int(wff)))
-			}
-			if wfi, ok := wf.(int); ok {
-				osc.setWaveform(Waveform(wfi))
-			}
+func (osc *Osc2) ReceiveMessage(msg any) []*muse.Message {
+	if content, ok := muse.DecodeMessage(osc, msg); ok {
+		content.Receive("frequency", osc.receiveFrequency)
+		content.Receive("pulseWidth", osc.receivePulseWidth)
+		content.Receive("amplitude", osc.receiveAmplitude)
+
+		if wf, ok := content.Int("waveform"); ok {
+			osc.setWaveform(Waveform(wf))
 		}
 	}
 
@@ -137,20 +199,58 @@ func (osc *Osc2) Synthesize() bool {
 
 	out := osc.OutputAtIndex(0).Buffer
 
-	for i := 0; i < osc.Config.BufferSize; i++ {
-		if freqInput.IsConnected() {
-			osc.setFrequency(freqInput.Buffer[i])
-		}
-		if pwInput.IsConnected() {
-			osc.setPulseWidth(pwInput.Buffer[i])
-		}
-		if ampInput.IsConnected() {
-			osc.setAmplitude(ampInput.Buffer[i])
+	osc.timeline.Render(osc.Config.BufferSize, func(start int, end int) {
+		for i := start; i < end; i++ {
+			if freqInput.IsConnected() {
+				osc.setFrequency(freqInput.Buffer[i])
+			} else if osc.frequency.IsSmoothing() || osc.fc != osc.frequency.Value() {
+				osc.setFrequency(osc.frequency.Next())
+			}
+			if pwInput.IsConnected() {
+				osc.setPulseWidth(pwInput.Buffer[i])
+			} else if osc.pulseWidth.IsSmoothing() || osc.pw != osc.pulseWidth.Value() {
+				osc.setPulseWidth(osc.pulseWidth.Next())
+			}
+			if ampInput.IsConnected() {
+				osc.setAmplitude(ampInput.Buffer[i])
+			} else if osc.amplitude.IsSmoothing() || osc.amp != osc.amplitude.Value() {
+				osc.setAmplitude(osc.amplitude.Next())
+			}
+
+			out[i] = osc.getAndInc()
 		}
+	})
 
-		out[i] = osc.getAndInc()
+	return true
+}
+
+func (osc *Osc2) receiveFrequency(value any) bool {
+	if !osc.frequency.Receive(value) {
+		return false
 	}
 
+	osc.setFrequency(osc.frequency.Value())
+
+	return true
+}
+
+func (osc *Osc2) receivePulseWidth(value any) bool {
+	if !osc.pulseWidth.Receive(value) {
+		return false
+	}
+
+	osc.setPulseWidth(osc.pulseWidth.Value())
+
+	return true
+}
+
+func (osc *Osc2) receiveAmplitude(value any) bool {
+	if !osc.amplitude.Receive(value) {
+		return false
+	}
+
+	osc.setAmplitude(osc.amplitude.Value())
+
 	return true
 }
 
@@ -52,6 +52,11 @@ func New(module muse.Module, converterType int) (*Oversampler, error) {
 	return osa, nil
 }
 
+// Parameters returns the parameters of the oversampled module
+func (osa *Oversampler) Parameters() []muse.Parameter {
+	return muse.ParametersOf(osa.module)
+}
+
 func (osa *Oversampler) ReceiveControlValue(value any, index int) {
 	osa.module.ReceiveControlValue(value, index)
 }
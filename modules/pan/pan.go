@@ -4,45 +4,66 @@ import (
 	"math"
 
 	"github.com/almerlucke/muse"
+	"github.com/almerlucke/muse/components/smooth"
 )
 
 var halfPi = math.Pi / 2.0
 
+var modulationInputs = []muse.ModulationInput{
+	{Name: "pan", Index: 1},
+}
+
 type Pan struct {
 	*muse.BaseModule
-	pan float64
+	pan *smooth.Param
 }
 
 func New(pan float64) *Pan {
 	p := &Pan{
 		BaseModule: muse.NewBaseModule(2, 2),
-		pan:        pan,
 	}
 
+	p.pan = smooth.New(pan, smooth.DefaultTime, smooth.Linear, p.Config.SampleRate)
+
 	p.SetSelf(p)
 
 	return p
 }
 
 func (p *Pan) Pan() float64 {
-	return p.pan
+	return p.pan.Target()
 }
 
 func (p *Pan) SetPan(pan float64) {
-	p.pan = pan
+	p.pan.Jump(pan)
+}
+
+func (p *Pan) SetSmoothing(time float64, mode smooth.Mode) {
+	p.pan.SetTime(time)
+	p.pan.SetMode(mode)
+}
+
+// ModulationInputs returns the audio inputs that modulate parameters per sample
+func (p *Pan) ModulationInputs() []muse.ModulationInput {
+	return modulationInputs
+}
+
+// Parameters describes the parameters of the panner
+func (p *Pan) Parameters() []muse.Parameter {
+	return []muse.Parameter{
+		{Name: "pan", ControlIndex: 0, MessageKey: "pan", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.5, Value: p.Pan()},
+	}
 }
 
 func (p *Pan) ReceiveControlValue(value any, index int) {
 	if index == 0 {
-		p.SetPan(value.(float64))
+		muse.ReceiveControl(p, index, value, p.pan.Receive)
 	}
 }
 
 func (p *Pan) ReceiveMessage(msg any) []*muse.Message {
-	content := msg.(map[string]any)
-
-	if pan, ok := content["pan"]; ok {
-		p.SetPan(pan.(float64))
+	if content, ok := muse.DecodeMessage(p, msg); ok {
+		content.Receive("pan", p.pan.Receive)
 	}
 
 	return nil
@@ -53,12 +74,8 @@ func (p *Pan) Synthesize() bool {
 		return false
 	}
 
-	pan := p.pan
-
 	for i := 0; i < p.Config.BufferSize; i++ {
-		if p.Inputs[1].IsConnected() {
-			pan = p.Inputs[1].Buffer[i]
-		}
+		pan := p.Inputs[1].Value(i, p.pan.Next())
 
 		inSamp := p.Inputs[0].Buffer[i]
 		panLookup := pan * halfPi
@@ -39,25 +39,34 @@ func (p *Phasor) SetFrequency(fc float64) {
 	p.fc = fc
 }
 
+// Parameters describes the parameters of the phasor
+func (p *Phasor) Parameters() []muse.Parameter {
+	return []muse.Parameter{
+		{Name: "frequency", ControlIndex: 0, MessageKey: "frequency", Type: muse.ParameterFloat, Min: 0.01, Max: 20000, Default: 1.0, Unit: "Hz", Scaling: muse.ScalingExponential, Value: p.Frequency()},
+		{Name: "phase", ControlIndex: 1, MessageKey: "phase", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.0, Value: p.Phase()},
+	}
+}
+
 func (p *Phasor) ReceiveControlValue(value any, index int) {
 	switch index {
 	case 0:
-		p.SetFrequency(value.(float64))
+		if v, ok := muse.ControlFloat(p, index, value); ok {
+			p.SetFrequency(v)
+		}
 	case 1:
-		p.SetPhase(value.(float64))
+		if v, ok := muse.ControlFloat(p, index, value); ok {
+			p.SetPhase(v)
+		}
 	}
 }
 
 func (p *Phasor) ReceiveMessage(msg any) []*muse.Message {
-	params, ok := msg.(map[string]any)
-	if ok {
-		fc, ok := params["frequency"]
-		if ok {
-			p.SetFrequency(fc.(float64))
+	if content, ok := muse.DecodeMessage(p, msg); ok {
+		if fc, ok := content.Float("frequency"); ok {
+			p.SetFrequency(fc)
 		}
-		phase, ok := params["phase"]
-		if ok {
-			p.SetPhase(phase.(float64))
+		if phase, ok := content.Float("phase"); ok {
+			p.SetPhase(phase)
 		}
 	}
 
@@ -5,6 +5,15 @@ import (
 	"github.com/almerlucke/sndfile"
 )
 
+// event is a control value, message or note scheduled on the timeline
+type event struct {
+	control   muse.ControlEvent
+	noteOn    bool
+	amplitude float64
+	message   any
+	config    *muse.Configuration
+}
+
 type Player struct {
 	*muse.BaseModule
 	sf          sndfile.SoundFiler
@@ -18,6 +27,7 @@ type Player struct {
 	oneShot     bool
 	done        bool
 	soundBank   sndfile.SoundBank
+	timeline    muse.Timeline[event]
 }
 
 func New(sf sndfile.SoundFiler, speed float64, amp float64, oneShot bool) *Player {
@@ -43,6 +53,8 @@ func NewX(sf sndfile.SoundFiler, speed float64, amp float64, startOffset float64
 		depth:      depth,
 	}
 
+	p.timeline = muse.NewTimeline(p.applyEvent)
+
 	p.startOffset = p.normalizeDurationOffset(startOffset)
 	p.endOffset = p.normalizeDurationOffset(endOffset)
 
@@ -102,6 +114,11 @@ func (p *Player) SetSpeed(speed float64) {
 	}
 }
 
+// StartOffset in seconds
+func (p *Player) StartOffset() float64 {
+	return p.startOffset * float64(p.sf.NumFrames()) / p.sf.SampleRate()
+}
+
 func (p *Player) SetStartOffset(offset float64) {
 	p.startOffset = p.normalizeDurationOffset(offset)
 }
@@ -110,6 +127,19 @@ func (p *Player) SetEndOffset(offset float64) {
 	p.endOffset = p.normalizeDurationOffset(offset)
 }
 
+// EndOffset in seconds
+func (p *Player) EndOffset() float64 {
+	return p.endOffset * float64(p.sf.NumFrames()) / p.sf.SampleRate()
+}
+
+func (p *Player) Amplitude() float64 {
+	return p.amp
+}
+
+func (p *Player) OneShot() bool {
+	return p.oneShot
+}
+
 func (p *Player) Bang() {
 	if p.oneShot {
 		p.done = false
@@ -121,6 +151,19 @@ func (p *Player) Bang() {
 	}
 }
 
+// Parameters describes the parameters of the player, offsets are in seconds and limited by the
+// duration of the current sound file
+func (p *Player) Parameters() []muse.Parameter {
+	duration := p.sf.Duration()
+
+	return []muse.Parameter{
+		{Name: "bang", ControlIndex: 0, MessageKey: "bang", Type: muse.ParameterBang},
+		{Name: "speed", ControlIndex: 1, MessageKey: "speed", Type: muse.ParameterFloat, Min: -4, Max: 4, Default: 1.0, Value: p.Speed()},
+		{Name: "startOffset", ControlIndex: 2, MessageKey: "startOffset", Type: muse.ParameterFloat, Min: 0, Max: duration, Default: 0.0, Unit: "s", Value: p.StartOffset()},
+		{Name: "endOffset", ControlIndex: 3, MessageKey: "endOffset", Type: muse.ParameterFloat, Min: 0, Max: duration, Default: duration, Unit: "s", Value: p.EndOffset()},
+	}
+}
+
 func (p *Player) ReceiveControlValue(value any, index int) {
 	switch index {
 	case 0: // Bang
@@ -128,26 +171,50 @@ func (p *Player) ReceiveControlValue(value any, index int) {
 			p.Bang()
 		}
 	case 1: // Speed
-		p.SetSpeed(value.(float64))
+		if v, ok := muse.ControlFloat(p, index, value); ok {
+			p.SetSpeed(v)
+		}
 	case 2: // Start offset
-		p.SetStartOffset(value.(float64))
+		if v, ok := muse.ControlFloat(p, index, value); ok {
+			p.SetStartOffset(v)
+		}
 	case 3: // End offset
-		p.SetEndOffset(value.(float64))
+		if v, ok := muse.ControlFloat(p, index, value); ok {
+			p.SetEndOffset(v)
+		}
+	}
+}
+
+func (p *Player) ReceiveControlValueAt(value any, index int, offset int) {
+	p.timeline.At(offset, event{control: muse.ControlEvent{Index: index, Value: value}})
+}
+
+func (p *Player) ReceiveMessageAt(msg any, offset int) []*muse.Message {
+	p.timeline.At(offset, event{control: muse.ControlEvent{Index: muse.MessageIndex, Value: msg}})
+
+	return nil
+}
+
+func (p *Player) applyEvent(e event) {
+	if e.noteOn {
+		p.activate(e.amplitude, e.message, e.config)
+	} else {
+		muse.ReceiveControlEvent(p, e.control)
 	}
 }
 
 func (p *Player) ReceiveMessage(msg any) []*muse.Message {
-	if content, ok := msg.(map[string]any); ok {
-		if speed, ok := content["speed"]; ok {
-			p.SetSpeed(speed.(float64))
+	if content, ok := muse.DecodeMessage(p, msg); ok {
+		if speed, ok := content.Float("speed"); ok {
+			p.SetSpeed(speed)
 		}
 
-		if startOffset, ok := content["startOffset"]; ok {
-			p.SetStartOffset(startOffset.(float64))
+		if startOffset, ok := content.Float("startOffset"); ok {
+			p.SetStartOffset(startOffset)
 		}
 
-		if endOffset, ok := content["endOffset"]; ok {
-			p.SetEndOffset(endOffset.(float64))
+		if endOffset, ok := content.Float("endOffset"); ok {
+			p.SetEndOffset(endOffset)
 		}
 	}
 
@@ -166,33 +233,45 @@ func (p *Player) Note(duration float64, amplitude float64, message any, config *
 	p.activate(amplitude, message, config)
 }
 
+func (p *Player) NoteOnAt(amplitude float64, message any, config *muse.Configuration, offset int) {
+	p.timeline.At(offset, event{noteOn: true, amplitude: amplitude, message: message, config: config})
+}
+
+func (p *Player) NoteAt(duration float64, amplitude float64, message any, config *muse.Configuration, offset int) {
+	p.NoteOnAt(amplitude, message, config, offset)
+}
+
 func (p *Player) NoteOff() {}
 
-func (p *Player) Clear() {}
+func (p *Player) NoteOffAt(offset int) {}
+
+func (p *Player) Clear() {
+	p.timeline.Clear()
+}
 
 func (p *Player) activate(amplitude float64, message any, config *muse.Configuration) {
-	content := message.(map[string]any)
+	content, _ := muse.DecodeMessage(p, message)
 
 	p.amp = amplitude
 
 	speed := p.speed
 
-	if sound, ok := content["sound"]; ok {
-		p.SetSound(sound.(string))
+	if sound, ok := content.String("sound"); ok {
+		p.SetSound(sound)
 	}
 
-	if newSpeed, ok := content["speed"]; ok {
-		speed = newSpeed.(float64)
+	if newSpeed, ok := content.Float("speed"); ok {
+		speed = newSpeed
 	}
 
 	p.SetSpeed(speed)
 
-	if startOffset, ok := content["startOffset"]; ok {
-		p.SetStartOffset(startOffset.(float64))
+	if startOffset, ok := content.Float("startOffset"); ok {
+		p.SetStartOffset(startOffset)
 	}
 
-	if endOffset, ok := content["endOffset"]; ok {
-		p.SetEndOffset(endOffset.(float64))
+	if endOffset, ok := content.Float("endOffset"); ok {
+		p.SetEndOffset(endOffset)
 	}
 
 	p.done = false
@@ -205,7 +284,7 @@ func (p *Player) activate(amplitude float64, message any, config *muse.Configura
 }
 
 func (p *Player) IsActive() bool {
-	return !p.oneShot || (p.oneShot && !p.done)
+	return !p.oneShot || (p.oneShot && !p.done) || p.timeline.Pending()
 }
 
 func (p *Player) Synthesize() bool {
@@ -213,7 +292,13 @@ func (p *Player) Synthesize() bool {
 		return false
 	}
 
-	for i := 0; i < p.Config.BufferSize; i++ {
+	p.timeline.Render(p.Config.BufferSize, p.render)
+
+	return true
+}
+
+func (p *Player) render(start int, end int) {
+	for i := start; i < end; i++ {
 		if p.done {
 			for _, out := range p.Outputs {
 				out.Buffer[i] = 0.0
@@ -250,6 +335,4 @@ func (p *Player) Synthesize() bool {
 			p.phase = p.startOffset + np
 		}
 	}
-
-	return true
 }
@@ -3,6 +3,7 @@ package polyphony
 import (
 	"github.com/almerlucke/muse"
 	"github.com/almerlucke/muse/utils/containers/list"
+	"github.com/almerlucke/muse/utils/workers"
 )
 
 /*
@@ -18,10 +19,27 @@ type Voice interface {
 	IsActive() bool
 }
 
+// TimedVoice is implemented by voices that can start and stop notes at a sample offset within the next block
+type TimedVoice interface {
+	Voice
+	NoteOnAt(amplitude float64, message any, config *muse.Configuration, offset int)
+	NoteAt(duration float64, amplitude float64, message any, config *muse.Configuration, offset int)
+	NoteOffAt(offset int)
+}
+
+// trigger holds the decoded values of a trigger message
+type trigger struct {
+	isNoteOn  bool
+	duration  float64
+	amplitude float64
+	message   any
+}
+
 type voiceInfo struct {
 	age            int64
 	isStolen       bool
-	nextMsg        map[string]any
+	isRendered     bool
+	next           trigger
 	nextIdentifier string
 	voice          Voice
 }
@@ -30,6 +48,9 @@ type Polyphony struct {
 	*muse.BaseModule
 	freePool   *list.List[*voiceInfo]
 	activePool *list.List[*voiceInfo]
+	pool       *workers.Pool
+	batch      *workers.Batch
+	rendering  []Voice
 }
 
 func New(numChannels int, voices []Voice) *Polyphony {
@@ -39,6 +60,8 @@ func New(numChannels int, voices []Voice) *Polyphony {
 
 	poly.freePool = list.New[*voiceInfo]()
 	poly.activePool = list.New[*voiceInfo]()
+	poly.rendering = make([]Voice, 0, len(voices))
+	poly.batch = workers.NewBatch(poly)
 
 	for _, voice := range voices {
 		poly.freePool.Push(&voiceInfo{
@@ -51,14 +74,18 @@ func New(numChannels int, voices []Voice) *Polyphony {
 	return poly
 }
 
-func (p *Polyphony) noteOff(identifier string) {
+func (p *Polyphony) noteOff(identifier string, offset int) {
 	p.CallActiveVoiceInfo(func(info *voiceInfo) bool {
 		if info.isStolen && info.nextIdentifier == identifier {
 			info.isStolen = false
-			info.nextMsg = nil
+			info.next = trigger{}
 			info.nextIdentifier = ""
 		} else if info.voice.Identifier() == identifier {
-			info.voice.NoteOff()
+			if timed, ok := info.voice.(TimedVoice); ok && offset > 0 {
+				timed.NoteOffAt(offset)
+			} else {
+				info.voice.NoteOff()
+			}
 			info.voice.SetIdentifier("")
 			return false
 		}
@@ -75,64 +102,152 @@ func (p *Polyphony) AllNotesOff() {
 	})
 }
 
+// SetSeed seeds the voices that have a random source, each voice with its own derived seed
+func (p *Polyphony) SetSeed(seed uint64) {
+	var voices []any
+
+	p.CallVoices(func(v Voice) {
+		voices = append(voices, v)
+	})
+
+	muse.SeedObjects(seed, voices...)
+}
+
+// Parameters describes the keys of trigger messages, followed by the parameters of the voices if they
+// describe them. Trigger messages need the command "trigger", voice parameters are set with the command "voice"
+func (p *Polyphony) Parameters() []muse.Parameter {
+	params := []muse.Parameter{
+		{Name: "noteOn", ControlIndex: -1, MessageKey: "noteOn", Type: muse.ParameterString},
+		{Name: "noteOff", ControlIndex: -1, MessageKey: "noteOff", Type: muse.ParameterString},
+		{Name: "duration", ControlIndex: -1, MessageKey: "duration", Type: muse.ParameterFloat, Min: 0, Max: 10000, Default: 250.0, Unit: "ms"},
+		{Name: "amplitude", ControlIndex: -1, MessageKey: "amplitude", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 1.0},
+		{Name: "message", ControlIndex: -1, MessageKey: "message", Type: muse.ParameterAny},
+	}
+
+	var first Voice
+
+	p.CallVoices(func(v Voice) {
+		if first == nil {
+			first = v
+		}
+	})
+
+	for _, param := range muse.ParametersOf(first) {
+		param.ControlIndex = -1
+		params = append(params, param)
+	}
+
+	return params
+}
+
 func (p *Polyphony) ReceiveControlValue(value any, index int) {
 	if index == 0 {
 		p.ReceiveMessage(value)
 	}
 }
 
-func (p *Polyphony) handleTriggerMessage(msg map[string]any, identifier string, duration float64, isNoteOn bool) {
+func (p *Polyphony) ReceiveControlValueAt(value any, index int, offset int) {
+	if index == 0 {
+		p.receiveMessage(value, offset)
+	}
+}
+
+func (p *Polyphony) handleTrigger(t trigger, identifier string, offset int) {
 	v := p.getFreeVoice()
 	if v != nil {
-		if isNoteOn {
+		timed, isTimed := v.(TimedVoice)
+		isTimed = isTimed && offset > 0
+
+		if t.isNoteOn {
 			v.SetIdentifier(identifier)
-			v.NoteOn(msg["amplitude"].(float64), msg["message"], p.Config)
+			if isTimed {
+				timed.NoteOnAt(t.amplitude, t.message, p.Config, offset)
+			} else {
+				v.NoteOn(t.amplitude, t.message, p.Config)
+			}
+		} else if isTimed {
+			timed.NoteAt(t.duration, t.amplitude, t.message, p.Config, offset)
 		} else {
-			v.Note(duration, msg["amplitude"].(float64), msg["message"], p.Config)
+			v.Note(t.duration, t.amplitude, t.message, p.Config)
 		}
 	} else {
 		// Steal oldest active voice
 		info := p.getOldestActiveVoiceInfo()
 		if info != nil {
 			info.isStolen = true
-			info.nextMsg = msg
+			info.next = t
 			info.nextIdentifier = identifier
 		}
 	}
 }
 
 func (p *Polyphony) activateStolenVoiceInfo(info *voiceInfo) {
-	if noteOnIdentifier, ok := info.nextMsg["noteOn"]; ok {
-		info.voice.Clear()
-		info.voice.SetIdentifier(noteOnIdentifier.(string))
-		info.voice.NoteOn(info.nextMsg["amplitude"].(float64), info.nextMsg["message"], p.Config)
-		info.isStolen = false
-		info.nextMsg = nil
-		info.age = 0
-		info.nextIdentifier = ""
-	} else if duration, ok := info.nextMsg["duration"]; ok {
-		info.voice.Clear()
-		info.voice.Note(duration.(float64), info.nextMsg["amplitude"].(float64), info.nextMsg["message"], p.Config)
-		info.isStolen = false
-		info.nextMsg = nil
-		info.age = 0
-		info.nextIdentifier = ""
+	info.voice.Clear()
+
+	if info.next.isNoteOn {
+		info.voice.SetIdentifier(info.nextIdentifier)
+		info.voice.NoteOn(info.next.amplitude, info.next.message, p.Config)
+	} else {
+		info.voice.Note(info.next.duration, info.next.amplitude, info.next.message, p.Config)
 	}
+
+	info.isStolen = false
+	info.next = trigger{}
+	info.age = 0
+	info.nextIdentifier = ""
 }
 
 // ReceiveMessage is used to activate voices
 func (p *Polyphony) ReceiveMessage(msg any) []*muse.Message {
-	content := msg.(map[string]any)
+	p.receiveMessage(msg, 0)
+	return nil
+}
+
+// ReceiveMessageAt starts or stops notes at a sample offset for voices that implement TimedVoice,
+// a stolen voice starts its new note at the start of the next block
+func (p *Polyphony) ReceiveMessageAt(msg any, offset int) []*muse.Message {
+	p.receiveMessage(msg, offset)
+	return nil
+}
+
+func (p *Polyphony) receiveMessage(msg any, offset int) {
+	content, ok := muse.DecodeMessage(p, msg)
+	if !ok || !content.Require("command") {
+		return
+	}
+
+	command, ok := content.String("command")
+	if !ok {
+		return
+	}
 
-	command := content["command"].(string)
 	if command == "trigger" {
 		// Trigger a voice
-		if noteOffIdentifier, ok := content["noteOff"]; ok {
-			p.noteOff(noteOffIdentifier.(string))
-		} else if noteOnIdentifier, ok := content["noteOn"]; ok {
-			p.handleTriggerMessage(content, noteOnIdentifier.(string), 0, true)
-		} else if duration, ok := content["duration"]; ok {
-			p.handleTriggerMessage(content, "", duration.(float64), false)
+		if noteOffIdentifier, ok := content.String("noteOff"); ok {
+			p.noteOff(noteOffIdentifier, offset)
+			return
+		}
+
+		if !content.Has("noteOn") && !content.Has("duration") {
+			content.Invalid("noteOn", "trigger needs noteOn, noteOff or duration")
+			return
+		}
+
+		if !content.Require("amplitude") {
+			return
+		}
+
+		amplitude, ok := content.Float("amplitude")
+		if !ok {
+			return
+		}
+
+		message, _ := content.Value("message")
+
+		if noteOnIdentifier, ok := content.String("noteOn"); ok {
+			p.handleTrigger(trigger{isNoteOn: true, amplitude: amplitude, message: message}, noteOnIdentifier, offset)
+		} else if duration, ok := content.Float("duration"); ok {
+			p.handleTrigger(trigger{duration: duration, amplitude: amplitude, message: message}, "", offset)
 		}
 	} else if command == "voice" {
 		// Pass message to all voices
@@ -140,8 +255,6 @@ func (p *Polyphony) ReceiveMessage(msg any) []*muse.Message {
 			v.ReceiveMessage(msg)
 		})
 	}
-
-	return nil
 }
 
 func (p *Polyphony) getOldestActiveVoiceInfo() *voiceInfo {
@@ -196,6 +309,28 @@ func (p *Polyphony) CallInactiveVoices(f func(Voice) bool) {
 	})
 }
 
+// SetWorkerPool renders the active voices in parallel on the workers of pool, voices are
+// still mixed in order so the output is identical to rendering without a pool
+func (p *Polyphony) SetWorkerPool(pool *workers.Pool) {
+	p.pool = pool
+}
+
+// SetTransport passes the transport on to the voices that follow it
+func (p *Polyphony) SetTransport(transport *muse.Transport) {
+	p.CallVoices(func(v Voice) {
+		if user, ok := v.(muse.TransportUser); ok {
+			user.SetTransport(transport)
+		}
+	})
+}
+
+// RunTask synthesizes one of the active voices
+func (p *Polyphony) RunTask(index int) {
+	voice := p.rendering[index]
+	voice.PrepareSynthesis()
+	voice.Synthesize()
+}
+
 func (p *Polyphony) Synthesize() bool {
 	if !p.BaseModule.Synthesize() {
 		return false
@@ -206,21 +341,25 @@ func (p *Polyphony) Synthesize() bool {
 		output.Buffer.Clear()
 	}
 
-	// First prepare all voices for synthesis
-	p.CallActiveVoices(func(v Voice) bool {
-		v.PrepareSynthesis()
-		return true
+	// First synthesize all active voices, voices do not depend on each other
+	p.rendering = p.rendering[:0]
+
+	p.activePool.ForEach(func(info *voiceInfo, _ int) {
+		info.isRendered = info.voice.IsActive()
+		if info.isRendered {
+			p.rendering = append(p.rendering, info.voice)
+		}
 	})
 
+	p.pool.Run(p.batch, len(p.rendering))
+
 	// Run active voices
 	p.activePool.ForEachElement(func(e *list.Element[*voiceInfo], index int) {
 		info := e.Value
 		voice := info.voice
 
-		if voice.IsActive() {
+		if info.isRendered {
 			// Add voice output to buffer
-			voice.Synthesize()
-
 			if info.isStolen {
 				// Fade out voice over 1 buffer cycle
 				x := 1.0 / float64(p.Config.BufferSize)
@@ -0,0 +1,102 @@
+package reblock
+
+import (
+	"fmt"
+
+	"github.com/almerlucke/muse"
+)
+
+/*
+Run inner module at a smaller block size, for instance a block size of 1 for
+single sample feedback loops (Karplus-Strong, FM feedback) built from modules
+with feedback connections
+*/
+
+// source feeds one input of the inner module
+type source struct {
+	*muse.BaseModule
+}
+
+func newSource() *source {
+	s := &source{BaseModule: muse.NewBaseModule(0, 1)}
+	s.SetSelf(s)
+	return s
+}
+
+type Reblock struct {
+	*muse.BaseModule
+	module    muse.Module
+	blockSize int
+	sources   []*source
+}
+
+// New creates a reblock module for an inner module (often a patch) created with a configuration
+// that has a smaller buffer size, the buffer size of the current configuration must be a
+// multiple of the inner buffer size
+func New(module muse.Module) (*Reblock, error) {
+	blockSize := module.Configuration().BufferSize
+	bufferSize := muse.CurrentConfiguration().BufferSize
+
+	if blockSize <= 0 || bufferSize%blockSize != 0 {
+		return nil, fmt.Errorf("buffer size %d is not a multiple of inner block size %d", bufferSize, blockSize)
+	}
+
+	rb := &Reblock{
+		BaseModule: muse.NewBaseModule(module.NumInputs(), module.NumOutputs()),
+		module:     module,
+		blockSize:  blockSize,
+		sources:    make([]*source, module.NumInputs()),
+	}
+
+	rb.SetSelf(rb)
+
+	// Sources need the inner configuration so their buffers match the inner block size
+	muse.PushConfiguration(module.Configuration())
+
+	for i := range rb.sources {
+		rb.sources[i] = newSource()
+		err := rb.sources[i].TryConnect(0, module, i)
+		if err != nil {
+			muse.PopConfiguration()
+			return nil, err
+		}
+	}
+
+	muse.PopConfiguration()
+
+	return rb, nil
+}
+
+// Parameters returns the parameters of the reblocked module
+func (rb *Reblock) Parameters() []muse.Parameter {
+	return muse.ParametersOf(rb.module)
+}
+
+func (rb *Reblock) ReceiveControlValue(value any, index int) {
+	rb.module.ReceiveControlValue(value, index)
+}
+
+func (rb *Reblock) ReceiveMessage(msg any) []*muse.Message {
+	return rb.module.ReceiveMessage(msg)
+}
+
+func (rb *Reblock) Synthesize() bool {
+	if !rb.BaseModule.Synthesize() {
+		return false
+	}
+
+	for offset := 0; offset < rb.Config.BufferSize; offset += rb.blockSize {
+		for i, src := range rb.sources {
+			copy(src.Outputs[0].Buffer, rb.Inputs[i].Buffer[offset:offset+rb.blockSize])
+		}
+
+		rb.module.PrepareSynthesis()
+		rb.module.Synthesize()
+
+		for i, output := range rb.Outputs {
+			copy(output.Buffer[offset:offset+rb.blockSize], rb.module.OutputAtIndex(i).Buffer)
+		}
+	}
+
+	return true
+}
@@ -48,31 +48,42 @@ func (vt *VarTri) SetFrequency(fc float64) {
 	vt.fc = fc
 }
 
+// Parameters describes the parameters of the variable triangle oscillator
+func (vt *VarTri) Parameters() []muse.Parameter {
+	return []muse.Parameter{
+		{Name: "frequency", ControlIndex: 0, MessageKey: "frequency", Type: muse.ParameterFloat, Min: 0.01, Max: 20000, Default: 440.0, Unit: "Hz", Scaling: muse.ScalingExponential, Value: vt.Frequency()},
+		{Name: "phase", ControlIndex: 1, MessageKey: "phase", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.0, Value: vt.Phase()},
+		{Name: "dutyWidth", ControlIndex: 2, MessageKey: "dutyWidth", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.5, Value: vt.DutyWidth()},
+	}
+}
+
 func (vt *VarTri) ReceiveControlValue(value any, index int) {
 	switch index {
 	case 0: // Frequency
-		vt.SetFrequency(value.(float64))
+		if v, ok := muse.ControlFloat(vt, index, value); ok {
+			vt.SetFrequency(v)
+		}
 	case 1: // Phase
-		vt.SetPhase(value.(float64))
+		if v, ok := muse.ControlFloat(vt, index, value); ok {
+			vt.SetPhase(v)
+		}
 	case 2: // Duty Width
-		vt.SetDutyWidth(value.(float64))
+		if v, ok := muse.ControlFloat(vt, index, value); ok {
+			vt.SetDutyWidth(v)
+		}
 	}
 }
 
 func (vt *VarTri) ReceiveMessage(msg any) []*muse.Message {
-	params, ok := msg.(map[string]any)
-	if ok {
-		p, ok := params["phase"]
-		if ok {
-			vt.SetPhase(p.(float64))
+	if content, ok := muse.DecodeMessage(vt, msg); ok {
+		if p, ok := content.Float("phase"); ok {
+			vt.SetPhase(p)
 		}
-		fc, ok := params["frequency"]
-		if ok {
-			vt.SetFrequency(fc.(float64))
+		if fc, ok := content.Float("frequency"); ok {
+			vt.SetFrequency(fc)
 		}
-		w, ok := params["dutyWidth"]
-		if ok {
-			vt.SetDutyWidth(w.(float64))
+		if w, ok := content.Float("dutyWidth"); ok {
+			vt.SetDutyWidth(w)
 		}
 	}
 
@@ -9,11 +9,14 @@ type ParamMapFunction func(int, any, shape.Shaper)
 
 type MessageMapFunction func(any, shape.Shaper)
 
+type ParameterFunction func(shape.Shaper) []muse.Parameter
+
 type WaveShaper struct {
 	*muse.BaseModule
 	shaper      shape.Shaper
 	paramMapper ParamMapFunction
 	msgMapper   MessageMapFunction
+	paramFunc   ParameterFunction
 }
 
 func New(shaper shape.Shaper, numParams int, paramMapper ParamMapFunction, msgMapper MessageMapFunction) *WaveShaper {
@@ -29,6 +32,19 @@ func New(shaper shape.Shaper, numParams int, paramMapper ParamMapFunction, msgMa
 	return w
 }
 
+// SetParameterFunction sets the function describing the parameters handled by the param and message mappers
+func (s *WaveShaper) SetParameterFunction(paramFunc ParameterFunction) {
+	s.paramFunc = paramFunc
+}
+
+func (s *WaveShaper) Parameters() []muse.Parameter {
+	if s.paramFunc != nil {
+		return s.paramFunc(s.shaper)
+	}
+
+	return nil
+}
+
 func (s *WaveShaper) ReceiveControlValue(value any, index int) {
 	s.paramMapper(index, value, s.shaper)
 }
@@ -23,35 +23,53 @@ func New(sf *io.WaveTableSoundFile, fc float64, phase float64, tableIndex float6
 	return sc
 }
 
+// Parameters describes the parameters of the wavetable scanner
+func (sc *Scanner) Parameters() []muse.Parameter {
+	return []muse.Parameter{
+		{Name: "frequency", ControlIndex: 0, MessageKey: "frequency", Type: muse.ParameterFloat, Min: 0.01, Max: 20000, Default: 440.0, Unit: "Hz", Scaling: muse.ScalingExponential, Value: sc.Frequency()},
+		{Name: "phase", ControlIndex: 1, MessageKey: "phase", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.0, Value: sc.Phase()},
+		{Name: "scanIndex", ControlIndex: 2, MessageKey: "scanIndex", Type: muse.ParameterFloat, Min: 0, Max: 0.999, Default: 0.0, Value: sc.ScanIndex()},
+		{Name: "amplitude", ControlIndex: 3, MessageKey: "amplitude", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 1.0, Value: sc.Amplitude()},
+	}
+}
+
 func (sc *Scanner) ReceiveControlValue(value any, index int) {
 	switch index {
 	case 0: // Frequency
-		sc.SetFrequency(value.(float64))
+		if v, ok := muse.ControlFloat(sc, index, value); ok {
+			sc.SetFrequency(v)
+		}
 	case 1: // Phase
-		sc.SetPhase(value.(float64))
+		if v, ok := muse.ControlFloat(sc, index, value); ok {
+			sc.SetPhase(v)
+		}
 	case 2: // Table Index
-		sc.SetScanIndex(value.(float64))
+		if v, ok := muse.ControlFloat(sc, index, value); ok {
+			sc.SetScanIndex(v)
+		}
 	case 3: // Amplitude
-		sc.SetAmplitude(value.(float64))
+		if v, ok := muse.ControlFloat(sc, index, value); ok {
+			sc.SetAmplitude(v)
+		}
 	}
 }
 
 func (sc *Scanner) ReceiveMessage(msg any) []*muse.Message {
-	if params, ok := msg.(map[string]any); ok {
-		if f, ok := params["frequency"]; ok {
-			sc.SetFrequency(f.(float64))
+	if content, ok := muse.DecodeMessage(sc, msg); ok {
+		if f, ok := content.Float("frequency"); ok {
+			sc.SetFrequency(f)
 		}
 
-		if ph, ok := params["phase"]; ok {
-			sc.SetPhase(ph.(float64))
+		if ph, ok := content.Float("phase"); ok {
+			sc.SetPhase(ph)
 		}
 
-		if ti, ok := params["scanIndex"]; ok {
-			sc.SetScanIndex(ti.(float64))
+		if ti, ok := content.Float("scanIndex"); ok {
+			sc.SetScanIndex(ti)
 		}
 
-		if amp, ok := params["amplitude"]; ok {
-			sc.SetAmplitude(amp.(float64))
+		if amp, ok := content.Float("amplitude"); ok {
+			sc.SetAmplitude(amp)
 		}
 	}
 
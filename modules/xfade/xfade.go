@@ -1,36 +1,65 @@
 package xfade
 
-import "github.com/almerlucke/muse"
+import (
+	"github.com/almerlucke/muse"
+	"github.com/almerlucke/muse/components/smooth"
+)
+
+var modulationInputs = []muse.ModulationInput{
+	{Name: "fade", Index: 2},
+}
 
 type XFade struct {
 	*muse.BaseModule
-	fade float64
+	fade *smooth.Param
 }
 
 func New(fade float64) *XFade {
 	xf := &XFade{
 		BaseModule: muse.NewBaseModule(3, 1),
-		fade:       fade,
 	}
 
+	xf.fade = smooth.New(fade, smooth.DefaultTime, smooth.Linear, xf.Config.SampleRate)
+
 	xf.SetSelf(xf)
 
 	return xf
 }
 
+func (x *XFade) Fade() float64 {
+	return x.fade.Target()
+}
+
+func (x *XFade) SetFade(fade float64) {
+	x.fade.Jump(fade)
+}
+
+func (x *XFade) SetSmoothing(time float64, mode smooth.Mode) {
+	x.fade.SetTime(time)
+	x.fade.SetMode(mode)
+}
+
+// ModulationInputs returns the audio inputs that modulate parameters per sample
+func (x *XFade) ModulationInputs() []muse.ModulationInput {
+	return modulationInputs
+}
+
+// Parameters describes the parameters of the cross fader
+func (x *XFade) Parameters() []muse.Parameter {
+	return []muse.Parameter{
+		{Name: "fade", ControlIndex: 0, MessageKey: "fade", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: 0.5, Value: x.Fade()},
+	}
+}
+
 func (x *XFade) ReceiveControlValue(value any, index int) {
 	if index == 0 {
-		if fade, ok := value.(float64); ok {
-			x.fade = fade
-		}
+		muse.ReceiveControl(x, index, value, x.fade.Receive)
 	}
 }
 
 func (x *XFade) ReceiveMessage(msg any) []*muse.Message {
-	content := msg.(map[string]any)
-
-	if fade, ok := content["fade"]; ok {
-		x.fade = fade.(float64)
+	if content, ok := muse.DecodeMessage(x, msg); ok {
+		content.Receive("fade", x.fade.Receive)
 	}
 
 	return nil
@@ -45,12 +74,8 @@ func (x *XFade) Synthesize() bool {
 	in2 := x.Inputs[1].Buffer
 	out := x.Outputs[0].Buffer
 
-	fade := x.fade
-
 	for i := 0; i < x.Config.BufferSize; i++ {
-		if x.Inputs[2].IsConnected() {
-			fade = x.Inputs[2].Buffer[i]
-		}
+		fade := x.Inputs[2].Value(i, x.fade.Next())
 
 		out[i] = in1[i] + (in2[i]-in1[i])*fade
 	}
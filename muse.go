@@ -2,16 +2,16 @@ package muse
 
 import (
 	"bufio"
+	"errors"
 	"github.com/almerlucke/muse/midi/clock"
 	"github.com/almerlucke/sndfile/writer"
 	"gitlab.com/gomidi/midi/v2"
 	"log"
-	"math"
 	"os"
+	"sync"
 
-	"github.com/almerlucke/muse/buffer"
-	"github.com/dh1tw/gosamplerate"
-	"github.com/gordonklaus/portaudio"
+	"github.com/almerlucke/muse/utils/queue"
+	"github.com/almerlucke/muse/utils/workers"
 )
 
 var DefaultSamplerate = 44100.0
@@ -23,11 +23,15 @@ func init() {
 
 type Muse struct {
 	*BasePatch
-	stream           *portaudio.Stream
-	outputFile       *writer.Writer
-	isRecording      bool
-	recordingBuffers []buffer.Buffer
-	midiClock        *clock.Clock
+	backend        AudioBackend
+	inputDevice    string
+	outputDevice   string
+	recorder       *Recorder
+	midiClock      *clock.Clock
+	clockTransport bool
+	commands       *queue.Ring[Command]
+	postLock       sync.Mutex
+	workerPool     *workers.Pool
 }
 
 func New(numOutputs int) *Muse {
@@ -37,124 +41,142 @@ func New(numOutputs int) *Muse {
 func NewWithInputs(numInputs, numOutputs int) *Muse {
 	e := &Muse{
 		BasePatch: NewPatch(numInputs, numOutputs),
+		commands:  queue.NewRing[Command](DefaultCommandQueueSize),
 	}
 
+	// Every muse owns and advances its own transport
+	e.SetTransport(NewTransport(120))
+
+	e.recorder = newRecorder(e)
+
 	e.SetSelf(e)
 
 	return e
 }
 
+// AddMidiClock adds a MIDI clock that is advanced by the audio stream, use MidiClock to add listeners
 func (m *Muse) AddMidiClock(bpm int, send func(msg midi.Message) error) {
-	m.midiClock = clock.New(bpm, send)
+	m.midiClock = clock.New(float64(bpm), m.Config.SampleRate, send)
 }
 
+func (m *Muse) MidiClock() *clock.Clock {
+	return m.midiClock
+}
+
+// MidiStart starts the MIDI clock at the start of the next block, StartAudio starts the clock so this is only
+// needed to restart a stopped clock while the audio is running
 func (m *Muse) MidiStart() error {
-	if m.midiClock != nil {
-		return m.midiClock.Start()
+	if m.midiClock == nil {
+		return nil
 	}
 
-	return nil
+	return m.Post(func(m *Muse) error {
+		return m.midiClock.Start()
+	})
 }
 
+// MidiStop stops the MIDI clock at the start of the next block while the audio is running, StopAudio stops
+// the clock so this is not needed to stop the audio
 func (m *Muse) MidiStop() error {
-	if m.midiClock != nil {
-		return m.midiClock.Stop()
+	if m.midiClock == nil {
+		return nil
 	}
 
-	return nil
+	return m.Post(func(m *Muse) error {
+		return m.midiClock.Stop()
+	})
 }
 
-func (m *Muse) Synthesize() bool {
-	m.PrepareSynthesis()
-
-	return m.BasePatch.Synthesize()
+// SyncMidiClockToTransport lets the MIDI clock follow the tempo and play state of the transport
+func (m *Muse) SyncMidiClockToTransport(sync bool) {
+	m.clockTransport = sync
 }
 
-func (m *Muse) StartRecording(filePath string, fileFormat writer.FileFormat, sampleRate float64, normalize bool) error {
-	inputSampleRate := m.Config.SampleRate
-	numChannels := m.NumOutputs()
-
-	wr, err := writer.NewWithOptions(filePath, fileFormat, numChannels, sampleRate, writer.Options{
-		InputConverter:    buffer.NewWriterConverter(m.Config.BufferSize, numChannels),
-		Normalize:         normalize,
-		ConvertSampleRate: inputSampleRate != sampleRate,
-		InputSampleRate:   inputSampleRate,
-		SrConvQuality:     gosamplerate.SRC_SINC_BEST_QUALITY,
-	})
+// advanceTime advances the transport and the MIDI clock at the start of a block
+func (m *Muse) advanceTime() {
+	if m.transport != nil {
+		m.transport.Advance(m.Config.BufferSize, m.Config.SampleRate)
+	}
 
-	if err != nil {
-		return err
+	if m.midiClock == nil {
+		return
 	}
 
-	m.outputFile = wr
-	m.isRecording = true
-	m.recordingBuffers = make([]buffer.Buffer, numChannels)
+	if t := m.transport; m.clockTransport && t != nil {
+		if t.Playing() && !m.midiClock.Running() {
+			_ = m.midiClock.SetPosition(int(t.Beat() * 4))
+			_ = m.midiClock.Continue()
+		} else if !t.Playing() && m.midiClock.Running() {
+			_ = m.midiClock.Stop()
+		}
 
-	for c := 0; c < numChannels; c++ {
-		m.recordingBuffers[c] = m.OutputAtIndex(c).Buffer
+		m.midiClock.SetBPM(t.BPM())
 	}
 
-	return nil
+	m.midiClock.Advance(m.Config.BufferSize)
 }
 
-func (m *Muse) RenderToSoundFile(filePath string, fileFormat writer.FileFormat, numSeconds float64, sampleRate float64, normalize bool) error {
-	inputSampleRate := m.Config.SampleRate
-	numChannels := m.NumOutputs()
-	buffers := make([]buffer.Buffer, numChannels)
+func (m *Muse) Synthesize() bool {
+	m.ProcessCommands()
+	m.advanceTime()
+	m.PrepareSynthesis()
+
+	return m.BasePatch.Synthesize()
+}
 
-	for c := 0; c < numChannels; c++ {
-		buffers[c] = m.OutputAtIndex(c).Buffer
+// SetWorkers renders independent modules of the muse in parallel on numWorkers goroutines besides the
+// audio thread, a negative number uses one worker less than the number of CPUs and 0 renders on the
+// audio thread only. The output is identical to rendering without workers. Set the workers before the
+// audio is started, the previous workers are stopped
+func (m *Muse) SetWorkers(numWorkers int) {
+	if m.workerPool != nil {
+		m.workerPool.Close()
+		m.workerPool = nil
 	}
 
-	wr, err := writer.NewWithOptions(filePath, fileFormat, numChannels, sampleRate, writer.Options{
-		InputConverter:    buffer.NewWriterConverter(m.Config.BufferSize, numChannels),
-		Normalize:         normalize,
-		ConvertSampleRate: inputSampleRate != sampleRate,
-		InputSampleRate:   inputSampleRate,
-		SrConvQuality:     gosamplerate.SRC_SINC_BEST_QUALITY,
-	})
-	if err != nil {
-		return err
+	if numWorkers != 0 {
+		m.workerPool = workers.New(max(numWorkers, 0))
 	}
 
-	defer func() {
-		_ = wr.Close()
-	}()
+	m.SetWorkerPool(m.workerPool)
+}
 
-	numCycles := int64(math.Ceil((m.Config.SampleRate * numSeconds) / float64(m.Config.BufferSize)))
+// Recorder returns the recorder of the muse, use it to record takes or module outputs
+func (m *Muse) Recorder() *Recorder {
+	return m.recorder
+}
 
-	if numCycles > 0 {
-		for numCycles > 1 {
-			m.Synthesize()
-			err = wr.Write(buffers, false)
-			if err != nil {
-				return err
-			}
-			numCycles--
-		}
-		m.Synthesize()
-		err = wr.Write(buffers, true)
-		if err != nil {
-			return err
-		}
-	}
+// StartRecording records the outputs of the muse to filePath in the background
+func (m *Muse) StartRecording(filePath string, fileFormat writer.FileFormat, sampleRate float64, normalize bool) error {
+	return m.recorder.Start(filePath, RecordingOptions{
+		FileFormat: fileFormat,
+		SampleRate: sampleRate,
+		Normalize:  normalize,
+	})
+}
 
-	return nil
+// StopRecording stops recording and waits until the file is written
+func (m *Muse) StopRecording() error {
+	return m.recorder.Stop()
 }
 
-func (m *Muse) audioCallback(in, out [][]float32) {
-	// Prepare synthesis
-	m.PrepareSynthesis()
+func (m *Muse) PauseRecording() {
+	m.recorder.Pause()
+}
+
+func (m *Muse) ResumeRecording() {
+	m.recorder.Resume()
+}
 
-	// Force did synthesize on input thru modules for muse patch,
-	// in this way we skip the normal synthesize call
+func (m *Muse) audioCallback(in, out [][]float32) {
 	numInputs := m.NumInputs()
 
-	for i := 0; i < numInputs; i++ {
-		m.InputModuleAtIndex(i).SetDidSynthesize(true)
-	}
+	// Apply changes posted from other goroutines
+	m.ProcessCommands()
+	m.advanceTime()
 
-	// Copy system audio input to thru modules output
+	// Copy system audio input to thru modules output, the input thru modules
+	// are not synthesized for the muse patch
 	for i := 0; i < m.Config.BufferSize; i++ {
 		for j := 0; j < numInputs; j++ {
 			m.InputModuleAtIndex(j).OutputAtIndex(0).Buffer[i] = float64(in[j][i])
@@ -162,12 +184,10 @@ func (m *Muse) audioCallback(in, out [][]float32) {
 	}
 
 	// Synthesize rest of the patch like normal
-	m.BasePatch.Synthesize()
+	m.synthesizeSchedule()
 
-	// Record to file
-	if m.isRecording {
-		_ = m.outputFile.Write(m.recordingBuffers, false)
-	}
+	// Hand the block to the recorder, the file is written by another goroutine
+	m.recorder.record()
 
 	// Copy outputs to system audio output
 	numOutputs := m.NumOutputs()
@@ -179,32 +199,41 @@ func (m *Muse) audioCallback(in, out [][]float32) {
 	}
 }
 
-func (m *Muse) InitializeAudio() error {
-	err := portaudio.Initialize()
-	if err != nil {
-		return err
-	}
+// SetAudioBackend sets the backend that runs the audio, it must be set before InitializeAudio. Without backend
+// the muse plays through PortAudio
+func (m *Muse) SetAudioBackend(backend AudioBackend) {
+	m.backend = backend
+}
 
-	stream, err := portaudio.OpenDefaultStream(
-		m.NumInputs(),
-		m.NumOutputs(),
-		m.Config.SampleRate,
-		m.Config.BufferSize,
-		m.audioCallback,
-	)
+// AudioBackend returns the audio backend, it is nil until set or until the audio is initialized
+func (m *Muse) AudioBackend() AudioBackend {
+	return m.backend
+}
 
-	if err != nil {
-		_ = portaudio.Terminate()
-		return err
-	}
+// SetAudioDevices selects the input and output device by name, an empty name selects the default device. Use
+// FindAudioDevice to select a device by channel count and sample rate
+func (m *Muse) SetAudioDevices(input string, output string) {
+	m.inputDevice = input
+	m.outputDevice = output
+}
 
-	m.stream = stream
+func (m *Muse) InitializeAudio() error {
+	if m.backend == nil {
+		m.backend = NewPortAudioBackend()
+	}
 
-	return nil
+	return m.backend.Open(AudioStreamParameters{
+		InputDevice:  m.inputDevice,
+		OutputDevice: m.outputDevice,
+		NumInputs:    m.NumInputs(),
+		NumOutputs:   m.NumOutputs(),
+		SampleRate:   m.Config.SampleRate,
+		BufferSize:   m.Config.BufferSize,
+	}, m.audioCallback)
 }
 
 func (m *Muse) StartAudio() error {
-	err := m.stream.Start()
+	err := m.backend.Start()
 	if err != nil {
 		return err
 	}
@@ -213,26 +242,26 @@ func (m *Muse) StartAudio() error {
 }
 
 func (m *Muse) StopAudio() error {
-	err := m.stream.Stop()
-	if err != nil {
-		_ = m.MidiStop()
-		return err
+	err := m.backend.Stop()
+
+	// The audio thread is stopped and posted commands are no longer processed, stop the clock directly
+	if m.midiClock != nil {
+		err = errors.Join(err, m.midiClock.Stop())
 	}
 
-	return m.MidiStop()
+	return err
 }
 
 func (m *Muse) TerminateAudio() {
-	if m.isRecording {
-		_ = m.outputFile.Close()
-	}
+	_ = m.recorder.Stop()
 
-	if m.stream != nil {
-		_ = m.stream.Close()
-		m.stream = nil
+	if m.backend != nil {
+		_ = m.backend.Close()
 	}
 
-	_ = portaudio.Terminate()
+	if m.midiClock != nil {
+		m.midiClock.Close()
+	}
 }
 
 func (m *Muse) RenderAudio() error {
@@ -248,6 +277,13 @@ func (m *Muse) RenderAudio() error {
 		return err
 	}
 
+	// A stream that ends by itself is rendered to the end
+	if finite, ok := m.backend.(FiniteAudioBackend); ok && finite.Done() != nil {
+		<-finite.Done()
+
+		return errors.Join(finite.Err(), m.StopAudio())
+	}
+
 	log.Printf("Press enter to quit...")
 
 	reader := bufio.NewReader(os.Stdin)
@@ -276,7 +312,11 @@ func (m *Muse) PlotModule(module Module, outIndex int, frames int, w float64, h
 
 	m.AddModule(pm)
 
-	module.Connect(outIndex, pm, 0)
+	err := module.TryConnect(outIndex, pm, 0)
+	if err != nil {
+		m.RemoveModule(pm)
+		return err
+	}
 
 	for i := 0; i < frames; i++ {
 		m.Synthesize()
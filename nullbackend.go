@@ -0,0 +1,98 @@
+package muse
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// NullAudioBackend drives the audio callback from a timer at the rate of the stream without a sound card,
+// the input is silent and the output is discarded. Use it to run a patch in CI or on a headless machine
+type NullAudioBackend struct {
+	params   AudioStreamParameters
+	callback AudioCallback
+	in       [][]float32
+	out      [][]float32
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+func NewNullAudioBackend() *NullAudioBackend {
+	return &NullAudioBackend{}
+}
+
+// Devices returns a single null device that accepts any channel count and sample rate
+func (nb *NullAudioBackend) Devices() ([]AudioDevice, error) {
+	return []AudioDevice{{
+		Name:              "null",
+		MaxInputChannels:  256,
+		MaxOutputChannels: 256,
+		DefaultSampleRate: DefaultSamplerate,
+		SampleRates:       CommonSampleRates,
+	}}, nil
+}
+
+func (nb *NullAudioBackend) Open(params AudioStreamParameters, callback AudioCallback) error {
+	if params.SampleRate <= 0 || params.BufferSize <= 0 {
+		return errors.New("null audio backend needs a sample rate and buffer size")
+	}
+
+	nb.params = params
+	nb.callback = callback
+	nb.in = allocateAudioBuffers(params.NumInputs, params.BufferSize)
+	nb.out = allocateAudioBuffers(params.NumOutputs, params.BufferSize)
+
+	return nil
+}
+
+func (nb *NullAudioBackend) Start() error {
+	if nb.callback == nil {
+		return errors.New("null audio stream is not open")
+	}
+
+	if nb.stop != nil {
+		return nil
+	}
+
+	interval := time.Duration(float64(nb.params.BufferSize) / nb.params.SampleRate * float64(time.Second))
+
+	nb.stop = make(chan struct{})
+	nb.wg.Add(1)
+
+	go nb.run(interval, nb.stop)
+
+	return nil
+}
+
+func (nb *NullAudioBackend) run(interval time.Duration, stop chan struct{}) {
+	defer nb.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			nb.callback(nb.in, nb.out)
+		}
+	}
+}
+
+func (nb *NullAudioBackend) Stop() error {
+	if nb.stop != nil {
+		close(nb.stop)
+		nb.wg.Wait()
+		nb.stop = nil
+	}
+
+	return nil
+}
+
+func (nb *NullAudioBackend) Close() error {
+	err := nb.Stop()
+	nb.callback = nil
+
+	return err
+}
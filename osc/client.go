@@ -0,0 +1,139 @@
+package osc
+
+import (
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/almerlucke/muse"
+)
+
+// DefaultClientQueueSize is the number of packets a client can queue before packets are dropped
+var DefaultClientQueueSize = 256
+
+// Client is a messenger that sends the messages it receives to an OSC server over UDP, see FromMuse for
+// the mapping. Set a client as replyTo address of queries to monitor parameter values. Packets are sent
+// from a separate goroutine so receiving messages never blocks the audio thread
+type Client struct {
+	*muse.BaseMessenger
+	conn    *net.UDPConn
+	prefix  string
+	packets chan []byte
+	done    chan struct{}
+	wg      sync.WaitGroup
+	dropped atomic.Int64
+}
+
+// NewClient returns a client sending to the UDP address, prefix is prepended to all OSC addresses
+func NewClient(address string, prefix string) (*Client, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialUDP("udp", nil, udpAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		BaseMessenger: muse.NewBaseMessenger(),
+		conn:          conn,
+		prefix:        prefix,
+		packets:       make(chan []byte, DefaultClientQueueSize),
+		done:          make(chan struct{}),
+	}
+
+	c.SetSelf(c)
+	c.wg.Add(1)
+
+	go c.run()
+
+	return c, nil
+}
+
+// Dropped returns the number of packets dropped because the queue was full
+func (c *Client) Dropped() int64 {
+	return c.dropped.Load()
+}
+
+// Send sends msgs immediately as one packet, msgs are sent as bundle if there is more than one
+func (c *Client) Send(msgs ...*Message) error {
+	packet, err := encode(msgs)
+	if err != nil || packet == nil {
+		return err
+	}
+
+	_, err = c.conn.Write(packet)
+
+	return err
+}
+
+func (c *Client) ReceiveMessage(msg any) []*muse.Message {
+	msgs, err := FromMuse(c.prefix, msg)
+	if err != nil {
+		muse.ReportMessageError(&muse.MessageError{Receiver: c.describe(), Index: -1, Value: msg, Reason: err.Error()})
+		return nil
+	}
+
+	packet, err := encode(msgs)
+	if err != nil {
+		muse.ReportMessageError(&muse.MessageError{Receiver: c.describe(), Index: -1, Value: msg, Reason: err.Error()})
+		return nil
+	}
+
+	if packet == nil {
+		return nil
+	}
+
+	select {
+	case c.packets <- packet:
+	default:
+		c.dropped.Add(1)
+	}
+
+	return nil
+}
+
+// Close stops sending, queued packets are discarded
+func (c *Client) Close() error {
+	close(c.done)
+	c.wg.Wait()
+
+	return c.conn.Close()
+}
+
+func (c *Client) describe() string {
+	if c.Identifier() != "" {
+		return c.Identifier()
+	}
+
+	return "osc client"
+}
+
+func (c *Client) run() {
+	defer c.wg.Done()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case packet := <-c.packets:
+			if _, err := c.conn.Write(packet); err != nil {
+				log.Printf("osc: %v", err)
+			}
+		}
+	}
+}
+
+func encode(msgs []*Message) ([]byte, error) {
+	switch len(msgs) {
+	case 0:
+		return nil, nil
+	case 1:
+		return msgs[0].Encode()
+	}
+
+	return EncodeBundle(msgs)
+}
@@ -0,0 +1,163 @@
+package osc
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/almerlucke/muse"
+)
+
+// OSC addresses map onto muse messages by using the last component as message key and the components
+// before it as dotted receiver address:
+//
+//	/synth/filter/cutoff 800.0   ->  synth.filter {"cutoff": 800.0}
+//	/synth/filter/mode "lp" 2    ->  synth.filter {"mode": ["lp", 2]}
+//	/synth/voice/bang            ->  synth.voice  {"bang": true}
+//	/synth                       ->  synth        Bang
+//
+// A message without arguments or with a single impulse sets the key to true. Components can use the wildcards
+// of muse addresses, /*/filter/cutoff reaches the filters of all sub patches. With a reply address the keys
+// "get" and "dump" become queries, their replies are sent to the reply address:
+//
+//	/synth/filter/get "cutoff"   ->  synth.filter {"command": "get", "parameter": "cutoff", "replyTo": replyTo}
+//	/synth/dump                  ->  synth        {"command": "dump", "replyTo": replyTo}
+
+// ToMuse converts an OSC message to a muse message, replyTo is the address query replies are sent to,
+// queries are not converted if replyTo is empty
+func ToMuse(msg *Message, replyTo string) (*muse.Message, error) {
+	components := strings.Split(strings.Trim(msg.Address, "/"), "/")
+	if slices.Contains(components, "") {
+		return nil, fmt.Errorf("osc address %q has an empty component", msg.Address)
+	}
+
+	args := make([]any, len(msg.Arguments))
+	for i, arg := range msg.Arguments {
+		args[i] = toMuseValue(arg)
+	}
+
+	if len(components) == 1 {
+		if len(args) > 0 && !isTrigger(args) {
+			return nil, fmt.Errorf("osc address %q needs a message key for its arguments", msg.Address)
+		}
+
+		return muse.NewMessage(components[0], muse.Bang), nil
+	}
+
+	address := strings.Join(components[:len(components)-1], ".")
+	key := components[len(components)-1]
+
+	if replyTo != "" && (key == muse.CommandGet || key == muse.CommandDump) {
+		return muse.NewMessage(address, query(key, args, replyTo)), nil
+	}
+
+	var value any
+
+	switch {
+	case isTrigger(args):
+		value = true
+	case len(args) == 1:
+		value = args[0]
+	default:
+		value = args
+	}
+
+	return muse.NewMessage(address, map[string]any{key: value}), nil
+}
+
+func query(command string, args []any, replyTo string) map[string]any {
+	content := map[string]any{"command": command, "replyTo": replyTo}
+
+	if command == muse.CommandGet && !isTrigger(args) {
+		if len(args) == 1 {
+			content["parameter"] = args[0]
+		} else {
+			content["parameters"] = args
+		}
+	}
+
+	return content
+}
+
+func isTrigger(args []any) bool {
+	return len(args) == 0 || (len(args) == 1 && args[0] == true)
+}
+
+// toMuseValue converts OSC arguments to the types muse receivers expect
+func toMuseValue(arg any) any {
+	switch v := arg.(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case float32:
+		return float64(v)
+	case Impulse:
+		return true
+	}
+
+	return arg
+}
+
+// FromMuse converts the content of a muse message to OSC messages with prefix as address, a bang becomes an
+// impulse at prefix. Each key of map content
+// becomes a message at prefix/key in key order, lists become multiple arguments. Replies to queries are converted
+// to a message for each value at the address of the replying receiver, so the reply to a get for the cutoff of
+// synth.filter becomes /synth/filter/cutoff 800.0
+func FromMuse(prefix string, content any) ([]*Message, error) {
+	prefix = strings.TrimSuffix(prefix, "/")
+
+	values, ok := content.(map[string]any)
+	if !ok {
+		if content == muse.Bang {
+			return []*Message{NewMessage(prefix, Impulse{})}, nil
+		}
+
+		return nil, fmt.Errorf("can not convert %T to osc", content)
+	}
+
+	if values["command"] == muse.CommandReply {
+		if address, ok := values["address"].(string); ok && address != "" {
+			prefix += "/" + strings.ReplaceAll(address, ".", "/")
+		}
+
+		values, ok = values["values"].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("reply without values")
+		}
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	msgs := make([]*Message, 0, len(keys))
+
+	for _, key := range keys {
+		msg := NewMessage(prefix + "/" + strings.ReplaceAll(key, ".", "/"))
+
+		switch v := values[key].(type) {
+		case []any:
+			msg.Arguments = slices.Clone(v)
+		case []float64:
+			for _, f := range v {
+				msg.Arguments = append(msg.Arguments, f)
+			}
+		default:
+			msg.Arguments = []any{v}
+		}
+
+		for i, arg := range msg.Arguments {
+			if arg == muse.Bang {
+				msg.Arguments[i] = Impulse{}
+			}
+		}
+
+		msgs = append(msgs, msg)
+	}
+
+	return msgs, nil
+}
@@ -0,0 +1,347 @@
+package osc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ErrInvalidPacket is wrapped by all errors for packets that can not be decoded
+var ErrInvalidPacket = errors.New("invalid osc packet")
+
+const bundleTag = "#bundle"
+
+// Impulse is the value of an OSC impulse argument ("I"), used for triggers without a value
+type Impulse struct{}
+
+// Message is an OSC message, arguments are int32, int64, float32, float64, string, []byte, bool, nil or Impulse.
+// Go ints and float64 values are encoded as int32 and float32 because most controllers do not support the
+// 64-bit types
+type Message struct {
+	Address   string
+	Arguments []any
+}
+
+func NewMessage(address string, arguments ...any) *Message {
+	return &Message{
+		Address:   address,
+		Arguments: arguments,
+	}
+}
+
+func (m *Message) String() string {
+	return fmt.Sprintf("%s %v", m.Address, m.Arguments)
+}
+
+// Encode returns the binary packet for m
+func (m *Message) Encode() ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := m.encode(&buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (m *Message) encode(buf *bytes.Buffer) error {
+	if !strings.HasPrefix(m.Address, "/") {
+		return fmt.Errorf("osc address %q must start with /", m.Address)
+	}
+
+	tags := []byte{','}
+	var args bytes.Buffer
+
+	for _, arg := range m.Arguments {
+		switch v := arg.(type) {
+		case int32:
+			tags = append(tags, 'i')
+			_ = binary.Write(&args, binary.BigEndian, v)
+		case int:
+			if v >= math.MinInt32 && v <= math.MaxInt32 {
+				tags = append(tags, 'i')
+				_ = binary.Write(&args, binary.BigEndian, int32(v))
+			} else {
+				tags = append(tags, 'h')
+				_ = binary.Write(&args, binary.BigEndian, int64(v))
+			}
+		case int64:
+			tags = append(tags, 'h')
+			_ = binary.Write(&args, binary.BigEndian, v)
+		case float32:
+			tags = append(tags, 'f')
+			_ = binary.Write(&args, binary.BigEndian, v)
+		case float64:
+			tags = append(tags, 'f')
+			_ = binary.Write(&args, binary.BigEndian, float32(v))
+		case string:
+			tags = append(tags, 's')
+			writeString(&args, v)
+		case []byte:
+			tags = append(tags, 'b')
+			_ = binary.Write(&args, binary.BigEndian, int32(len(v)))
+			args.Write(v)
+			pad(&args, len(v))
+		case bool:
+			if v {
+				tags = append(tags, 'T')
+			} else {
+				tags = append(tags, 'F')
+			}
+		case nil:
+			tags = append(tags, 'N')
+		case Impulse:
+			tags = append(tags, 'I')
+		default:
+			return fmt.Errorf("osc argument of type %T is not supported", arg)
+		}
+	}
+
+	writeString(buf, m.Address)
+	writeString(buf, string(tags))
+	buf.Write(args.Bytes())
+
+	return nil
+}
+
+// EncodeBundle returns a bundle packet with all messages that is executed immediately
+func EncodeBundle(msgs []*Message) ([]byte, error) {
+	var buf bytes.Buffer
+	var elem bytes.Buffer
+
+	writeString(&buf, bundleTag)
+	// Time tag 1 means immediately
+	_ = binary.Write(&buf, binary.BigEndian, uint64(1))
+
+	for _, msg := range msgs {
+		elem.Reset()
+
+		if err := msg.encode(&elem); err != nil {
+			return nil, err
+		}
+
+		_ = binary.Write(&buf, binary.BigEndian, int32(elem.Len()))
+		buf.Write(elem.Bytes())
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decode returns the messages in packet, messages in bundles are returned in order and
+// time tags are ignored
+func Decode(packet []byte) ([]*Message, error) {
+	return decode(packet, nil)
+}
+
+func decode(packet []byte, msgs []*Message) ([]*Message, error) {
+	if len(packet) == 0 || len(packet)%4 != 0 {
+		return msgs, fmt.Errorf("%w: size %d is not a multiple of 4", ErrInvalidPacket, len(packet))
+	}
+
+	if packet[0] == '#' {
+		return decodeBundle(packet, msgs)
+	}
+
+	msg, err := decodeMessage(packet)
+	if err != nil {
+		return msgs, err
+	}
+
+	return append(msgs, msg), nil
+}
+
+func decodeBundle(packet []byte, msgs []*Message) ([]*Message, error) {
+	r := reader{data: packet}
+
+	tag, err := r.string()
+	if err != nil {
+		return msgs, err
+	}
+
+	if tag != bundleTag {
+		return msgs, fmt.Errorf("%w: unknown bundle tag %q", ErrInvalidPacket, tag)
+	}
+
+	// Skip the time tag
+	if _, err := r.next(8); err != nil {
+		return msgs, err
+	}
+
+	for !r.done() {
+		size, err := r.int32()
+		if err != nil {
+			return msgs, err
+		}
+
+		if size < 0 {
+			return msgs, fmt.Errorf("%w: negative bundle element size", ErrInvalidPacket)
+		}
+
+		elem, err := r.next(int(size))
+		if err != nil {
+			return msgs, err
+		}
+
+		if msgs, err = decode(elem, msgs); err != nil {
+			return msgs, err
+		}
+	}
+
+	return msgs, nil
+}
+
+func decodeMessage(packet []byte) (*Message, error) {
+	r := reader{data: packet}
+
+	address, err := r.string()
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(address, "/") {
+		return nil, fmt.Errorf("%w: address %q must start with /", ErrInvalidPacket, address)
+	}
+
+	msg := &Message{Address: address}
+
+	// Very old implementations omit the type tags for messages without arguments
+	if r.done() {
+		return msg, nil
+	}
+
+	tags, err := r.string()
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(tags, ",") {
+		return nil, fmt.Errorf("%w: type tags %q must start with a comma", ErrInvalidPacket, tags)
+	}
+
+	for _, tag := range tags[1:] {
+		var arg any
+
+		switch tag {
+		case 'i':
+			arg, err = r.int32()
+		case 'h':
+			var b []byte
+			if b, err = r.next(8); err == nil {
+				arg = int64(binary.BigEndian.Uint64(b))
+			}
+		case 'f':
+			var b []byte
+			if b, err = r.next(4); err == nil {
+				arg = math.Float32frombits(binary.BigEndian.Uint32(b))
+			}
+		case 'd':
+			var b []byte
+			if b, err = r.next(8); err == nil {
+				arg = math.Float64frombits(binary.BigEndian.Uint64(b))
+			}
+		case 's', 'S':
+			arg, err = r.string()
+		case 'b':
+			arg, err = r.blob()
+		case 'T':
+			arg = true
+		case 'F':
+			arg = false
+		case 'N':
+			arg = nil
+		case 'I':
+			arg = Impulse{}
+		default:
+			return nil, fmt.Errorf("%w: unsupported type tag %q", ErrInvalidPacket, tag)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		msg.Arguments = append(msg.Arguments, arg)
+	}
+
+	return msg, nil
+}
+
+// writeString writes s with a terminating zero padded to a multiple of 4 bytes
+func writeString(buf *bytes.Buffer, s string) {
+	buf.WriteString(s)
+	buf.WriteByte(0)
+	pad(buf, len(s)+1)
+}
+
+func pad(buf *bytes.Buffer, n int) {
+	for ; n%4 != 0; n++ {
+		buf.WriteByte(0)
+	}
+}
+
+type reader struct {
+	data []byte
+	pos  int
+}
+
+func (r *reader) done() bool {
+	return r.pos >= len(r.data)
+}
+
+func (r *reader) next(n int) ([]byte, error) {
+	if n > len(r.data)-r.pos {
+		return nil, fmt.Errorf("%w: unexpected end of packet", ErrInvalidPacket)
+	}
+
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+
+	return b, nil
+}
+
+func (r *reader) int32() (int32, error) {
+	b, err := r.next(4)
+	if err != nil {
+		return 0, err
+	}
+
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+func (r *reader) string() (string, error) {
+	end := bytes.IndexByte(r.data[r.pos:], 0)
+	if end < 0 {
+		return "", fmt.Errorf("%w: unterminated string", ErrInvalidPacket)
+	}
+
+	b, err := r.next((end + 4) &^ 3)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b[:end]), nil
+}
+
+func (r *reader) blob() ([]byte, error) {
+	size, err := r.int32()
+	if err != nil {
+		return nil, err
+	}
+
+	if size < 0 {
+		return nil, fmt.Errorf("%w: negative blob size", ErrInvalidPacket)
+	}
+
+	b, err := r.next(int(size))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := r.next((4 - int(size)%4) % 4); err != nil {
+		return nil, err
+	}
+
+	return bytes.Clone(b), nil
+}
@@ -0,0 +1,184 @@
+package osc
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeMessage(t *testing.T) {
+	packet, err := NewMessage("/a", 1, "hi", []byte{7}).Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{
+		'/', 'a', 0, 0,
+		',', 'i', 's', 'b', 0, 0, 0, 0,
+		0, 0, 0, 1,
+		'h', 'i', 0, 0,
+		0, 0, 0, 1, 7, 0, 0, 0,
+	}
+
+	if !bytes.Equal(packet, expected) {
+		t.Fatalf("encoded %v, expected %v", packet, expected)
+	}
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	msg := NewMessage("/synth/filter",
+		int32(-3), 42, int64(1)<<40, 1<<40, float32(0.25), 1.5,
+		"", "abc", "abcd",
+		[]byte{}, []byte{1}, []byte{1, 2, 3}, []byte{1, 2, 3, 4}, []byte{1, 2, 3, 4, 5},
+		true, false, nil, Impulse{},
+	)
+
+	packet, err := msg.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(packet)%4 != 0 {
+		t.Fatalf("packet size %d is not a multiple of 4", len(packet))
+	}
+
+	msgs, err := Decode(packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*Message{NewMessage("/synth/filter",
+		int32(-3), int32(42), int64(1)<<40, int64(1)<<40, float32(0.25), float32(1.5),
+		"", "abc", "abcd",
+		[]byte{}, []byte{1}, []byte{1, 2, 3}, []byte{1, 2, 3, 4}, []byte{1, 2, 3, 4, 5},
+		true, false, nil, Impulse{},
+	)}
+
+	if !reflect.DeepEqual(msgs, expected) {
+		t.Fatalf("decoded %v, expected %v", msgs, expected)
+	}
+}
+
+func TestStringPadding(t *testing.T) {
+	for n := 0; n < 9; n++ {
+		var buf bytes.Buffer
+
+		writeString(&buf, string(bytes.Repeat([]byte{'x'}, n)))
+
+		if buf.Len()%4 != 0 || buf.Len() < n+1 || buf.Len() > n+4 {
+			t.Fatalf("string of %d bytes is padded to %d bytes", n, buf.Len())
+		}
+
+		if !bytes.Equal(buf.Bytes()[n:], make([]byte, buf.Len()-n)) {
+			t.Fatalf("string of %d bytes is not padded with zeros: %v", n, buf.Bytes())
+		}
+	}
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	msgs := []*Message{
+		NewMessage("/a", int32(1)),
+		NewMessage("/b", "two", []byte{2, 2}),
+		NewMessage("/c"),
+	}
+
+	packet, err := EncodeBundle(msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(packet, []byte("#bundle\x00\x00\x00\x00\x00\x00\x00\x00\x01")) {
+		t.Fatalf("bundle does not start with the bundle tag and the immediate time tag: %v", packet[:16])
+	}
+
+	decoded, err := Decode(packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(decoded, msgs) {
+		t.Fatalf("decoded %v, expected %v", decoded, msgs)
+	}
+}
+
+func TestNestedBundle(t *testing.T) {
+	inner, err := EncodeBundle([]*Message{NewMessage("/b", int32(2)), NewMessage("/c", int32(3))})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := NewMessage("/a", int32(1)).Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	writeString(&buf, bundleTag)
+	buf.Write(make([]byte, 8))
+
+	for _, elem := range [][]byte{first, inner} {
+		buf.Write([]byte{0, 0, 0, byte(len(elem))})
+		buf.Write(elem)
+	}
+
+	msgs, err := Decode(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*Message{NewMessage("/a", int32(1)), NewMessage("/b", int32(2)), NewMessage("/c", int32(3))}
+
+	if !reflect.DeepEqual(msgs, expected) {
+		t.Fatalf("decoded %v, expected %v", msgs, expected)
+	}
+}
+
+func TestDecodeWithoutTypeTags(t *testing.T) {
+	msgs, err := Decode([]byte{'/', 'a', 0, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msgs) != 1 || msgs[0].Address != "/a" || len(msgs[0].Arguments) != 0 {
+		t.Fatalf("decoded %v", msgs)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	valid, err := NewMessage("/a", []byte{1, 2, 3, 4, 5}).Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packets := map[string][]byte{
+		"empty":             {},
+		"unaligned":         {'/', 'a', 0},
+		"no address slash":  {'a', 0, 0, 0},
+		"unterminated":      {'/', 'a', 'b', 'c'},
+		"no tag comma":      {'/', 'a', 0, 0, 'i', 0, 0, 0},
+		"unknown tag":       {'/', 'a', 0, 0, ',', 'x', 0, 0},
+		"missing argument":  {'/', 'a', 0, 0, ',', 'i', 0, 0},
+		"truncated blob":    valid[:len(valid)-4],
+		"negative blob":     {'/', 'a', 0, 0, ',', 'b', 0, 0, 0xff, 0xff, 0xff, 0xff},
+		"unknown bundle":    {'#', 'b', 'u', 'n', 0, 0, 0, 0},
+		"truncated element": append([]byte("#bundle\x00"), 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 8, '/', 'a', 0, 0),
+	}
+
+	for name, packet := range packets {
+		if _, err := Decode(packet); !errors.Is(err, ErrInvalidPacket) {
+			t.Errorf("%s: expected an invalid packet error, got %v", name, err)
+		}
+	}
+}
+
+func TestEncodeInvalid(t *testing.T) {
+	if _, err := NewMessage("a").Encode(); err == nil {
+		t.Error("expected an error for an address without slash")
+	}
+
+	if _, err := NewMessage("/a", struct{}{}).Encode(); err == nil {
+		t.Error("expected an error for an unsupported argument")
+	}
+}
@@ -0,0 +1,124 @@
+package osc
+
+import (
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/almerlucke/muse"
+)
+
+// MaxPacketSize is the largest UDP packet the server can receive
+const MaxPacketSize = 65507
+
+// Poster delivers messages on the audio thread, it is implemented by *muse.Muse
+type Poster interface {
+	PostMessage(msg *muse.Message) error
+}
+
+// Server receives OSC packets over UDP and posts them as muse messages, see ToMuse for the mapping
+type Server struct {
+	conn         *net.UDPConn
+	poster       Poster
+	replyTo      string
+	errorHandler atomic.Pointer[func(error)]
+	wg           sync.WaitGroup
+}
+
+// Listen starts a server on the UDP address, ":0" picks a free port. Messages are posted to poster so they
+// are received on the audio thread, query replies are sent to the muse address replyTo
+func Listen(address string, poster Poster, replyTo string) (*Server, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		conn:    conn,
+		poster:  poster,
+		replyTo: replyTo,
+	}
+
+	s.wg.Add(1)
+
+	go s.serve()
+
+	return s, nil
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() net.Addr {
+	return s.conn.LocalAddr()
+}
+
+// SetErrorHandler sets the function invalid packets and failed posts are reported to, nil restores
+// the default handler that logs the error
+func (s *Server) SetErrorHandler(handler func(error)) {
+	if handler == nil {
+		s.errorHandler.Store(nil)
+		return
+	}
+
+	s.errorHandler.Store(&handler)
+}
+
+func (s *Server) report(err error) {
+	if handler := s.errorHandler.Load(); handler != nil {
+		(*handler)(err)
+		return
+	}
+
+	log.Printf("osc: %v", err)
+}
+
+// Close stops the server and waits until it no longer posts messages
+func (s *Server) Close() error {
+	err := s.conn.Close()
+
+	s.wg.Wait()
+
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	buf := make([]byte, MaxPacketSize)
+
+	for {
+		n, _, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			s.report(err)
+
+			continue
+		}
+
+		msgs, err := Decode(buf[:n])
+		if err != nil {
+			s.report(err)
+		}
+
+		for _, msg := range msgs {
+			museMsg, err := ToMuse(msg, s.replyTo)
+			if err != nil {
+				s.report(err)
+				continue
+			}
+
+			if err := s.poster.PostMessage(museMsg); err != nil {
+				s.report(err)
+			}
+		}
+	}
+}
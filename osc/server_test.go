@@ -0,0 +1,221 @@
+package osc
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/almerlucke/muse"
+	"github.com/almerlucke/muse/modules/filters/moog"
+)
+
+// recorder is a poster that passes the posted messages on to a channel
+type recorder chan *muse.Message
+
+func (r recorder) PostMessage(msg *muse.Message) error {
+	r <- msg
+	return nil
+}
+
+func (r recorder) next(t *testing.T) *muse.Message {
+	t.Helper()
+
+	select {
+	case msg := <-r:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for a message")
+	}
+
+	return nil
+}
+
+// localAddress returns the localhost address of the port the server listens on
+func localAddress(s *Server) string {
+	return fmt.Sprintf("127.0.0.1:%d", s.Addr().(*net.UDPAddr).Port)
+}
+
+func TestServerRoundTrip(t *testing.T) {
+	posted := make(recorder, 16)
+
+	server, err := Listen("127.0.0.1:0", posted, "ui")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	client, err := NewClient(localAddress(server), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	tests := []struct {
+		msgs     []*Message
+		expected []*muse.Message
+	}{
+		{
+			msgs:     []*Message{NewMessage("/synth/filter/cutoff", 800.0)},
+			expected: []*muse.Message{muse.NewMessage("synth.filter", map[string]any{"cutoff": 800.0})},
+		},
+		{
+			msgs:     []*Message{NewMessage("/synth/filter/mode", "lp", 2)},
+			expected: []*muse.Message{muse.NewMessage("synth.filter", map[string]any{"mode": []any{"lp", 2}})},
+		},
+		{
+			msgs:     []*Message{NewMessage("/synth/voice/bang", Impulse{})},
+			expected: []*muse.Message{muse.NewMessage("synth.voice", map[string]any{"bang": true})},
+		},
+		{
+			msgs:     []*Message{NewMessage("/synth")},
+			expected: []*muse.Message{muse.NewMessage("synth", muse.Bang)},
+		},
+		{
+			msgs: []*Message{NewMessage("/synth/filter/get", "cutoff")},
+			expected: []*muse.Message{muse.NewMessage("synth.filter", map[string]any{
+				"command": "get", "parameter": "cutoff", "replyTo": "ui",
+			})},
+		},
+		{
+			msgs: []*Message{NewMessage("/synth/dump")},
+			expected: []*muse.Message{muse.NewMessage("synth", map[string]any{
+				"command": "dump", "replyTo": "ui",
+			})},
+		},
+		{
+			// More than one message is sent as bundle
+			msgs: []*Message{NewMessage("/a/x", int32(1)), NewMessage("/b/y", true)},
+			expected: []*muse.Message{
+				muse.NewMessage("a", map[string]any{"x": 1}),
+				muse.NewMessage("b", map[string]any{"y": true}),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		if err := client.Send(test.msgs...); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, expected := range test.expected {
+			if msg := posted.next(t); !reflect.DeepEqual(msg, expected) {
+				t.Errorf("%v: posted %v %v, expected %v %v", test.msgs, msg.Address, msg.Content, expected.Address, expected.Content)
+			}
+		}
+	}
+}
+
+func TestServerReportsInvalidPackets(t *testing.T) {
+	server, err := Listen("127.0.0.1:0", make(recorder, 1), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	reported := make(chan error, 1)
+	server.SetErrorHandler(func(err error) {
+		reported <- err
+	})
+
+	conn, err := net.Dial("udp", localAddress(server))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte{'/', 'a', 0}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-reported:
+	case <-time.After(2 * time.Second):
+		t.Fatal("invalid packet was not reported")
+	}
+}
+
+func TestQueryReply(t *testing.T) {
+	root := muse.New(1)
+	moog.New(800.0, 0.5, 1.0).Named("filter").AddTo(root)
+
+	// Replies to queries are sent by the ui client to the replies server
+	replies := make(recorder, 16)
+
+	replyServer, err := Listen("127.0.0.1:0", replies, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replyServer.Close()
+
+	ui, err := NewClient(localAddress(replyServer), "/ui")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ui.Close()
+
+	ui.MsgrNamed("ui").MsgrAddTo(root)
+
+	server, err := Listen("127.0.0.1:0", root, "ui")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	client, err := NewClient(localAddress(server), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if err := client.Send(NewMessage("/filter/frequency", 1200.0), NewMessage("/filter/get", "frequency")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Run the audio thread until the reply arrives
+	deadline := time.Now().Add(2 * time.Second)
+
+	for {
+		root.Synthesize()
+
+		select {
+		case msg := <-replies:
+			expected := muse.NewMessage("ui.filter", map[string]any{"frequency": 1200.0})
+			if !reflect.DeepEqual(msg, expected) {
+				t.Fatalf("reply %v %v, expected %v %v", msg.Address, msg.Content, expected.Address, expected.Content)
+			}
+
+			return
+		default:
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for the reply")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFromMuse(t *testing.T) {
+	msgs, err := FromMuse("/ui/", map[string]any{
+		"command": muse.CommandReply,
+		"query":   muse.CommandGet,
+		"address": "synth.filter",
+		"values":  map[string]any{"frequency": 1200.0, "range": []float64{1, 2}, "trigger": muse.Bang},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*Message{
+		NewMessage("/ui/synth/filter/frequency", 1200.0),
+		NewMessage("/ui/synth/filter/range", 1.0, 2.0),
+		NewMessage("/ui/synth/filter/trigger", Impulse{}),
+	}
+
+	if !reflect.DeepEqual(msgs, expected) {
+		t.Fatalf("converted %v, expected %v", msgs, expected)
+	}
+}
@@ -0,0 +1,152 @@
+package muse
+
+import (
+	"fmt"
+	"math"
+)
+
+// ParameterType is the type of value a parameter accepts
+type ParameterType int
+
+const (
+	ParameterFloat ParameterType = iota
+	ParameterInt
+	ParameterBool
+	ParameterString
+	// ParameterBang parameters trigger an action, they accept Bang as control value and
+	// {"bang": true} as message
+	ParameterBang
+	// ParameterAny parameters accept arbitrary values, like message contents passed on to other receivers
+	ParameterAny
+)
+
+func (t ParameterType) String() string {
+	switch t {
+	case ParameterFloat:
+		return "float"
+	case ParameterInt:
+		return "int"
+	case ParameterBool:
+		return "bool"
+	case ParameterString:
+		return "string"
+	case ParameterBang:
+		return "bang"
+	default:
+		return "any"
+	}
+}
+
+// Scaling describes how the range of a parameter maps to a control like a slider or a MIDI controller
+type Scaling int
+
+const (
+	ScalingLinear Scaling = iota
+	// ScalingExponential maps equal control steps to equal ratios, used for frequencies and times
+	ScalingExponential
+)
+
+func (s Scaling) String() string {
+	if s == ScalingExponential {
+		return "exponential"
+	}
+
+	return "linear"
+}
+
+func (s Scaling) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Scaling) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "linear":
+		*s = ScalingLinear
+	case "exponential":
+		*s = ScalingExponential
+	default:
+		return fmt.Errorf("unknown scaling %q", text)
+	}
+
+	return nil
+}
+
+// Parameter describes a parameter of a module, messenger or control together with its current value
+type Parameter struct {
+	Name string
+	// ControlIndex is the control input index that sets the parameter, -1 if it can not be set with a control value
+	ControlIndex int
+	// MessageKey is the message key that sets the parameter, empty if it can not be set with a message
+	MessageKey string
+	Type       ParameterType
+	Min        float64
+	Max        float64
+	Default    any
+	Unit       string
+	Scaling    Scaling
+	// Value is the current value, nil for parameters without state like bangs
+	Value any
+}
+
+// Parameterized is implemented by objects that describe the parameters they accept through
+// ReceiveControlValue and ReceiveMessage
+type Parameterized interface {
+	Parameters() []Parameter
+}
+
+// ParametersOf returns the parameters of v, nil if v does not describe its parameters
+func ParametersOf(v any) []Parameter {
+	if p, ok := v.(Parameterized); ok {
+		return p.Parameters()
+	}
+
+	return nil
+}
+
+// FindParameter returns the parameter with name
+func FindParameter(params []Parameter, name string) (Parameter, bool) {
+	for _, param := range params {
+		if param.Name == name {
+			return param, true
+		}
+	}
+
+	return Parameter{}, false
+}
+
+// Denormalize maps x in the range 0 - 1 to the range of the parameter using its scaling, exponential
+// scaling falls back to linear if the range includes zero
+func (p Parameter) Denormalize(x float64) float64 {
+	x = math.Max(0, math.Min(1, x))
+
+	var v float64
+
+	if p.Scaling == ScalingExponential && p.Min*p.Max > 0 {
+		v = p.Min * math.Pow(p.Max/p.Min, x)
+	} else {
+		v = p.Min + (p.Max-p.Min)*x
+	}
+
+	if p.Type == ParameterInt {
+		v = math.Round(v)
+	}
+
+	return v
+}
+
+// Normalize maps v in the range of the parameter to the range 0 - 1, it is the inverse of Denormalize
+func (p Parameter) Normalize(v float64) float64 {
+	if p.Max == p.Min {
+		return 0
+	}
+
+	var x float64
+
+	if p.Scaling == ScalingExponential && p.Min*p.Max > 0 && v*p.Min > 0 {
+		x = math.Log(v/p.Min) / math.Log(p.Max/p.Min)
+	} else {
+		x = (v - p.Min) / (p.Max - p.Min)
+	}
+
+	return math.Max(0, math.Min(1, x))
+}
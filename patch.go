@@ -2,6 +2,9 @@ package muse
 
 import (
 	"strings"
+	"sync/atomic"
+
+	"github.com/almerlucke/muse/utils/workers"
 )
 
 type Patch interface {
@@ -18,7 +21,14 @@ type Patch interface {
 	RemoveControl(Control)
 	RemoveControlByID(string)
 	Contains(Module) bool
+	Modules() []Module
+	Messengers() []Messenger
+	Controls() []Control
 	Lookup(string) MessageReceiver
+	LookupAll(string) []MessageReceiver
+	Tag(identifier string, tags ...string)
+	Untag(identifier string, tags ...string)
+	Tags(identifier string) []string
 	InputModuleAtIndex(index int) Module
 	OutputModuleAtIndex(index int) Module
 	InternalInputControl() Control
@@ -37,9 +47,28 @@ type BasePatch struct {
 	messengers            []Messenger
 	controls              []Control
 	receivers             map[string]MessageReceiver
+	identifiers           []string
+	tags                  map[string][]string
+	schedule              []Module
+	taps                  []feedbackTap
+	graphVersion          atomic.Uint64
+	scheduleVersion       uint64
+	pool                  *workers.Pool
+	transport             *Transport
+	stages                stages
 	timestamp             int64
 }
 
+// WorkerPoolUser is implemented by modules that can render parts of their work in parallel
+type WorkerPoolUser interface {
+	SetWorkerPool(*workers.Pool)
+}
+
+// TransportUser is implemented by objects that follow the transport of the muse they are added to
+type TransportUser interface {
+	SetTransport(*Transport)
+}
+
 func NewPatch(numInputs int, numOutputs int) *BasePatch {
 	var subModules []Module
 
@@ -65,10 +94,17 @@ func NewPatch(numInputs int, numOutputs int) *BasePatch {
 		inputModules:          inputModules,
 		outputModules:         outputModules,
 		receivers:             map[string]MessageReceiver{},
+		tags:                  map[string][]string{},
 	}
 
 	p.SetSelf(p)
 
+	for _, m := range subModules {
+		m.(graphMember).setParent(p)
+	}
+
+	p.invalidate()
+
 	return p
 }
 
@@ -83,16 +119,20 @@ func (p *BasePatch) NumOutputs() int {
 func (p *BasePatch) AddMessageReceiver(rcvr MessageReceiver, identifier string) {
 	if identifier != "" {
 		p.receivers[identifier] = rcvr
+		p.identifiers = nil
 	}
 }
 
 func (p *BasePatch) RemoveMessageReceiverByID(id string) {
 	delete(p.receivers, id)
+	p.identifiers = nil
 }
 
 func (p *BasePatch) AddMessenger(msgr Messenger) Messenger {
 	p.messengers = append(p.messengers, msgr)
 
+	p.passTransport(msgr)
+
 	p.AddMessageReceiver(msgr, msgr.Identifier())
 
 	return msgr
@@ -111,7 +151,7 @@ func (p *BasePatch) RemoveMessenger(msgr Messenger) {
 		p.messengers = append(p.messengers[:removeIndex], p.messengers[removeIndex+1:]...)
 		if receiver, ok := p.receivers[msgr.Identifier()]; ok {
 			if receiver == msgr {
-				delete(p.receivers, msgr.Identifier())
+				p.RemoveMessageReceiverByID(msgr.Identifier())
 			}
 		}
 	}
@@ -133,7 +173,7 @@ func (p *BasePatch) RemoveMessengerByID(id string) {
 		p.messengers = append(p.messengers[:removeIndex], p.messengers[removeIndex+1:]...)
 		if receiver, ok := p.receivers[id]; ok {
 			if receiver == msgr {
-				delete(p.receivers, id)
+				p.RemoveMessageReceiverByID(id)
 			}
 		}
 
@@ -144,8 +184,20 @@ func (p *BasePatch) RemoveMessengerByID(id string) {
 func (p *BasePatch) AddModule(m Module) Module {
 	p.subModules = append(p.subModules, m)
 
+	if user, ok := m.(WorkerPoolUser); ok && p.pool != nil {
+		user.SetWorkerPool(p.pool)
+	}
+
+	p.passTransport(m)
+
+	if member, ok := m.(graphMember); ok {
+		member.setParent(p)
+	}
+
 	p.AddMessageReceiver(m, m.Identifier())
 
+	p.invalidate()
+
 	return m
 }
 
@@ -162,13 +214,16 @@ func (p *BasePatch) RemoveModule(m Module) {
 		p.subModules = append(p.subModules[:removeIndex], p.subModules[removeIndex+1:]...)
 		if receiver, ok := p.receivers[m.Identifier()]; ok {
 			if receiver == m {
-				delete(p.receivers, m.Identifier())
+				p.RemoveMessageReceiverByID(m.Identifier())
 			}
 		}
 	}
 
 	m.Disconnect()
 	m.CtrlDisconnect()
+
+	p.removeParent(m)
+	p.invalidate()
 }
 
 func (p *BasePatch) RemoveModuleByID(id string) {
@@ -185,18 +240,23 @@ func (p *BasePatch) RemoveModuleByID(id string) {
 		p.subModules = append(p.subModules[:removeIndex], p.subModules[removeIndex+1:]...)
 		if receiver, ok := p.receivers[id]; ok {
 			if receiver == m {
-				delete(p.receivers, id)
+				p.RemoveMessageReceiverByID(id)
 			}
 		}
 
 		m.Disconnect()
 		m.CtrlDisconnect()
+
+		p.removeParent(m)
+		p.invalidate()
 	}
 }
 
 func (p *BasePatch) AddControl(ct Control) Control {
 	p.controls = append(p.controls, ct)
 
+	p.passTransport(ct)
+
 	p.AddMessageReceiver(ct, ct.Identifier())
 
 	return ct
@@ -215,7 +275,7 @@ func (p *BasePatch) RemoveControl(ct Control) {
 		p.controls = append(p.controls[:removeIndex], p.controls[removeIndex+1:]...)
 		if receiver, ok := p.receivers[ct.Identifier()]; ok {
 			if receiver == ct {
-				delete(p.receivers, ct.Identifier())
+				p.RemoveMessageReceiverByID(ct.Identifier())
 			}
 		}
 	}
@@ -237,7 +297,7 @@ func (p *BasePatch) RemoveControlByID(id string) {
 		p.controls = append(p.controls[:removeIndex], p.controls[removeIndex+1:]...)
 		if receiver, ok := p.receivers[id]; ok {
 			if receiver == ct {
-				delete(p.receivers, id)
+				p.RemoveMessageReceiverByID(id)
 			}
 		}
 		ct.CtrlDisconnect()
@@ -254,7 +314,35 @@ func (p *BasePatch) Contains(m Module) bool {
 	return false
 }
 
+// Timestamp returns the sample time of the block that is synthesized next, or that is being synthesized
+// while messages are sent
+func (p *BasePatch) Timestamp() int64 {
+	return p.timestamp
+}
+
+// Modules returns all sub modules, including the input and output thru modules
+func (p *BasePatch) Modules() []Module {
+	return p.subModules
+}
+
+func (p *BasePatch) Messengers() []Messenger {
+	return p.messengers
+}
+
+func (p *BasePatch) Controls() []Control {
+	return p.controls
+}
+
+// Lookup returns the receiver at address, for a pattern the first matching receiver is returned
 func (p *BasePatch) Lookup(address string) MessageReceiver {
+	if IsPattern(address) {
+		if all := p.LookupAll(address); len(all) > 0 {
+			return all[0]
+		}
+
+		return nil
+	}
+
 	components := strings.SplitN(address, ".", 2)
 	identifier := ""
 	restAddress := ""
@@ -284,12 +372,10 @@ func (p *BasePatch) Lookup(address string) MessageReceiver {
 	return m
 }
 
+// PrepareSynthesis only prepares the patch itself, sub modules are prepared
+// right before they are synthesized by the schedule
 func (p *BasePatch) PrepareSynthesis() {
 	p.BaseModule.PrepareSynthesis()
-
-	for _, module := range p.subModules {
-		module.PrepareSynthesis()
-	}
 }
 
 func (p *BasePatch) Synthesize() bool {
@@ -297,6 +383,24 @@ func (p *BasePatch) Synthesize() bool {
 		return false
 	}
 
+	// Input modules gather the connections into the patch
+	for _, input := range p.inputModules {
+		input.PrepareSynthesis()
+		input.Synthesize()
+	}
+
+	p.synthesizeSchedule()
+
+	return true
+}
+
+// synthesizeSchedule runs messengers and controls and synthesizes all sub modules in
+// schedule order, the schedule is recompiled first if the graph has changed
+func (p *BasePatch) synthesizeSchedule() {
+	if version := p.graphVersion.Load(); version != p.scheduleVersion {
+		p.compile(version)
+	}
+
 	// Send messages for each messenger
 	for _, msgr := range p.messengers {
 		p.SendMessages(msgr.Messages(p.timestamp, p.Config))
@@ -307,74 +411,169 @@ func (p *BasePatch) Synthesize() bool {
 		ticker.Tick(p.timestamp, p.Config)
 	}
 
-	// Must synthesize some modules outside normal pull mechanism
-	for _, module := range p.subModules {
-		if module.MustSynthesize() {
+	if p.pool != nil {
+		p.stages.run(p.pool)
+	} else {
+		// Modules in the schedule come after the modules they depend on
+		for _, module := range p.schedule {
+			module.PrepareSynthesis()
 			module.Synthesize()
 		}
 	}
 
-	// Output modules pull and request synthesize from the connected input modules
-	for _, output := range p.outputModules {
-		output.Synthesize()
+	// Keep the output of feedback senders for the next block
+	for _, tap := range p.taps {
+		copy(tap.tap, tap.output)
 	}
 
 	// Update timestamp
 	p.timestamp += int64(p.Config.BufferSize)
+}
 
-	return true
+// SetWorkerPool renders independent modules of the patch in parallel on the workers of pool,
+// the pool is passed on to all sub modules that implement WorkerPoolUser. Modules that send
+// control values are synthesized on their own so the output is identical to rendering without
+// a pool, as long as modules that are not connected do not share state (like a random source).
+// A nil pool switches back to rendering on the calling goroutine
+func (p *BasePatch) SetWorkerPool(pool *workers.Pool) {
+	p.pool = pool
+
+	if pool != nil && p.stages.batch == nil {
+		p.stages.batch = workers.NewBatch(&p.stages)
+	}
+
+	for _, m := range p.subModules {
+		if user, ok := m.(WorkerPoolUser); ok {
+			user.SetWorkerPool(pool)
+		}
+	}
+
+	// Force a recompile to create the stages
+	p.scheduleVersion = 0
+}
+
+// SetTransport passes the transport on to all modules, messengers and controls of the patch that implement
+// TransportUser, and to objects added later. The muse sets its transport on itself
+func (p *BasePatch) SetTransport(transport *Transport) {
+	p.transport = transport
+
+	for _, m := range p.subModules {
+		p.passTransport(m)
+	}
+
+	for _, msgr := range p.messengers {
+		p.passTransport(msgr)
+	}
+
+	for _, ct := range p.controls {
+		p.passTransport(ct)
+	}
+}
+
+// Transport returns the transport of the muse the patch is added to, or nil
+func (p *BasePatch) Transport() *Transport {
+	return p.transport
+}
+
+// removeParent detaches a removed module from the patch, unless it was added to another patch since
+func (p *BasePatch) removeParent(m Module) {
+	if member, ok := m.(graphMember); ok && member.parentPatch() == p {
+		member.setParent(nil)
+	}
+}
+
+func (p *BasePatch) passTransport(obj any) {
+	if user, ok := obj.(TransportUser); ok && p.transport != nil {
+		user.SetTransport(p.transport)
+	}
 }
 
 func (p *BasePatch) ReceiveMessage(msg any) []*Message {
-	content := msg.(map[string]any)
+	content, ok := DecodeMessage(p, msg)
+	if !ok {
+		return nil
+	}
 
-	if command, ok := content["command"]; ok {
-		switch command.(string) {
-		case "AddMessenger":
-			if messenger, ok := content["messenger"]; ok {
-				p.AddMessenger(messenger.(Messenger))
-			}
-		case "RemoveMessenger":
-			if messenger, ok := content["messenger"]; ok {
-				p.RemoveMessenger(messenger.(Messenger))
-			}
-		case "RemoveMessengerByID":
-			if messenger, ok := content["messenger"]; ok {
-				p.RemoveMessengerByID(messenger.(string))
-			}
-		case "AddControl":
-			if control, ok := content["control"]; ok {
-				p.AddControl(control.(Control))
-			}
-		case "RemoveControl":
-			if control, ok := content["control"]; ok {
-				p.RemoveControl(control.(Control))
-			}
-		case "RemoveControlByID":
-			if control, ok := content["control"]; ok {
-				p.RemoveControlByID(control.(string))
-			}
-		case "AddModule":
-			if module, ok := content["module"]; ok {
-				p.AddModule(module.(Module))
-			}
-		case "RemoveModule":
-			if module, ok := content["module"]; ok {
-				p.RemoveModule(module.(Module))
-			}
-		case "RemoveModuleByID":
-			if module, ok := content["module"]; ok {
-				p.RemoveModuleByID(module.(string))
-			}
+	command, ok := content.String("command")
+	if !ok {
+		return nil
+	}
+
+	switch command {
+	case "AddMessenger":
+		if messenger, ok := decodeValue[Messenger](content, "messenger"); ok {
+			p.AddMessenger(messenger)
+		}
+	case "RemoveMessenger":
+		if messenger, ok := decodeValue[Messenger](content, "messenger"); ok {
+			p.RemoveMessenger(messenger)
+		}
+	case "RemoveMessengerByID":
+		if messenger, ok := content.String("messenger"); ok {
+			p.RemoveMessengerByID(messenger)
+		}
+	case "AddControl":
+		if control, ok := decodeValue[Control](content, "control"); ok {
+			p.AddControl(control)
+		}
+	case "RemoveControl":
+		if control, ok := decodeValue[Control](content, "control"); ok {
+			p.RemoveControl(control)
 		}
+	case "RemoveControlByID":
+		if control, ok := content.String("control"); ok {
+			p.RemoveControlByID(control)
+		}
+	case "AddModule":
+		if module, ok := decodeValue[Module](content, "module"); ok {
+			p.AddModule(module)
+		}
+	case "RemoveModule":
+		if module, ok := decodeValue[Module](content, "module"); ok {
+			p.RemoveModule(module)
+		}
+	case "RemoveModuleByID":
+		if module, ok := content.String("module"); ok {
+			p.RemoveModuleByID(module)
+		}
+	case CommandGet, CommandDump:
+		// Queries sent to the patch itself, queries addressed inside the patch are answered by SendMessage
+		return answerQuery(p.Identifier(), p.Self(), content.Values(), command)
+	default:
+		content.Invalid("command", "unknown command")
 	}
 
 	return nil
 }
 
+// SendMessage sends a message to the receiver at the message address, or to all receivers matching
+// the address if it is a pattern. Messages with an offset go to receivers that implement
+// TimedMessageReceiver. Messages returned by the receiver without an offset of their own get the
+// offset of the message
 func (p *BasePatch) SendMessage(msg *Message) {
-	rcvr := p.Lookup(msg.Address)
-	if rcvr != nil {
+	for _, m := range p.lookupMatches(msg.Address) {
+		p.deliver(m.address, m.receiver, msg)
+	}
+}
+
+func (p *BasePatch) deliver(address string, rcvr MessageReceiver, msg *Message) {
+	if command, ok := queryCommand(msg.Content); ok {
+		p.SendMessages(answerQuery(address, rcvr, msg.Content.(map[string]any), command))
+		return
+	}
+
+	if timed, ok := rcvr.(TimedMessageReceiver); ok && msg.Offset > 0 {
+		for _, returned := range timed.ReceiveMessageAt(msg.Content, msg.Offset) {
+			if returned.Offset == 0 {
+				// Copy because returned messages can be reused by their sender
+				timedMsg := *returned
+				timedMsg.Offset = msg.Offset
+				returned = &timedMsg
+			}
+
+			p.SendMessage(returned)
+		}
+	} else {
 		p.SendMessages(rcvr.ReceiveMessage(msg.Content))
 	}
 }
@@ -444,10 +643,22 @@ func (p *BasePatch) ReceiveControlValue(value any, index int) {
 	p.internalInputControl.ReceiveControlValue(value, index)
 }
 
+func (p *BasePatch) ReceiveControlValueAt(value any, index int, offset int) {
+	p.internalInputControl.ReceiveControlValueAt(value, index, offset)
+}
+
 func (p *BasePatch) AddControlInputConnection(inputIndex int, sender Control, outputIndex int) {
 	p.internalInputControl.AddControlInputConnection(inputIndex, sender, outputIndex)
 }
 
+func (p *BasePatch) CtrlInputConnections() map[int][]*ControlConnection {
+	return p.internalInputControl.CtrlInputConnections()
+}
+
+func (p *BasePatch) CtrlOutputConnections() map[int][]*ControlConnection {
+	return p.internalOutputControl.CtrlOutputConnections()
+}
+
 func (p *BasePatch) AddControlOutputConnection(outputIndex int, receiver Control, inputIndex int) {
 	p.internalOutputControl.AddControlOutputConnection(outputIndex, receiver, inputIndex)
 }
@@ -0,0 +1,550 @@
+package builtin
+
+import (
+	"fmt"
+
+	adsrc "github.com/almerlucke/genny/float/envelopes/adsr"
+	"github.com/almerlucke/muse/controls/divider"
+	"github.com/almerlucke/muse/controls/log"
+	"github.com/almerlucke/muse/messengers/lfo"
+	"github.com/almerlucke/muse/messengers/scheduler"
+	"github.com/almerlucke/muse/messengers/triggers/once"
+	"github.com/almerlucke/muse/messengers/triggers/timer"
+	"github.com/almerlucke/muse/modules/adsr"
+	"github.com/almerlucke/muse/modules/allpass"
+	"github.com/almerlucke/muse/modules/delay"
+	"github.com/almerlucke/muse/modules/effects/chorus"
+	"github.com/almerlucke/muse/modules/effects/flanger"
+	"github.com/almerlucke/muse/modules/effects/freeverb"
+	"github.com/almerlucke/muse/modules/effects/pingpong"
+	"github.com/almerlucke/muse/modules/filters"
+	"github.com/almerlucke/muse/modules/filters/butterworth"
+	"github.com/almerlucke/muse/modules/filters/korg35"
+	"github.com/almerlucke/muse/modules/filters/moog"
+	"github.com/almerlucke/muse/modules/filters/moog2"
+	"github.com/almerlucke/muse/modules/filters/rbj"
+	"github.com/almerlucke/muse/modules/functor"
+	lfom "github.com/almerlucke/muse/modules/lfo"
+	"github.com/almerlucke/muse/modules/mixer"
+	"github.com/almerlucke/muse/modules/noise"
+	"github.com/almerlucke/muse/modules/osc"
+	"github.com/almerlucke/muse/modules/pan"
+	"github.com/almerlucke/muse/modules/phasor"
+	"github.com/almerlucke/muse/modules/player"
+	"github.com/almerlucke/muse/modules/vartri"
+	"github.com/almerlucke/muse/modules/xfade"
+	"github.com/almerlucke/muse/patchdoc"
+	"github.com/almerlucke/muse/synths/classic"
+	"github.com/almerlucke/muse/utils"
+	"github.com/almerlucke/sndfile"
+)
+
+// reader wraps params and remembers the first error so factories can read all
+// parameters first and check for errors once
+type reader struct {
+	params patchdoc.Params
+	err    error
+}
+
+func (r *reader) keep(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *reader) float(key string, def float64) float64 {
+	v, err := r.params.Float(key, def)
+	r.keep(err)
+	return v
+}
+
+func (r *reader) int(key string, def int) int {
+	v, err := r.params.Int(key, def)
+	r.keep(err)
+	return v
+}
+
+func (r *reader) bool(key string, def bool) bool {
+	v, err := r.params.Bool(key, def)
+	r.keep(err)
+	return v
+}
+
+func (r *reader) string(key string, def string) string {
+	v, err := r.params.String(key, def)
+	r.keep(err)
+	return v
+}
+
+func (r *reader) floats(key string, def []float64) []float64 {
+	v, err := r.params.Floats(key, def)
+	r.keep(err)
+	return v
+}
+
+func (r *reader) strings(key string, def []string) []string {
+	v, err := r.params.Strings(key, def)
+	r.keep(err)
+	return v
+}
+
+func (r *reader) decode(key string, v any) {
+	r.keep(r.params.Decode(key, v))
+}
+
+// Register registers all built-in modules, messengers and controls with the registry
+func Register(reg *patchdoc.Registry) {
+	registerModules(reg)
+	registerFilters(reg)
+	registerEffects(reg)
+	registerSynths(reg)
+	registerMessengers(reg)
+	registerControls(reg)
+	registerExporters(reg)
+}
+
+func init() {
+	Register(patchdoc.DefaultRegistry)
+}
+
+var releaseModes = map[string]adsrc.ReleaseMode{
+	"automatic": adsrc.Automatic,
+	"duration":  adsrc.Duration,
+	"noteOff":   adsrc.NoteOff,
+}
+
+func releaseMode(name string) (adsrc.ReleaseMode, error) {
+	mode, ok := releaseModes[name]
+	if !ok {
+		return adsrc.Automatic, fmt.Errorf("unknown release mode %q", name)
+	}
+
+	return mode, nil
+}
+
+func releaseModeName(mode adsrc.ReleaseMode) string {
+	for name, m := range releaseModes {
+		if m == mode {
+			return name
+		}
+	}
+
+	return "automatic"
+}
+
+func envelopeSetting(r *reader, key string) *adsrc.Setting {
+	setting := adsrc.NewSetting(1.0, 5.0, 0.2, 5.0, 0.0, 5.0)
+	r.decode(key, setting)
+	return setting
+}
+
+func registerModules(reg *patchdoc.Registry) {
+	reg.Register("osc.Osc", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		mix := r.floats("mix", []float64{0.5, 0.01, 0.1, 0.5})
+		if len(mix) != 4 {
+			return nil, fmt.Errorf("parameter \"mix\": expected 4 values")
+		}
+		o := osc.NewX(r.float("frequency", 100.0), r.float("phase", 0.0), r.float("pulseWidth", 0.5), [4]float64(mix))
+		return o, r.err
+	})
+
+	reg.Register("osc.Osc2", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		o := osc.NewOsc2(
+			r.float("frequency", 100.0),
+			r.float("phase", 0.0),
+			r.float("pulseWidth", 0.5),
+			r.float("amplitude", 1.0),
+			osc.Waveform(r.int("waveform", int(osc.SINE))),
+		)
+		return o, r.err
+	})
+
+	reg.Register("phasor", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		return phasor.New(r.float("frequency", 100.0), r.float("phase", 0.0)), r.err
+	})
+
+	reg.Register("vartri", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		return vartri.New(r.float("frequency", 100.0), r.float("phase", 0.0), r.float("dutyWidth", 0.5)), r.err
+	})
+
+	reg.Register("lfo", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		return lfom.New(r.float("frequency", 1.0), r.float("min", 0.0), r.float("max", 1.0)), r.err
+	})
+
+	reg.Register("noise", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		return noise.New(uint64(r.int("seed", 1))), r.err
+	})
+
+	reg.Register("adsr", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		setting := envelopeSetting(r, "setting")
+		mode, err := releaseMode(r.string("releaseMode", "duration"))
+		r.keep(err)
+		a := adsr.New(setting, mode, r.float("level", 1.0))
+		a.SetDuration(r.float("duration", 250.0))
+		return a, r.err
+	})
+
+	reg.Register("player", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		file := r.string("file", "")
+		if r.err != nil {
+			return nil, r.err
+		}
+
+		sf, err := sndfile.NewMipMapSoundFile(file, 4)
+		if err != nil {
+			return nil, err
+		}
+
+		p := player.NewX(
+			sf,
+			r.float("speed", 1.0),
+			r.float("amplitude", 1.0),
+			r.float("startOffset", 0.0),
+			r.float("endOffset", sf.Duration()),
+			r.bool("oneShot", true),
+		)
+
+		return p, r.err
+	})
+
+	reg.Register("mixer", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		m := mixer.New(r.int("numInputs", 2))
+		mix := r.floats("mix", nil)
+		if len(mix) > m.NumInputs() {
+			r.keep(fmt.Errorf("parameter \"mix\": more values than inputs"))
+		} else {
+			for i, v := range mix {
+				m.SetMixAt(i, v)
+			}
+		}
+		return m, r.err
+	})
+
+	reg.Register("pan", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		return pan.New(r.float("pan", 0.5)), r.err
+	})
+
+	reg.Register("xfade", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		return xfade.New(r.float("fade", 0.5)), r.err
+	})
+
+	reg.Register("delay", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		return delay.New(r.float("length", 1000.0), r.float("location", 250.0)), r.err
+	})
+
+	reg.Register("allpass", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		return allpass.New(r.float("length", 1000.0), r.float("location", 250.0), r.float("feedback", 0.5)), r.err
+	})
+
+	reg.Register("functor.mult", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		return functor.NewMult(r.int("numInputs", 2)), r.err
+	})
+
+	reg.Register("functor.scale", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		return functor.NewScale(r.float("scale", 1.0), r.float("offset", 0.0)), r.err
+	})
+
+	reg.Register("functor.amp", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		return functor.NewAmp(r.float("amp", 1.0)), r.err
+	})
+
+	reg.Register("functor.between", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		return functor.NewBetween(r.float("min", 0.0), r.float("max", 1.0)), r.err
+	})
+}
+
+var filterFactories = map[string]utils.Factory[filters.Filter]{
+	"moog":        &moog.Factory{},
+	"korg35":      &korg35.Factory{},
+	"butterworth": &butterworth.Factory{},
+	"rbj":         &rbj.Factory{},
+}
+
+var filterDefaults = map[string]func() *filters.FilterConfig{
+	"moog":        moog.DefaultConfig,
+	"korg35":      korg35.DefaultConfig,
+	"butterworth": butterworth.DefaultConfig,
+	"rbj":         rbj.DefaultConfig,
+}
+
+func filterConfig(r *reader, name string) *filters.FilterConfig {
+	cfg := filterDefaults[name]()
+	cfg.Frequency = r.float("frequency", cfg.Frequency)
+	cfg.Resonance = r.float("resonance", cfg.Resonance)
+	cfg.Drive = r.float("drive", cfg.Drive)
+	cfg.Type = r.int("filterType", cfg.Type)
+	return cfg
+}
+
+func registerFilters(reg *patchdoc.Registry) {
+	for name, factory := range filterFactories {
+		reg.Register("filters."+name, func(params patchdoc.Params) (any, error) {
+			r := &reader{params: params}
+			cfg := filterConfig(r, name)
+			if r.err != nil {
+				return nil, r.err
+			}
+			return factory.New(cfg), nil
+		})
+	}
+
+	reg.Register("filters.moog2", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		return moog2.New(r.float("frequency", 1500.0), r.float("resonance", 0.5)), r.err
+	})
+}
+
+func registerEffects(reg *patchdoc.Registry) {
+	reg.Register("effects.freeverb", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		fv := freeverb.New()
+		fv.SetRoomSize(r.float("roomSize", 0.5))
+		fv.SetDamp(r.float("damp", 0.5))
+		fv.SetWet(r.float("wet", 1.0/3.0))
+		fv.SetDry(r.float("dry", 0.0))
+		fv.SetWidth(r.float("width", 1.0))
+		fv.SetMode(r.float("mode", 0.0))
+		return fv, r.err
+	})
+
+	reg.Register("effects.chorus", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		c := chorus.New(
+			r.float("rate", 0.1),
+			r.float("amount", 0.5),
+			r.float("delay", 0.5),
+			r.float("feedback", 0.1),
+			r.float("width", 0.5),
+			r.float("mix", 0.5),
+			nil,
+		)
+		return c, r.err
+	})
+
+	reg.Register("effects.flanger", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		return flanger.New(r.float("depth", 0.5), r.float("feedback", 0.5), r.float("mix", 0.5)), r.err
+	})
+
+	reg.Register("effects.pingpong", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		pp := pingpong.New(r.float("length", 1000.0), r.float("read", 250.0), r.float("feedback", 0.3), r.float("mix", 0.3))
+		return pp, r.err
+	})
+}
+
+func registerSynths(reg *patchdoc.Registry) {
+	reg.Register("synths.classic", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		ampEnv := envelopeSetting(r, "ampEnvelope")
+		filterEnv := envelopeSetting(r, "filterEnvelope")
+		filterName := r.string("filter", "moog")
+
+		factory, ok := filterFactories[filterName]
+		if !ok {
+			r.keep(fmt.Errorf("unknown filter %q", filterName))
+		}
+
+		if r.err != nil {
+			return nil, r.err
+		}
+
+		cfg := filterConfig(r, filterName)
+
+		s := classic.New(r.int("numVoices", 8), ampEnv, filterEnv, factory, cfg)
+
+		setting := classic.DefaultSetting()
+		r.decode("setting", &setting)
+		s.Set(setting)
+
+		return s, r.err
+	})
+}
+
+func registerMessengers(reg *patchdoc.Registry) {
+	reg.Register("messengers.timer", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		return timer.New(r.float("interval", 250.0), r.strings("addresses", nil), nil), r.err
+	})
+
+	reg.Register("messengers.once", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		return once.NewOnce(r.strings("addresses", nil)), r.err
+	})
+
+	reg.Register("messengers.lfo", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		return lfo.NewBasicControlLFO(r.float("speed", 1.0), r.float("min", 0.0), r.float("max", 1.0)), r.err
+	})
+
+	reg.Register("messengers.scheduler", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		var events []*scheduler.Event
+		r.decode("events", &events)
+		return scheduler.NewWithEvents(events), r.err
+	})
+}
+
+func registerControls(reg *patchdoc.Registry) {
+	reg.Register("controls.divider", func(params patchdoc.Params) (any, error) {
+		r := &reader{params: params}
+		return divider.New(r.int("n", 2)), r.err
+	})
+
+	reg.Register("controls.log", func(params patchdoc.Params) (any, error) {
+		return log.New(), nil
+	})
+}
+
+func exportFilter(obj any, params patchdoc.Params) {
+	f := obj.(filters.Filter)
+	params["frequency"] = f.Frequency()
+	params["resonance"] = f.Resonance()
+	params["drive"] = f.Drive()
+	params["filterType"] = f.Type()
+}
+
+// registerExporters registers exporters for all types with parameters that can change after creation.
+// Some types are created with parameters that can not be read back, like the player sound file or a
+// delay length, objects of those types only export them when they were created by the registry
+func registerExporters(reg *patchdoc.Registry) {
+	reg.RegisterExporter("osc.Osc", (*osc.Osc)(nil), func(obj any, params patchdoc.Params) {
+		o := obj.(*osc.Osc)
+		mix := o.Mix()
+		params["frequency"] = o.Frequency()
+		params["pulseWidth"] = o.PulseWidth()
+		params["mix"] = mix[:]
+	})
+
+	reg.RegisterExporter("osc.Osc2", (*osc.Osc2)(nil), func(obj any, params patchdoc.Params) {
+		o := obj.(*osc.Osc2)
+		params["frequency"] = o.Frequency()
+		params["pulseWidth"] = o.PulseWidth()
+		params["amplitude"] = o.Amplitude()
+		params["waveform"] = int(o.Waveform())
+	})
+
+	reg.RegisterExporter("phasor", (*phasor.Phasor)(nil), func(obj any, params patchdoc.Params) {
+		params["frequency"] = obj.(*phasor.Phasor).Frequency()
+	})
+
+	reg.RegisterExporter("vartri", (*vartri.VarTri)(nil), func(obj any, params patchdoc.Params) {
+		vt := obj.(*vartri.VarTri)
+		params["frequency"] = vt.Frequency()
+		params["dutyWidth"] = vt.DutyWidth()
+	})
+
+	reg.RegisterExporter("adsr", (*adsr.ADSR)(nil), func(obj any, params patchdoc.Params) {
+		a := obj.(*adsr.ADSR)
+		params["setting"] = a.Setting()
+		params["releaseMode"] = releaseModeName(a.ReleaseMode())
+		params["level"] = a.Level()
+		params["duration"] = a.Duration()
+	})
+
+	reg.RegisterExporter("player", (*player.Player)(nil), func(obj any, params patchdoc.Params) {
+		p := obj.(*player.Player)
+		params["speed"] = p.Speed()
+		params["startOffset"] = p.StartOffset()
+		params["endOffset"] = p.EndOffset()
+		params["amplitude"] = p.Amplitude()
+		params["oneShot"] = p.OneShot()
+	})
+
+	reg.RegisterExporter("mixer", (*mixer.Mixer)(nil), func(obj any, params patchdoc.Params) {
+		m := obj.(*mixer.Mixer)
+		params["numInputs"] = m.NumInputs()
+		params["mix"] = m.Mix()
+	})
+
+	reg.RegisterExporter("pan", (*pan.Pan)(nil), func(obj any, params patchdoc.Params) {
+		params["pan"] = obj.(*pan.Pan).Pan()
+	})
+
+	reg.RegisterExporter("xfade", (*xfade.XFade)(nil), func(obj any, params patchdoc.Params) {
+		params["fade"] = obj.(*xfade.XFade).Fade()
+	})
+
+	reg.RegisterExporter("delay", (*delay.Delay)(nil), func(obj any, params patchdoc.Params) {
+		params["location"] = obj.(*delay.Delay).ReadLocation()
+	})
+
+	reg.RegisterExporter("allpass", (*allpass.Allpass)(nil), func(obj any, params patchdoc.Params) {
+		a := obj.(*allpass.Allpass)
+		params["location"] = a.ReadLocation()
+		params["feedback"] = a.Feedback()
+	})
+
+	reg.RegisterExporter("filters.moog", (*moog.Moog)(nil), exportFilter)
+	reg.RegisterExporter("filters.korg35", (*korg35.LPF)(nil), exportFilter)
+	reg.RegisterExporter("filters.butterworth", (*butterworth.Butterworth)(nil), exportFilter)
+	reg.RegisterExporter("filters.rbj", (*rbj.Filter)(nil), exportFilter)
+
+	reg.RegisterExporter("effects.freeverb", (*freeverb.FreeVerb)(nil), func(obj any, params patchdoc.Params) {
+		fv := obj.(*freeverb.FreeVerb)
+		params["roomSize"] = fv.RoomSize()
+		params["damp"] = fv.Damp()
+		params["wet"] = fv.Wet()
+		params["dry"] = fv.Dry()
+		params["width"] = fv.Width()
+		params["mode"] = fv.Mode()
+	})
+
+	reg.RegisterExporter("effects.chorus", (*chorus.Chorus)(nil), func(obj any, params patchdoc.Params) {
+		c := obj.(*chorus.Chorus)
+		params["rate"] = c.Rate()
+		params["amount"] = c.Amount()
+		params["delay"] = c.Delay()
+		params["feedback"] = c.Feedback()
+		params["width"] = c.Width()
+		params["mix"] = c.Mix()
+	})
+
+	reg.RegisterExporter("effects.flanger", (*flanger.Flanger)(nil), func(obj any, params patchdoc.Params) {
+		f := obj.(*flanger.Flanger)
+		params["depth"] = f.Depth()
+		params["feedback"] = f.Feedback()
+		params["mix"] = f.Mix()
+	})
+
+	reg.RegisterExporter("effects.pingpong", (*pingpong.PingPong)(nil), func(obj any, params patchdoc.Params) {
+		pp := obj.(*pingpong.PingPong)
+		params["read"] = pp.Read()
+		params["feedback"] = pp.Feedback()
+		params["mix"] = pp.Mix()
+	})
+
+	reg.RegisterExporter("synths.classic", (*classic.Synth)(nil), func(obj any, params patchdoc.Params) {
+		params["setting"] = obj.(*classic.Synth).Setting()
+	})
+
+	reg.RegisterExporter("messengers.timer", (*timer.Timer)(nil), func(obj any, params patchdoc.Params) {
+		t := obj.(*timer.Timer)
+		params["interval"] = t.Interval()
+		params["addresses"] = t.Addresses()
+	})
+
+	reg.RegisterExporter("messengers.lfo", (*lfo.LFO)(nil), func(obj any, params patchdoc.Params) {
+		l := obj.(*lfo.LFO)
+		params["speed"] = l.Speed()
+		params["min"] = l.Min()
+		params["max"] = l.Max()
+	})
+}
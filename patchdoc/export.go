@@ -0,0 +1,317 @@
+package patchdoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/almerlucke/muse"
+	"gopkg.in/yaml.v3"
+)
+
+// endpoint is the document address and input index of a connection end, an index
+// of -1 means the input index of the connection itself is used
+type endpoint struct {
+	address string
+	index   int
+}
+
+// exporter holds the address maps of a single patch level
+type exporter struct {
+	reg      *Registry
+	p        muse.Patch
+	doc      *Document
+	used     map[string]bool
+	ids      map[any]string
+	thrus    map[muse.Module]bool
+	targets  map[muse.Module]endpoint
+	controls map[muse.Control]string
+}
+
+// Export creates a document from a live patch, including all sub patches, connections
+// and the current parameters of all objects. Objects must either be created by the
+// registry or have an exporter registered for their type, sub patches of type
+// *muse.BasePatch are exported as nested documents. Objects without identifier get
+// a generated identifier
+func Export(p muse.Patch, reg *Registry) (*Document, error) {
+	if reg == nil {
+		reg = DefaultRegistry
+	}
+
+	e := &exporter{
+		reg: reg,
+		p:   p,
+		doc: &Document{
+			ID:      p.Identifier(),
+			Inputs:  p.NumInputs(),
+			Outputs: p.NumOutputs(),
+		},
+		used:     map[string]bool{SelfAddress: true},
+		ids:      map[any]string{},
+		thrus:    map[muse.Module]bool{},
+		targets:  map[muse.Module]endpoint{},
+		controls: map[muse.Control]string{},
+	}
+
+	for i := 0; i < p.NumInputs(); i++ {
+		e.thrus[p.InputModuleAtIndex(i)] = true
+	}
+
+	for i := 0; i < p.NumOutputs(); i++ {
+		e.thrus[p.OutputModuleAtIndex(i)] = true
+		e.targets[p.OutputModuleAtIndex(i)] = endpoint{address: SelfAddress, index: i}
+	}
+
+	err := e.exportObjects()
+	if err != nil {
+		return nil, err
+	}
+
+	err = e.exportConnections()
+	if err != nil {
+		return nil, err
+	}
+
+	err = e.exportControlConnections()
+	if err != nil {
+		return nil, err
+	}
+
+	return e.doc, nil
+}
+
+// Save exports a patch with the default registry and writes it to file
+func Save(p muse.Patch, filePath string) error {
+	doc, err := Export(p, DefaultRegistry)
+	if err != nil {
+		return err
+	}
+
+	return doc.WriteFile(filePath)
+}
+
+// Marshal serializes the document as JSON or YAML
+func (doc *Document) Marshal(asYAML bool) ([]byte, error) {
+	if asYAML {
+		return yaml.Marshal(doc)
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// WriteFile writes the document to file, files with a .yaml or .yml extension are written as YAML
+func (doc *Document) WriteFile(filePath string) error {
+	data, err := doc.Marshal(isYAML(filePath))
+	if err != nil {
+		return fmt.Errorf("failed to serialize patch document %s: %w", filePath, err)
+	}
+
+	return os.WriteFile(filePath, data, 0666)
+}
+
+// identify returns a unique document identifier for an object
+func (e *exporter) identify(obj any, id string, prefix string) string {
+	if id == "" || e.used[id] {
+		base := id
+		if base == "" {
+			base = prefix
+		}
+
+		for n := 1; ; n++ {
+			candidate := fmt.Sprintf("%s%d", base, n)
+			if !e.used[candidate] {
+				id = candidate
+				break
+			}
+		}
+	}
+
+	e.used[id] = true
+	e.ids[obj] = id
+
+	if ctrl, ok := obj.(muse.Control); ok {
+		e.controls[ctrl] = id
+	}
+
+	return id
+}
+
+func (e *exporter) exportObject(obj muse.Control, prefix string) (*Object, error) {
+	id := e.identify(obj, obj.Identifier(), prefix)
+
+	typeName, params, err := e.reg.Export(obj)
+	if err != nil {
+		return nil, fmt.Errorf("object %q: %w", id, err)
+	}
+
+	if len(params) == 0 {
+		params = nil
+	}
+
+	return &Object{ID: id, Type: typeName, Params: params, Tags: e.p.Tags(obj.Identifier())}, nil
+}
+
+func (e *exporter) exportObjects() error {
+	for _, m := range e.p.Modules() {
+		if e.thrus[m] {
+			// Skip the input and output thru modules of the patch itself
+			continue
+		}
+
+		if sub, ok := m.(*muse.BasePatch); ok {
+			id := e.identify(sub, sub.Identifier(), "patch")
+
+			subDoc, err := Export(sub, e.reg)
+			if err != nil {
+				return fmt.Errorf("sub patch %q: %w", id, err)
+			}
+
+			subDoc.ID = id
+			subDoc.Tags = e.p.Tags(sub.Identifier())
+			e.doc.Patches = append(e.doc.Patches, subDoc)
+		} else {
+			obj, err := e.exportObject(m, "module")
+			if err != nil {
+				return err
+			}
+
+			e.doc.Modules = append(e.doc.Modules, obj)
+		}
+
+		if sub, ok := m.(muse.Patch); ok {
+			// Connections into a sub patch end at its input thru modules
+			for i := 0; i < sub.NumInputs(); i++ {
+				e.targets[sub.InputModuleAtIndex(i)] = endpoint{address: e.ids[m], index: i}
+			}
+		} else {
+			e.targets[m] = endpoint{address: e.ids[m], index: -1}
+		}
+	}
+
+	for _, msgr := range e.p.Messengers() {
+		obj, err := e.exportObject(msgr, "messenger")
+		if err != nil {
+			return err
+		}
+
+		e.doc.Messengers = append(e.doc.Messengers, obj)
+	}
+
+	for _, ctrl := range e.p.Controls() {
+		obj, err := e.exportObject(ctrl, "control")
+		if err != nil {
+			return err
+		}
+
+		e.doc.Controls = append(e.doc.Controls, obj)
+	}
+
+	return nil
+}
+
+func (e *exporter) exportSocket(from string, outIndex int, socket *muse.Socket) error {
+	for _, conn := range socket.Connections {
+		to, ok := e.targets[conn.Module]
+		if !ok {
+			return fmt.Errorf("connection %s:%d leaves patch %q", from, outIndex, e.doc.ID)
+		}
+
+		inIndex := to.index
+		if inIndex < 0 {
+			inIndex = conn.Index
+		}
+
+		e.doc.Connections = append(e.doc.Connections, &Connection{
+			From:     from,
+			Out:      outIndex,
+			To:       to.address,
+			In:       inIndex,
+			Feedback: conn.IsFeedback(),
+		})
+	}
+
+	return nil
+}
+
+func (e *exporter) exportConnections() error {
+	for i := 0; i < e.p.NumInputs(); i++ {
+		err := e.exportSocket(SelfAddress, i, e.p.InputModuleAtIndex(i).OutputAtIndex(0))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, m := range e.p.Modules() {
+		if e.thrus[m] {
+			continue
+		}
+
+		for i := 0; i < m.NumOutputs(); i++ {
+			err := e.exportSocket(e.ids[m], i, m.OutputAtIndex(i))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (e *exporter) exportControlOutputs(from string, ctrl muse.Control) error {
+	conns := ctrl.CtrlOutputConnections()
+
+	outIndices := make([]int, 0, len(conns))
+	for outIndex := range conns {
+		outIndices = append(outIndices, outIndex)
+	}
+
+	sort.Ints(outIndices)
+
+	for _, outIndex := range outIndices {
+		for _, conn := range conns[outIndex] {
+			to, ok := e.controls[conn.Control]
+			if !ok {
+				if conn.Control != e.p.InternalOutputControl() {
+					return fmt.Errorf("control connection %s:%d leaves patch %q", from, outIndex, e.doc.ID)
+				}
+
+				to = SelfAddress
+			}
+
+			e.doc.ControlConnections = append(e.doc.ControlConnections, &Connection{From: from, Out: outIndex, To: to, In: conn.Index})
+		}
+	}
+
+	return nil
+}
+
+func (e *exporter) exportControlConnections() error {
+	err := e.exportControlOutputs(SelfAddress, e.p.InternalInputControl())
+	if err != nil {
+		return err
+	}
+
+	var senders []muse.Control
+
+	for _, m := range e.p.Modules() {
+		if !e.thrus[m] {
+			senders = append(senders, m)
+		}
+	}
+
+	for _, msgr := range e.p.Messengers() {
+		senders = append(senders, msgr)
+	}
+
+	senders = append(senders, e.p.Controls()...)
+
+	for _, sender := range senders {
+		err = e.exportControlOutputs(e.ids[sender], sender)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package patchdoc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/almerlucke/muse/utils"
+)
+
+// Params holds the constructor parameters of a document object, numbers can be
+// given as any Go numeric type so both JSON and YAML decoded values are accepted
+type Params map[string]any
+
+// get returns the raw value at key, null values count as missing
+func (p Params) get(key string) (any, bool) {
+	raw, ok := p[key]
+	return raw, ok && raw != nil
+}
+
+func (p Params) Has(key string) bool {
+	_, ok := p.get(key)
+	return ok
+}
+
+func (p Params) Float(key string, def float64) (float64, error) {
+	raw, ok := p.get(key)
+	if !ok {
+		return def, nil
+	}
+
+	f, ok := utils.ToFloat(raw)
+	if !ok {
+		return def, fmt.Errorf("parameter %q: expected number, got %T", key, raw)
+	}
+
+	return f, nil
+}
+
+func (p Params) Int(key string, def int) (int, error) {
+	raw, ok := p.get(key)
+	if !ok {
+		return def, nil
+	}
+
+	i, ok := utils.ToInt(raw)
+	if !ok {
+		return def, fmt.Errorf("parameter %q: expected integer, got %v", key, raw)
+	}
+
+	return i, nil
+}
+
+func (p Params) Bool(key string, def bool) (bool, error) {
+	raw, ok := p.get(key)
+	if !ok {
+		return def, nil
+	}
+
+	b, ok := raw.(bool)
+	if !ok {
+		return def, fmt.Errorf("parameter %q: expected bool, got %T", key, raw)
+	}
+
+	return b, nil
+}
+
+func (p Params) String(key string, def string) (string, error) {
+	raw, ok := p.get(key)
+	if !ok {
+		return def, nil
+	}
+
+	s, ok := raw.(string)
+	if !ok {
+		return def, fmt.Errorf("parameter %q: expected string, got %T", key, raw)
+	}
+
+	return s, nil
+}
+
+func (p Params) Floats(key string, def []float64) ([]float64, error) {
+	raw, ok := p.get(key)
+	if !ok {
+		return def, nil
+	}
+
+	if fs, ok := raw.([]float64); ok {
+		return fs, nil
+	}
+
+	list, ok := raw.([]any)
+	if !ok {
+		return def, fmt.Errorf("parameter %q: expected list of numbers, got %T", key, raw)
+	}
+
+	fs := make([]float64, len(list))
+	for i, elem := range list {
+		f, ok := utils.ToFloat(elem)
+		if !ok {
+			return def, fmt.Errorf("parameter %q: expected number at index %d, got %T", key, i, elem)
+		}
+		fs[i] = f
+	}
+
+	return fs, nil
+}
+
+func (p Params) Strings(key string, def []string) ([]string, error) {
+	raw, ok := p.get(key)
+	if !ok {
+		return def, nil
+	}
+
+	if ss, ok := raw.([]string); ok {
+		return ss, nil
+	}
+
+	list, ok := raw.([]any)
+	if !ok {
+		return def, fmt.Errorf("parameter %q: expected list of strings, got %T", key, raw)
+	}
+
+	ss := make([]string, len(list))
+	for i, elem := range list {
+		s, ok := elem.(string)
+		if !ok {
+			return def, fmt.Errorf("parameter %q: expected string at index %d, got %T", key, i, elem)
+		}
+		ss[i] = s
+	}
+
+	return ss, nil
+}
+
+// Decode decodes the parameter at key into v by round tripping through JSON,
+// useful for struct parameters like envelope settings
+func (p Params) Decode(key string, v any) error {
+	raw, ok := p.get(key)
+	if !ok {
+		return nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return fmt.Errorf("parameter %q: %w", key, err)
+	}
+
+	err = json.Unmarshal(data, v)
+	if err != nil {
+		return fmt.Errorf("parameter %q: %w", key, err)
+	}
+
+	return nil
+}
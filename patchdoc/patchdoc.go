@@ -0,0 +1,319 @@
+package patchdoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/almerlucke/muse"
+	"gopkg.in/yaml.v3"
+)
+
+// SelfAddress refers to the patch being built in a connection, as source it
+// addresses the patch inputs and as destination the patch outputs
+const SelfAddress = "self"
+
+type Object struct {
+	ID     string `json:"id,omitempty" yaml:"id,omitempty"`
+	Type   string `json:"type" yaml:"type"`
+	Params Params `json:"params,omitempty" yaml:"params,omitempty"`
+	// Tags address the object together with others as "#tag" (see muse.BasePatch.Tag)
+	Tags []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+// Connection connects output Out of From to input In of To, feedback connections
+// are delayed by one block and may form cycles (see muse.Module.ConnectFeedback).
+// If Param is set the connection modulates that parameter of To and In is ignored
+// (see muse.Modulatable)
+type Connection struct {
+	From     string `json:"from" yaml:"from"`
+	Out      int    `json:"out" yaml:"out"`
+	To       string `json:"to" yaml:"to"`
+	In       int    `json:"in" yaml:"in"`
+	Param    string `json:"param,omitempty" yaml:"param,omitempty"`
+	Feedback bool   `json:"feedback,omitempty" yaml:"feedback,omitempty"`
+}
+
+// Document describes a patch, sub-patches are described by nested documents
+// and can be addressed from connections with dotted identifiers (see muse.Patch.Lookup)
+type Document struct {
+	ID                 string        `json:"id,omitempty" yaml:"id,omitempty"`
+	Tags               []string      `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Inputs             int           `json:"inputs" yaml:"inputs"`
+	Outputs            int           `json:"outputs" yaml:"outputs"`
+	Modules            []*Object     `json:"modules,omitempty" yaml:"modules,omitempty"`
+	Messengers         []*Object     `json:"messengers,omitempty" yaml:"messengers,omitempty"`
+	Controls           []*Object     `json:"controls,omitempty" yaml:"controls,omitempty"`
+	Patches            []*Document   `json:"patches,omitempty" yaml:"patches,omitempty"`
+	Connections        []*Connection `json:"connections,omitempty" yaml:"connections,omitempty"`
+	ControlConnections []*Connection `json:"controlConnections,omitempty" yaml:"controlConnections,omitempty"`
+}
+
+func isYAML(filePath string) bool {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// Parse parses a JSON or YAML document
+func Parse(data []byte, asYAML bool) (*Document, error) {
+	var (
+		doc Document
+		err error
+	)
+
+	if asYAML {
+		err = yaml.Unmarshal(data, &doc)
+	} else {
+		err = json.Unmarshal(data, &doc)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &doc, nil
+}
+
+// ReadFile reads a document from file, files with a .yaml or .yml extension are parsed as YAML
+func ReadFile(filePath string) (*Document, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := Parse(data, isYAML(filePath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse patch document %s: %w", filePath, err)
+	}
+
+	return doc, nil
+}
+
+// Load reads a document from file and builds the patch with the default registry
+func Load(filePath string) (*muse.BasePatch, error) {
+	doc, err := ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return doc.Build(DefaultRegistry)
+}
+
+// Build creates a new patch from the document
+func (doc *Document) Build(reg *Registry) (*muse.BasePatch, error) {
+	p := muse.NewPatch(doc.Inputs, doc.Outputs)
+	p.SetIdentifier(doc.ID)
+
+	err := doc.BuildInto(p, reg)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// BuildInto adds all objects and connections of the document to an existing patch,
+// for instance a *muse.Muse, the patch must have enough inputs and outputs
+func (doc *Document) BuildInto(p muse.Patch, reg *Registry) error {
+	if reg == nil {
+		reg = DefaultRegistry
+	}
+
+	if p.NumInputs() < doc.Inputs || p.NumOutputs() < doc.Outputs {
+		return fmt.Errorf("patch %q needs %d inputs and %d outputs", doc.ID, doc.Inputs, doc.Outputs)
+	}
+
+	for _, sub := range doc.Patches {
+		sp, err := sub.Build(reg)
+		if err != nil {
+			return fmt.Errorf("sub patch %q: %w", sub.ID, err)
+		}
+
+		p.AddModule(sp)
+		tag(p, sub.ID, sub.Tags)
+	}
+
+	for _, obj := range doc.Modules {
+		v, err := obj.create(reg)
+		if err != nil {
+			return err
+		}
+
+		m, ok := v.(muse.Module)
+		if !ok {
+			return fmt.Errorf("object %q of type %s is not a module", obj.ID, obj.Type)
+		}
+
+		p.AddModule(m)
+		tag(p, obj.ID, obj.Tags)
+	}
+
+	for _, obj := range doc.Messengers {
+		v, err := obj.create(reg)
+		if err != nil {
+			return err
+		}
+
+		msgr, ok := v.(muse.Messenger)
+		if !ok {
+			return fmt.Errorf("object %q of type %s is not a messenger", obj.ID, obj.Type)
+		}
+
+		p.AddMessenger(msgr)
+		tag(p, obj.ID, obj.Tags)
+	}
+
+	for _, obj := range doc.Controls {
+		v, err := obj.create(reg)
+		if err != nil {
+			return err
+		}
+
+		ctrl, ok := v.(muse.Control)
+		if !ok {
+			return fmt.Errorf("object %q of type %s is not a control", obj.ID, obj.Type)
+		}
+
+		p.AddControl(ctrl)
+		tag(p, obj.ID, obj.Tags)
+	}
+
+	for _, conn := range doc.Connections {
+		err := connect(p, conn)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, conn := range doc.ControlConnections {
+		err := ctrlConnect(p, conn)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func tag(p muse.Patch, id string, tags []string) {
+	if id != "" && len(tags) > 0 {
+		p.Tag(id, tags...)
+	}
+}
+
+func (obj *Object) create(reg *Registry) (any, error) {
+	v, err := reg.New(obj.Type, obj.Params)
+	if err != nil {
+		return nil, fmt.Errorf("object %q: %w", obj.ID, err)
+	}
+
+	if ident, ok := v.(muse.Identifiable); ok {
+		ident.SetIdentifier(obj.ID)
+	}
+
+	return v, nil
+}
+
+func lookupModule(p muse.Patch, address string) (muse.Module, error) {
+	if address == SelfAddress {
+		return p, nil
+	}
+
+	m, ok := p.Lookup(address).(muse.Module)
+	if !ok {
+		return nil, fmt.Errorf("no module found at address %q", address)
+	}
+
+	return m, nil
+}
+
+func lookupControl(p muse.Patch, address string, asSender bool) (muse.Control, error) {
+	if address == SelfAddress {
+		if asSender {
+			return p.InternalInputControl(), nil
+		}
+
+		return p.InternalOutputControl(), nil
+	}
+
+	ctrl, ok := p.Lookup(address).(muse.Control)
+	if !ok {
+		return nil, fmt.Errorf("no control found at address %q", address)
+	}
+
+	return ctrl, nil
+}
+
+func connect(p muse.Patch, conn *Connection) error {
+	from, err := lookupModule(p, conn.From)
+	if err != nil {
+		return err
+	}
+
+	to, err := lookupModule(p, conn.To)
+	if err != nil {
+		return err
+	}
+
+	numOutputs := from.NumOutputs()
+	if from == p {
+		numOutputs = p.NumInputs()
+	}
+
+	numInputs := to.NumInputs()
+	if to == p {
+		numInputs = p.NumOutputs()
+	}
+
+	outIndex := conn.Out
+	inIndex := conn.In
+
+	if conn.Param != "" {
+		var ok bool
+
+		inIndex, ok = muse.ModulationInputIndex(to, conn.Param)
+		if !ok {
+			return fmt.Errorf("connection %s:%d -> %s:%s: %w", conn.From, conn.Out, conn.To, conn.Param, muse.ErrUnknownParameter)
+		}
+	}
+
+	if outIndex < 0 || outIndex >= numOutputs || inIndex < 0 || inIndex >= numInputs {
+		return fmt.Errorf("connection %s:%d -> %s:%d: index out of range", conn.From, conn.Out, conn.To, inIndex)
+	}
+
+	if from == p {
+		// Patch inputs are routed through the input thru modules
+		from = p.InputModuleAtIndex(outIndex)
+		outIndex = 0
+	}
+
+	if to == p {
+		// Patch outputs are routed through the output thru modules
+		to = p.OutputModuleAtIndex(inIndex)
+		inIndex = 0
+	}
+
+	if conn.Feedback {
+		return from.ConnectFeedback(outIndex, to, inIndex)
+	}
+
+	return from.TryConnect(outIndex, to, inIndex)
+}
+
+func ctrlConnect(p muse.Patch, conn *Connection) error {
+	from, err := lookupControl(p, conn.From, true)
+	if err != nil {
+		return err
+	}
+
+	to, err := lookupControl(p, conn.To, false)
+	if err != nil {
+		return err
+	}
+
+	from.CtrlConnect(conn.Out, to, conn.In)
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package patchdoc_test
+
+import (
+	"bytes"
+	"testing"
+
+	adsrc "github.com/almerlucke/genny/float/envelopes/adsr"
+	"github.com/almerlucke/muse"
+	"github.com/almerlucke/muse/modules/adsr"
+	"github.com/almerlucke/muse/modules/osc"
+	"github.com/almerlucke/muse/patchdoc"
+	_ "github.com/almerlucke/muse/patchdoc/builtin"
+)
+
+const testDocument = `
+inputs: 0
+outputs: 2
+patches:
+  - id: voice
+    outputs: 1
+    modules:
+      - {id: osc, type: osc.Osc2, params: {frequency: 110.0, waveform: 5, amplitude: 0.5}}
+      - {id: lfo, type: lfo, params: {frequency: 0.2, min: 200.0, max: 2400.0}}
+      - {id: filter, type: filters.moog, params: {frequency: 1200.0, resonance: 0.4}}
+    connections:
+      - {from: osc, out: 0, to: filter, in: 0}
+      - {from: lfo, out: 0, to: filter, in: 1}
+      - {from: filter, out: 0, to: self, in: 0}
+modules:
+  - {id: mix, type: mixer, params: {numInputs: 2, mix: [0.8, 0.3]}, tags: [out]}
+  - {id: echo, type: delay, params: {length: 100.0, location: 20.0}}
+  - {id: reverb, type: effects.freeverb, params: {roomSize: 0.8, wet: 0.2, dry: 0.7}}
+connections:
+  - {from: voice, out: 0, to: mix, in: 0}
+  - {from: mix, out: 0, to: echo, in: 0}
+  - {from: echo, out: 0, to: mix, in: 1, feedback: true}
+  - {from: mix, out: 0, to: reverb, in: 0}
+  - {from: mix, out: 0, to: reverb, in: 1}
+  - {from: reverb, out: 0, to: self, in: 0}
+  - {from: reverb, out: 1, to: self, in: 1}
+`
+
+// build builds a document into a new muse
+func build(t *testing.T, doc *patchdoc.Document) *muse.Muse {
+	t.Helper()
+
+	root := muse.New(doc.Outputs)
+
+	if err := doc.BuildInto(root, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	return root
+}
+
+// export exports a patch and serializes the document as JSON
+func export(t *testing.T, p muse.Patch) (*patchdoc.Document, []byte) {
+	t.Helper()
+
+	doc, err := patchdoc.Export(p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := doc.Marshal(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return doc, data
+}
+
+// render synthesizes blocks of the patch and returns its first output
+func render(root *muse.Muse, blocks int) []float64 {
+	var output []float64
+
+	for i := 0; i < blocks; i++ {
+		root.Synthesize()
+		output = append(output, root.OutputAtIndex(0).Buffer...)
+	}
+
+	return output
+}
+
+func TestRoundTrip(t *testing.T) {
+	doc, err := patchdoc.Parse([]byte(testDocument), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := build(t, doc)
+	exported, data := export(t, first)
+
+	// The exported document is read back as JSON and builds the same patch
+	parsed, err := patchdoc.Parse(data, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second := build(t, parsed)
+
+	if _, again := export(t, second); !bytes.Equal(again, data) {
+		t.Fatalf("exports differ:\n%s\n%s", data, again)
+	}
+
+	if len(exported.Patches) != 1 || len(exported.Modules) != 3 || len(exported.Connections) != 7 {
+		t.Fatalf("exported %d patches, %d modules and %d connections", len(exported.Patches), len(exported.Modules), len(exported.Connections))
+	}
+
+	if tags := second.Tags("mix"); len(tags) != 1 || tags[0] != "out" {
+		t.Fatalf("expected the mixer to be tagged out, got %v", tags)
+	}
+
+	firstOutput := render(first, 16)
+	secondOutput := render(second, 16)
+
+	silent := true
+
+	for i := range firstOutput {
+		if firstOutput[i] != secondOutput[i] {
+			t.Fatalf("outputs differ at frame %d: %v and %v", i, firstOutput[i], secondOutput[i])
+		}
+
+		if firstOutput[i] != 0 {
+			silent = false
+		}
+	}
+
+	if silent {
+		t.Fatal("the patch rendered silence")
+	}
+}
+
+func TestExportLiveParameters(t *testing.T) {
+	doc, err := patchdoc.Parse([]byte(testDocument), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := build(t, doc)
+	root.Lookup("voice.osc").(*osc.Osc2).ReceiveControlValue(220.0, 0)
+
+	exported, _ := export(t, root)
+
+	params := exported.Patches[0].Modules[0].Params
+	if params["frequency"] != 220.0 || params["amplitude"] != 0.5 {
+		t.Fatalf("exported %v, expected the live frequency and the created amplitude", params)
+	}
+}
+
+func TestExportWithoutRegistry(t *testing.T) {
+	root := muse.New(1)
+
+	setting := adsrc.NewSetting(1.0, 10.0, 0.5, 20.0, 0.0, 30.0)
+	env := adsr.New(setting, adsrc.NoteOff, 0.8).Named("env").AddTo(root)
+	root.In(osc.NewOsc2(440.0, 0, 0.5, 1.0, osc.SAWTOOTH).Named("osc").AddTo(root))
+
+	exported, data := export(t, root)
+
+	types := map[string]string{}
+	for _, obj := range exported.Modules {
+		types[obj.ID] = obj.Type
+	}
+
+	if types["env"] != "adsr" || types["osc"] != "osc.Osc2" {
+		t.Fatalf("exported types %v", types)
+	}
+
+	parsed, err := patchdoc.Parse(data, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rebuilt := build(t, parsed)
+
+	built := rebuilt.Lookup("env").(*adsr.ADSR)
+	if *built.Setting() != *setting || built.ReleaseMode() != env.(*adsr.ADSR).ReleaseMode() || built.Level() != 0.8 {
+		t.Fatalf("rebuilt envelope with setting %+v, release mode %v and level %v", *built.Setting(), built.ReleaseMode(), built.Level())
+	}
+}
@@ -0,0 +1,141 @@
+package patchdoc
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Factory creates a module, messenger or control from document parameters
+type Factory func(params Params) (any, error)
+
+// Exporter adds the current parameters of obj to params, params already holds
+// the parameters obj was created with if obj was created by the registry
+type Exporter func(obj any, params Params)
+
+// origin remembers the type and parameters an object was created with
+type origin struct {
+	typeName string
+	params   Params
+}
+
+// originHolder is implemented by objects that store their origin, all objects based on
+// muse.BaseControl do. The origin is stored on the object so it does not outlive the object
+type originHolder interface {
+	Origin() any
+	SetOrigin(origin any)
+}
+
+type Registry struct {
+	factories map[string]Factory
+	exporters map[string]Exporter
+	types     map[reflect.Type]string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		factories: map[string]Factory{},
+		exporters: map[string]Exporter{},
+		types:     map[reflect.Type]string{},
+	}
+}
+
+// DefaultRegistry is used by Load and Build when no registry is given, import
+// github.com/almerlucke/muse/patchdoc/builtin to register all built-in types
+var DefaultRegistry = NewRegistry()
+
+func Register(typeName string, factory Factory) {
+	DefaultRegistry.Register(typeName, factory)
+}
+
+func RegisterExporter(typeName string, prototype any, exporter Exporter) {
+	DefaultRegistry.RegisterExporter(typeName, prototype, exporter)
+}
+
+func (r *Registry) Register(typeName string, factory Factory) {
+	r.factories[typeName] = factory
+}
+
+// RegisterExporter registers an exporter for a type name, objects with the same
+// Go type as prototype are exported as typeName even when they were not created by the registry
+func (r *Registry) RegisterExporter(typeName string, prototype any, exporter Exporter) {
+	r.exporters[typeName] = exporter
+
+	if prototype != nil {
+		r.types[reflect.TypeOf(prototype)] = typeName
+	}
+}
+
+func (r *Registry) Has(typeName string) bool {
+	_, ok := r.factories[typeName]
+	return ok
+}
+
+// New creates an object of a registered type, objects that hold an origin remember the type
+// and parameters so Export can return them
+func (r *Registry) New(typeName string, params Params) (any, error) {
+	factory, ok := r.factories[typeName]
+	if !ok {
+		return nil, fmt.Errorf("unknown type %q", typeName)
+	}
+
+	if params == nil {
+		params = Params{}
+	}
+
+	v, err := factory(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if holder, ok := v.(originHolder); ok {
+		holder.SetOrigin(&origin{typeName: typeName, params: params})
+	}
+
+	return v, nil
+}
+
+// Export returns the type name and current parameters of an object
+func (r *Registry) Export(obj any) (string, Params, error) {
+	var (
+		typeName string
+		params   = Params{}
+	)
+
+	var org *origin
+
+	if holder, ok := obj.(originHolder); ok {
+		org, _ = holder.Origin().(*origin)
+	}
+
+	if org != nil {
+		typeName = org.typeName
+		for key, value := range org.params {
+			params[key] = value
+		}
+	} else {
+		var ok bool
+		typeName, ok = r.types[reflect.TypeOf(obj)]
+		if !ok {
+			return "", nil, fmt.Errorf("no registered type for %T", obj)
+		}
+	}
+
+	if exporter := r.exporters[typeName]; exporter != nil {
+		exporter(obj, params)
+	}
+
+	return typeName, params, nil
+}
+
+// Types returns all registered type names in sorted order
+func (r *Registry) Types() []string {
+	types := make([]string, 0, len(r.factories))
+	for typeName := range r.factories {
+		types = append(types, typeName)
+	}
+
+	sort.Strings(types)
+
+	return types
+}
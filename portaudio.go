@@ -0,0 +1,171 @@
+package muse
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gordonklaus/portaudio"
+)
+
+// PortAudioBackend plays through the sound cards of the system with PortAudio
+type PortAudioBackend struct {
+	stream *portaudio.Stream
+}
+
+func NewPortAudioBackend() *PortAudioBackend {
+	return &PortAudioBackend{}
+}
+
+func (pa *PortAudioBackend) Devices() ([]AudioDevice, error) {
+	err := portaudio.Initialize()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = portaudio.Terminate()
+	}()
+
+	devices, err := portaudio.Devices()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]AudioDevice, len(devices))
+
+	for i, device := range devices {
+		result[i] = AudioDevice{
+			Name:              device.Name,
+			MaxInputChannels:  device.MaxInputChannels,
+			MaxOutputChannels: device.MaxOutputChannels,
+			DefaultSampleRate: device.DefaultSampleRate,
+			SampleRates:       portAudioSampleRates(device),
+		}
+
+		if device.HostApi != nil {
+			result[i].HostAPI = device.HostApi.Name
+		}
+	}
+
+	return result, nil
+}
+
+// portAudioSampleRates returns the common sample rates the device supports
+func portAudioSampleRates(device *portaudio.DeviceInfo) []float64 {
+	var (
+		rates []float64
+		in    *portaudio.DeviceInfo
+		out   *portaudio.DeviceInfo
+	)
+
+	if device.MaxInputChannels > 0 {
+		in = device
+	}
+
+	if device.MaxOutputChannels > 0 {
+		out = device
+	}
+
+	for _, rate := range CommonSampleRates {
+		params := portaudio.HighLatencyParameters(in, out)
+		params.SampleRate = rate
+
+		if portaudio.IsFormatSupported(params, func(in, out [][]float32) {}) == nil {
+			rates = append(rates, rate)
+		}
+	}
+
+	return rates
+}
+
+// portAudioDevice returns the device with name, the default device if name is empty or nil if numChannels is 0
+func portAudioDevice(name string, numChannels int, input bool) (*portaudio.DeviceInfo, error) {
+	if numChannels == 0 {
+		return nil, nil
+	}
+
+	if name == "" {
+		if input {
+			return portaudio.DefaultInputDevice()
+		}
+
+		return portaudio.DefaultOutputDevice()
+	}
+
+	devices, err := portaudio.Devices()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, device := range devices {
+		if strings.EqualFold(device.Name, name) {
+			return device, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrNoAudioDevice, name)
+}
+
+func (pa *PortAudioBackend) Open(params AudioStreamParameters, callback AudioCallback) error {
+	if pa.stream != nil {
+		return errors.New("portaudio stream is already open")
+	}
+
+	err := portaudio.Initialize()
+	if err != nil {
+		return err
+	}
+
+	in, err := portAudioDevice(params.InputDevice, params.NumInputs, true)
+	if err == nil {
+		var out *portaudio.DeviceInfo
+
+		out, err = portAudioDevice(params.OutputDevice, params.NumOutputs, false)
+		if err == nil {
+			streamParams := portaudio.HighLatencyParameters(in, out)
+			streamParams.Input.Channels = params.NumInputs
+			streamParams.Output.Channels = params.NumOutputs
+			streamParams.SampleRate = params.SampleRate
+			streamParams.FramesPerBuffer = params.BufferSize
+
+			pa.stream, err = portaudio.OpenStream(streamParams, func(in, out [][]float32) {
+				callback(in, out)
+			})
+		}
+	}
+
+	if err != nil {
+		_ = portaudio.Terminate()
+		return err
+	}
+
+	return nil
+}
+
+func (pa *PortAudioBackend) Start() error {
+	if pa.stream == nil {
+		return errors.New("portaudio stream is not open")
+	}
+
+	return pa.stream.Start()
+}
+
+func (pa *PortAudioBackend) Stop() error {
+	if pa.stream == nil {
+		return nil
+	}
+
+	return pa.stream.Stop()
+}
+
+func (pa *PortAudioBackend) Close() error {
+	if pa.stream == nil {
+		return nil
+	}
+
+	err := pa.stream.Close()
+	pa.stream = nil
+
+	return errors.Join(err, portaudio.Terminate())
+}
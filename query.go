@@ -0,0 +1,153 @@
+package muse
+
+// Query commands are answered by the patch that delivers them instead of by the addressed receiver, so
+// every receiver that describes its parameters can be queried:
+//
+//	{"command": "get", "replyTo": "ui", "parameter": "frequency"}
+//	{"command": "get", "replyTo": "ui", "parameters": ["frequency", "resonance"]}
+//	{"command": "dump", "replyTo": "ui", "id": 12}
+//
+// The reply is sent to the replyTo address:
+//
+//	{"command": "reply", "query": "get", "address": "voice1.filter", "values": {"frequency": 1200.0}, "id": 12}
+//
+// get without parameters returns all parameter values of the receiver, dump also returns the values of all
+// receivers inside a sub patch with dotted names like "filter.frequency". An optional id is echoed in the reply
+const (
+	CommandGet   = "get"
+	CommandDump  = "dump"
+	CommandReply = "reply"
+)
+
+// ParameterValues returns the current values of the parameters of rcvr by name, parameters without
+// state like bangs are left out
+func ParameterValues(rcvr any) map[string]any {
+	values := map[string]any{}
+
+	for _, param := range ParametersOf(rcvr) {
+		if param.Value != nil {
+			values[param.Name] = param.Value
+		}
+	}
+
+	return values
+}
+
+// Dump returns the parameter values of rcvr, if rcvr is a patch the values of all receivers inside it
+// are added recursively with their dotted address relative to rcvr as prefix
+func Dump(rcvr any) map[string]any {
+	values := ParameterValues(rcvr)
+
+	if lookup, ok := rcvr.(patchLookup); ok {
+		lookup.forEachReceiver(func(identifier string, sub MessageReceiver) {
+			for name, value := range Dump(sub) {
+				values[identifier+"."+name] = value
+			}
+		})
+	}
+
+	return values
+}
+
+// queryCommand returns the query command of msg if msg is a get or dump query
+func queryCommand(msg any) (string, bool) {
+	values, ok := msg.(map[string]any)
+	if !ok {
+		return "", false
+	}
+
+	command, _ := values["command"].(string)
+
+	return command, command == CommandGet || command == CommandDump
+}
+
+// answerQuery returns the reply to a get or dump query for rcvr at address
+func answerQuery(address string, rcvr any, msg map[string]any, command string) []*Message {
+	content := Content{receiver: rcvr, values: msg}
+
+	if !content.Require("replyTo") {
+		return nil
+	}
+
+	replyTo, ok := content.String("replyTo")
+	if !ok {
+		return nil
+	}
+
+	var values map[string]any
+
+	if command == CommandDump {
+		values = Dump(rcvr)
+	} else if names, ok := queryParameters(content); !ok {
+		return nil
+	} else if len(names) == 0 {
+		values = ParameterValues(rcvr)
+	} else {
+		// Names can address parameters inside a sub patch
+		all := Dump(rcvr)
+		values = make(map[string]any, len(names))
+
+		for _, name := range names {
+			value, ok := all[name]
+			if !ok {
+				content.Invalid("parameters", "unknown parameter "+name)
+				continue
+			}
+
+			values[name] = value
+		}
+	}
+
+	reply := map[string]any{
+		"command": CommandReply,
+		"query":   command,
+		"address": address,
+		"values":  values,
+	}
+
+	if id, ok := content.Value("id"); ok {
+		reply["id"] = id
+	}
+
+	return []*Message{NewMessage(replyTo, reply)}
+}
+
+// queryParameters returns the parameter names of a get query, empty if all parameters are requested
+func queryParameters(content Content) ([]string, bool) {
+	if content.Has("parameter") {
+		name, ok := content.String("parameter")
+		if !ok {
+			return nil, false
+		}
+
+		return []string{name}, true
+	}
+
+	raw, ok := content.Value("parameters")
+	if !ok {
+		return nil, true
+	}
+
+	switch list := raw.(type) {
+	case []string:
+		return list, true
+	case []any:
+		names := make([]string, 0, len(list))
+
+		for _, item := range list {
+			name, ok := item.(string)
+			if !ok {
+				content.Invalid("parameters", "expected list of strings")
+				return nil, false
+			}
+
+			names = append(names, name)
+		}
+
+		return names, true
+	}
+
+	content.Invalid("parameters", "expected list of strings")
+
+	return nil, false
+}
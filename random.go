@@ -0,0 +1,66 @@
+package muse
+
+import (
+	"fmt"
+	mathrand "math/rand"
+
+	"github.com/almerlucke/muse/utils/rand"
+)
+
+// Seeder is implemented by objects with a random source, objects that contain other objects with random
+// sources pass a derived seed on to them (see SeedObjects)
+type Seeder interface {
+	SetSeed(seed uint64)
+}
+
+// SeedObjects seeds the objects that implement Seeder, each object gets a seed derived from seed and its
+// position so the objects do not produce the same random values
+func SeedObjects(seed uint64, objects ...any) {
+	for i, obj := range objects {
+		if seeder, ok := obj.(Seeder); ok {
+			seeder.SetSeed(rand.DeriveSeed(seed, fmt.Sprint(i)))
+		}
+	}
+}
+
+// SetSeed seeds all modules, messengers and controls of the patch and its sub patches. Every object gets a seed
+// derived from seed and its identifier, objects without identifier are identified by their position in the
+// patch. Objects with an identifier keep their seed when other objects are added to or removed from the patch
+func (p *BasePatch) SetSeed(seed uint64) {
+	seedAll := func(kind string, i int, obj Identifiable) {
+		seeder, ok := obj.(Seeder)
+		if !ok {
+			return
+		}
+
+		key := obj.Identifier()
+		if key == "" {
+			key = fmt.Sprintf("%s#%d", kind, i)
+		}
+
+		seeder.SetSeed(rand.DeriveSeed(seed, key))
+	}
+
+	for i, module := range p.subModules {
+		seedAll("module", i, module)
+	}
+
+	for i, msgr := range p.messengers {
+		seedAll("messenger", i, msgr)
+	}
+
+	for i, ctrl := range p.controls {
+		seedAll("control", i, ctrl)
+	}
+}
+
+// SetSeed seeds all random sources of the muse. The generators of genny (walk, bucket, markov, sequence, and,
+// arpeggio) have no source of their own and draw from the global math/rand source, it gets a seed derived from
+// seed so patches that use them render the same for the same seed. This only holds if nothing else draws from
+// the global source while the muse renders, and not for values generators draw when they are created (walk
+// picks its start position in New), set the seed before creating those generators
+func (m *Muse) SetSeed(seed uint64) {
+	mathrand.Seed(int64(rand.DeriveSeed(seed, "math/rand")))
+
+	m.BasePatch.SetSeed(seed)
+}
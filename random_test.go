@@ -0,0 +1,72 @@
+package muse_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/almerlucke/genny/bucket"
+	"github.com/almerlucke/muse"
+	"github.com/almerlucke/muse/controls/gen"
+	"github.com/almerlucke/muse/messengers/triggers/stepper"
+	"github.com/almerlucke/muse/messengers/triggers/stepper/swing"
+	"github.com/almerlucke/muse/modules/mixer"
+	"github.com/almerlucke/muse/modules/noise"
+	"github.com/almerlucke/muse/modules/osc"
+)
+
+// renderSeeded builds a patch with random sources of the engine and of genny generators, seeds it and
+// renders blocks of its output
+func renderSeeded(seed uint64, blocks int) []float64 {
+	root := muse.New(1)
+
+	o := osc.NewOsc2(220.0, 0, 0.5, 1.0, osc.RECTANGLE).AddTo(root)
+	n := noise.New(1).AddTo(root)
+
+	mix := mixer.New(2)
+	mix.SetMix([]float64{0.5, 0.1})
+	root.In(mix.AddTo(root).In(o, n))
+
+	// The swing draws from its own source
+	steps := stepper.NewStepper(swing.New(120, 16, bucket.NewContinuous(bucket.Indexed, &swing.Step{
+		Shuffle: 0.5, ShuffleRand: 0.5, SkipChance: 0.3, BurstChance: 0.2, NumBurst: 3,
+	})), nil)
+	steps.MsgrAddTo(root)
+
+	root.SetSeed(seed)
+
+	// The buckets draw from the global math/rand source, they draw when they are created so they are
+	// created after the seed is set
+	frequencies := gen.New[float64](bucket.New(bucket.Random, 220.0, 330.0, 440.0, 550.0), true)
+	root.AddControl(frequencies).CtrlConnect(0, o, 0)
+
+	pulseWidths := gen.New[float64](bucket.New(bucket.Random, 0.1, 0.3, 0.5, 0.7), false)
+	root.AddControl(pulseWidths).CtrlConnect(0, o, 1)
+
+	steps.CtrlConnect(0, pulseWidths, 0)
+
+	var output []float64
+
+	for block := 0; block < blocks; block++ {
+		root.Synthesize()
+		output = append(output, root.OutputAtIndex(0).Buffer...)
+	}
+
+	return output
+}
+
+func TestSeededRenderIdentical(t *testing.T) {
+	const blocks = 64
+
+	first := renderSeeded(7, blocks)
+	second := renderSeeded(7, blocks)
+
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("renders with the same seed differ at frame %d: %v and %v", i, first[i], second[i])
+		}
+	}
+
+	if slices.Equal(first, renderSeeded(8, blocks)) {
+		t.Fatal("renders with different seeds are identical")
+	}
+}
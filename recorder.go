@@ -0,0 +1,327 @@
+package muse
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/almerlucke/muse/buffer"
+	"github.com/almerlucke/muse/utils/queue"
+	"github.com/almerlucke/sndfile/writer"
+	"github.com/dh1tw/gosamplerate"
+)
+
+// RecordingQueueSeconds is the amount of audio a recording can buffer before blocks are dropped
+var RecordingQueueSeconds = 2.0
+
+// recordingPollInterval is how often the writer goroutine looks for new blocks
+const recordingPollInterval = 10 * time.Millisecond
+
+// RecordingSource is a module output to record, a bus is recorded by recording the module that sums it
+type RecordingSource struct {
+	Module Module
+	Output int
+}
+
+// RecordingOptions configure a recording, without sources the outputs of the muse are recorded
+type RecordingOptions struct {
+	FileFormat writer.FileFormat
+	// SampleRate of the file, 0 records at the sample rate of the muse
+	SampleRate float64
+	Normalize  bool
+	Sources    []RecordingSource
+}
+
+// Recorder records module outputs to sound files without disk I/O on the audio thread. Each block is copied
+// into a lock-free queue on the audio thread and written by a writer goroutine. If the writer can not keep up
+// for RecordingQueueSeconds, blocks are dropped and counted by Dropped.
+//
+// Start, Stop, Pause and Resume can be called from any goroutine
+type Recorder struct {
+	muse    *Muse
+	lock    sync.Mutex
+	current atomic.Pointer[recording]
+	take    int
+	dropped atomic.Int64
+}
+
+// recording is a single take, it owns the queues and the writer goroutine
+type recording struct {
+	path    string
+	sockets []*Socket
+	// free holds the empty blocks, filled the blocks waiting to be written
+	free   *queue.Ring[[]buffer.Buffer]
+	filled *queue.Ring[[]buffer.Buffer]
+	// busy is set while the audio thread copies a block, Stop takes it to shut out the audio thread
+	busy   atomic.Bool
+	paused atomic.Bool
+	quit   chan struct{}
+	done   chan struct{}
+	err    error
+}
+
+func newRecorder(m *Muse) *Recorder {
+	return &Recorder{muse: m}
+}
+
+// Start records to filePath, a running recording is stopped first
+func (r *Recorder) Start(filePath string, opts RecordingOptions) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.start(filePath, opts)
+}
+
+// StartTake records the next numbered take to basePath-001.wav, basePath-002.wav and so on, files that
+// already exist are skipped. A running recording is stopped first. StartTake returns the path of the take
+func (r *Recorder) StartTake(basePath string, opts RecordingOptions) (string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	ext := ".wav"
+	if opts.FileFormat == writer.AIFC {
+		ext = ".aif"
+	}
+
+	var filePath string
+
+	for {
+		r.take++
+		filePath = fmt.Sprintf("%s-%03d%s", basePath, r.take, ext)
+
+		if _, err := os.Stat(filePath); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+	}
+
+	return filePath, r.start(filePath, opts)
+}
+
+// Take returns the number of the last take started with StartTake
+func (r *Recorder) Take() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.take
+}
+
+func (r *Recorder) start(filePath string, opts RecordingOptions) error {
+	err := r.stop()
+	if err != nil {
+		return err
+	}
+
+	sockets, err := r.muse.sourceSockets(opts.Sources)
+	if err != nil {
+		return err
+	}
+
+	config := r.muse.Config
+	sampleRate := opts.SampleRate
+
+	if sampleRate <= 0 {
+		sampleRate = config.SampleRate
+	}
+
+	wr, err := writer.NewWithOptions(filePath, opts.FileFormat, len(sockets), sampleRate, writer.Options{
+		InputConverter:    buffer.NewWriterConverter(config.BufferSize, len(sockets)),
+		Normalize:         opts.Normalize,
+		ConvertSampleRate: config.SampleRate != sampleRate,
+		InputSampleRate:   config.SampleRate,
+		SrConvQuality:     gosamplerate.SRC_SINC_BEST_QUALITY,
+	})
+	if err != nil {
+		return err
+	}
+
+	numBlocks := max(8, int(math.Ceil(RecordingQueueSeconds*config.SampleRate/float64(config.BufferSize))))
+
+	rec := &recording{
+		path:    filePath,
+		sockets: sockets,
+		free:    queue.NewRing[[]buffer.Buffer](numBlocks),
+		filled:  queue.NewRing[[]buffer.Buffer](numBlocks),
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	for i := 0; i < rec.free.Capacity(); i++ {
+		block := make([]buffer.Buffer, len(sockets))
+		for c := range block {
+			block[c] = make(buffer.Buffer, config.BufferSize)
+		}
+
+		rec.free.Push(block)
+	}
+
+	go rec.write(wr)
+
+	r.current.Store(rec)
+
+	return nil
+}
+
+// sourceSockets returns the output sockets of the sources, or the outputs of the muse without sources
+func (m *Muse) sourceSockets(sources []RecordingSource) ([]*Socket, error) {
+	if len(sources) == 0 {
+		sockets := make([]*Socket, m.NumOutputs())
+		for i := range sockets {
+			sockets[i] = m.OutputAtIndex(i)
+		}
+
+		return sockets, nil
+	}
+
+	sockets := make([]*Socket, len(sources))
+
+	for i, source := range sources {
+		if source.Module == nil || source.Output < 0 || source.Output >= source.Module.NumOutputs() {
+			return nil, fmt.Errorf("recording source %d has no output %d", i, source.Output)
+		}
+
+		sockets[i] = source.Module.OutputAtIndex(source.Output)
+	}
+
+	return sockets, nil
+}
+
+// Stop stops recording and waits until the file is written
+func (r *Recorder) Stop() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.stop()
+}
+
+func (r *Recorder) stop() error {
+	rec := r.current.Swap(nil)
+	if rec == nil {
+		return nil
+	}
+
+	// Wait until the audio thread has finished a block it is copying, it never takes the recording again
+	for !rec.busy.CompareAndSwap(false, true) {
+		runtime.Gosched()
+	}
+
+	close(rec.quit)
+	<-rec.done
+
+	return rec.err
+}
+
+// Pause stops adding blocks to the recording until Resume
+func (r *Recorder) Pause() {
+	if rec := r.current.Load(); rec != nil {
+		rec.paused.Store(true)
+	}
+}
+
+func (r *Recorder) Resume() {
+	if rec := r.current.Load(); rec != nil {
+		rec.paused.Store(false)
+	}
+}
+
+func (r *Recorder) Recording() bool {
+	return r.current.Load() != nil
+}
+
+func (r *Recorder) Paused() bool {
+	rec := r.current.Load()
+
+	return rec != nil && rec.paused.Load()
+}
+
+// Path returns the file of the current recording, empty if not recording
+func (r *Recorder) Path() string {
+	if rec := r.current.Load(); rec != nil {
+		return rec.path
+	}
+
+	return ""
+}
+
+// Dropped returns the number of blocks dropped because the writer could not keep up
+func (r *Recorder) Dropped() int64 {
+	return r.dropped.Load()
+}
+
+// record copies the current block of the sources, it is called on the audio thread after synthesis
+func (r *Recorder) record() {
+	rec := r.current.Load()
+	if rec == nil || rec.paused.Load() || !rec.busy.CompareAndSwap(false, true) {
+		return
+	}
+
+	if block, ok := rec.free.Pop(); ok {
+		for c, socket := range rec.sockets {
+			copy(block[c], socket.Buffer)
+		}
+
+		rec.filled.Push(block)
+	} else {
+		r.dropped.Add(1)
+	}
+
+	rec.busy.Store(false)
+}
+
+// write writes the filled blocks to wr until the recording is stopped. The last block is held back so it can
+// be written as end of input, which flushes the sample rate converter
+func (rec *recording) write(wr *writer.Writer) {
+	defer close(rec.done)
+
+	var (
+		pending []buffer.Buffer
+		ticker  = time.NewTicker(recordingPollInterval)
+	)
+
+	defer ticker.Stop()
+
+	flush := func(block []buffer.Buffer, end bool) {
+		if rec.err == nil {
+			rec.err = wr.Write(block, end)
+		}
+	}
+
+	for {
+		block, ok := rec.filled.Pop()
+		if ok {
+			if pending != nil {
+				flush(pending, false)
+				rec.free.Push(pending)
+			}
+
+			pending = block
+
+			continue
+		}
+
+		select {
+		case <-rec.quit:
+			// The audio thread is shut out, the queue holds the last blocks
+			for block, ok = rec.filled.Pop(); ok; block, ok = rec.filled.Pop() {
+				if pending != nil {
+					flush(pending, false)
+				}
+
+				pending = block
+			}
+
+			if pending != nil {
+				flush(pending, true)
+			}
+
+			rec.err = errors.Join(rec.err, wr.Close())
+
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package muse
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+
+	museio "github.com/almerlucke/muse/io"
+	"github.com/almerlucke/sndfile/writer"
+	"github.com/dh1tw/gosamplerate"
+)
+
+// DefaultTailThreshold is the level below which a tail counts as silent, -80 dB
+var DefaultTailThreshold = 0.0001
+
+// DefaultRenderMaxDuration limits a render until silence in seconds
+var DefaultRenderMaxDuration = 600.0
+
+// RenderStem is a named group of module outputs that is rendered to its own file, without sources the outputs
+// of the muse are rendered
+type RenderStem struct {
+	Name    string
+	Sources []RecordingSource
+}
+
+// RenderOptions configure an offline render. Durations of the render are in seconds, fades and tails in
+// milliseconds
+type RenderOptions struct {
+	FileFormat writer.FileFormat
+	// SampleRate of the files, 0 renders at the sample rate of the muse
+	SampleRate float64
+	Normalize  bool
+	// BitDepth 16 or 24 writes integer WAV files, 0 writes float files
+	BitDepth int
+	// Dither adds TPDF dither with noise shaping when writing integer files
+	Dither bool
+	// Start is rendered but not written, to skip the start of a patch
+	Start float64
+	// Duration is the length of the render, with a tail duration it is the minimum length
+	Duration float64
+	// TailDuration renders until the output stays below TailThreshold for the duration, at most MaxDuration
+	TailDuration  float64
+	TailThreshold float64
+	MaxDuration   float64
+	FadeIn        float64
+	FadeOut       float64
+	// Stems are rendered in one pass to files named after the file path and the stem name, without stems
+	// the outputs of the muse are rendered to the file path
+	Stems []RenderStem
+}
+
+// renderStem writes the sockets of a stem, frames are held back in pending until it is certain they are not
+// part of the fade out
+type renderStem struct {
+	sockets []*Socket
+	writer  *writer.Writer
+	pending []float32
+}
+
+// RenderToSoundFile renders numSeconds of the outputs of the muse
+func (m *Muse) RenderToSoundFile(filePath string, fileFormat writer.FileFormat, numSeconds float64, sampleRate float64, normalize bool) error {
+	return m.Render(filePath, RenderOptions{
+		FileFormat: fileFormat,
+		SampleRate: sampleRate,
+		Normalize:  normalize,
+		Duration:   numSeconds,
+	})
+}
+
+// Render renders the muse offline as fast as possible
+func (m *Muse) Render(filePath string, opts RenderOptions) (err error) {
+	if opts.Duration <= 0 && opts.TailDuration <= 0 {
+		return errors.New("render needs a duration or a tail duration")
+	}
+
+	if opts.BitDepth != 0 && opts.FileFormat != writer.WAV {
+		return errors.New("integer bit depths are only supported for WAV files")
+	}
+
+	stems, err := m.renderStems(filePath, opts)
+
+	defer func() {
+		for _, stem := range stems {
+			err = errors.Join(err, stem.writer.Close())
+		}
+	}()
+
+	if err != nil {
+		return err
+	}
+
+	var (
+		sampleRate     = m.Config.SampleRate
+		startFrames    = int64(opts.Start * sampleRate)
+		durationFrames = int64(opts.Duration * sampleRate)
+		maxFrames      = durationFrames
+		tailFrames     = int64(opts.TailDuration / 1000.0 * sampleRate)
+		fadeInFrames   = int64(opts.FadeIn / 1000.0 * sampleRate)
+		fadeOutFrames  = int(opts.FadeOut / 1000.0 * sampleRate)
+		threshold      = opts.TailThreshold
+		frame          int64
+		written        int64
+		silent         int64
+		done           bool
+	)
+
+	if tailFrames > 0 {
+		maxDuration := opts.MaxDuration
+		if maxDuration <= 0 {
+			maxDuration = DefaultRenderMaxDuration
+		}
+
+		maxFrames = max(durationFrames, int64(maxDuration*sampleRate))
+	}
+
+	if threshold <= 0 {
+		threshold = DefaultTailThreshold
+	}
+
+	for !done {
+		m.Synthesize()
+
+		for i := 0; i < m.Config.BufferSize && !done; i++ {
+			frame++
+			if frame <= startFrames {
+				continue
+			}
+
+			gain := 1.0
+			if written < fadeInFrames {
+				gain = float64(written) / float64(fadeInFrames)
+			}
+
+			loud := false
+
+			for _, stem := range stems {
+				for _, socket := range stem.sockets {
+					v := socket.Buffer[i]
+					loud = loud || math.Abs(v) >= threshold
+					stem.pending = append(stem.pending, float32(v*gain))
+				}
+			}
+
+			written++
+
+			if loud {
+				silent = 0
+			} else {
+				silent++
+			}
+
+			done = written >= maxFrames || (written >= durationFrames && (tailFrames == 0 || silent >= tailFrames))
+		}
+
+		if !done {
+			for _, stem := range stems {
+				if err = stem.write(len(stem.pending)/len(stem.sockets)-fadeOutFrames, false); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for _, stem := range stems {
+		stem.fadeOut(fadeOutFrames)
+
+		if err = stem.write(len(stem.pending)/len(stem.sockets), true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// renderStems creates the writers of the stems
+func (m *Muse) renderStems(filePath string, opts RenderOptions) ([]*renderStem, error) {
+	stemOpts := opts.Stems
+	if len(stemOpts) == 0 {
+		stemOpts = []RenderStem{{}}
+	}
+
+	ext := filepath.Ext(filePath)
+	base := strings.TrimSuffix(filePath, ext)
+
+	if ext == "" {
+		ext = ".wav"
+		if opts.FileFormat == writer.AIFC {
+			ext = ".aif"
+		}
+	}
+
+	sampleRate := opts.SampleRate
+	if sampleRate <= 0 {
+		sampleRate = m.Config.SampleRate
+	}
+
+	stems := make([]*renderStem, 0, len(stemOpts))
+
+	for _, stemOpt := range stemOpts {
+		sockets, err := m.sourceSockets(stemOpt.Sources)
+		if err != nil {
+			return stems, err
+		}
+
+		if len(sockets) == 0 {
+			return stems, fmt.Errorf("stem %q has no outputs", stemOpt.Name)
+		}
+
+		path := base + ext
+		if len(opts.Stems) > 0 {
+			path = base + "-" + stemOpt.Name + ext
+		}
+
+		wrOpts := writer.Options{
+			InputConverter:    writer.NewNoConverter(m.Config.BufferSize + int(opts.FadeOut/1000.0*m.Config.SampleRate)),
+			Normalize:         opts.Normalize,
+			ConvertSampleRate: m.Config.SampleRate != sampleRate,
+			InputSampleRate:   m.Config.SampleRate,
+			SrConvQuality:     gosamplerate.SRC_SINC_BEST_QUALITY,
+		}
+
+		var wr *writer.Writer
+
+		if opts.BitDepth != 0 {
+			var be *museio.PCMWav
+
+			be, err = museio.NewPCMWav(path, len(sockets), sampleRate, opts.BitDepth, opts.Dither)
+			if err == nil {
+				wr, err = writer.NewWithBackend(be, len(sockets), sampleRate, wrOpts)
+			}
+		} else {
+			wr, err = writer.NewWithOptions(path, opts.FileFormat, len(sockets), sampleRate, wrOpts)
+		}
+
+		if err != nil {
+			return stems, err
+		}
+
+		stems = append(stems, &renderStem{sockets: sockets, writer: wr})
+	}
+
+	return stems, nil
+}
+
+// write writes the first numFrames pending frames
+func (s *renderStem) write(numFrames int, end bool) error {
+	if numFrames <= 0 && !end {
+		return nil
+	}
+
+	numSamples := max(0, numFrames) * len(s.sockets)
+
+	err := s.writer.Write(s.pending[:numSamples], end)
+
+	s.pending = s.pending[:copy(s.pending, s.pending[numSamples:])]
+
+	return err
+}
+
+// fadeOut fades out the last numFrames pending frames
+func (s *renderStem) fadeOut(numFrames int) {
+	numChannels := len(s.sockets)
+	totalFrames := len(s.pending) / numChannels
+	numFrames = min(numFrames, totalFrames)
+
+	for i := 0; i < numFrames; i++ {
+		gain := float32(numFrames-i-1) / float32(numFrames)
+		frame := totalFrames - numFrames + i
+
+		for c := 0; c < numChannels; c++ {
+			s.pending[frame*numChannels+c] *= gain
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package muse_test
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/almerlucke/muse"
+	"github.com/almerlucke/sndfile/writer"
+)
+
+// counter outputs the frame count as 16 bit sample values until it reaches length, after that it outputs
+// silence. A negative length counts forever
+type counter struct {
+	*muse.BaseModule
+	frame  int
+	length int
+	value  float64
+}
+
+func newCounter(length int) *counter {
+	c := &counter{
+		BaseModule: muse.NewBaseModule(0, 1),
+		length:     length,
+	}
+
+	c.SetSelf(c)
+
+	return c
+}
+
+// constant makes the counter output value instead of the frame count
+func (c *counter) constant(value float64) *counter {
+	c.value = value
+	return c
+}
+
+func (c *counter) Synthesize() bool {
+	if !c.BaseModule.Synthesize() {
+		return false
+	}
+
+	for i := 0; i < c.Config.BufferSize; i++ {
+		v := 0.0
+
+		if c.length < 0 || c.frame < c.length {
+			v = c.value
+			if v == 0 {
+				v = float64(c.frame%16384) / 32768.0
+			}
+		}
+
+		c.Outputs[0].Buffer[i] = v
+		c.frame++
+	}
+
+	return true
+}
+
+// renderSource renders a source to a mono 16 bit WAV file and returns its samples
+func renderSource(t *testing.T, source muse.Module, opts muse.RenderOptions) []int16 {
+	t.Helper()
+
+	root := muse.New(1)
+	source.AddTo(root)
+	root.In(source)
+
+	path := filepath.Join(t.TempDir(), "render.wav")
+
+	opts.FileFormat = writer.WAV
+	opts.BitDepth = 16
+
+	if err := root.Render(path, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	return readSamples(t, path)
+}
+
+// readSamples returns the 16 bit samples of the data chunk of a WAV file
+func readSamples(t *testing.T, path string) []int16 {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data) < 44 {
+		t.Fatalf("%s has no header", path)
+	}
+
+	size := int(binary.LittleEndian.Uint32(data[40:]))
+	samples := make([]int16, size/2)
+
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(data[44+i*2:]))
+	}
+
+	return samples
+}
+
+// frames returns the number of frames of milliseconds at the default sample rate
+func frames(ms float64) int {
+	return int(ms / 1000.0 * muse.DefaultSamplerate)
+}
+
+func TestRenderDuration(t *testing.T) {
+	samples := renderSource(t, newCounter(-1), muse.RenderOptions{Duration: 0.1})
+
+	if len(samples) != frames(100) {
+		t.Fatalf("rendered %d frames, expected %d", len(samples), frames(100))
+	}
+
+	for i, s := range samples {
+		if int(s) != i%16384 {
+			t.Fatalf("sample %d is %d, expected %d", i, s, i%16384)
+		}
+	}
+}
+
+func TestRenderStart(t *testing.T) {
+	samples := renderSource(t, newCounter(-1), muse.RenderOptions{Start: 0.05, Duration: 0.1})
+
+	start := frames(50)
+
+	if len(samples) != frames(100) || int(samples[0]) != start || int(samples[1]) != start+1 {
+		t.Fatalf("rendered %d frames starting with %v, expected %d frames starting with %d", len(samples), samples[:2], frames(100), start)
+	}
+}
+
+func TestRenderFades(t *testing.T) {
+	samples := renderSource(t, newCounter(-1).constant(0.5), muse.RenderOptions{Duration: 0.2, FadeIn: 10, FadeOut: 30})
+
+	fadeIn := frames(10)
+	fadeOut := frames(30)
+	n := len(samples)
+
+	if n != frames(200) {
+		t.Fatalf("rendered %d frames, expected %d", n, frames(200))
+	}
+
+	if samples[0] != 0 || samples[fadeIn/2] >= samples[fadeIn] || samples[fadeIn] != 16384 {
+		t.Fatalf("fade in %v, %v, %v", samples[0], samples[fadeIn/2], samples[fadeIn])
+	}
+
+	if samples[n-fadeOut-1] != 16384 || samples[n-fadeOut/2] >= samples[n-fadeOut] || samples[n-1] != 0 {
+		t.Fatalf("fade out %v, %v, %v", samples[n-fadeOut-1], samples[n-fadeOut/2], samples[n-1])
+	}
+}
+
+func TestRenderTail(t *testing.T) {
+	burst := 3000
+
+	samples := renderSource(t, newCounter(burst).constant(0.5), muse.RenderOptions{TailDuration: 50})
+
+	if expected := burst + frames(50); len(samples) != expected {
+		t.Fatalf("rendered %d frames, expected the burst and the tail of %d frames", len(samples), expected)
+	}
+
+	// The duration is the minimum length of a render with a tail
+	samples = renderSource(t, newCounter(burst).constant(0.5), muse.RenderOptions{Duration: 1, TailDuration: 50})
+
+	if len(samples) != frames(1000) {
+		t.Fatalf("rendered %d frames, expected %d", len(samples), frames(1000))
+	}
+}
+
+func TestRenderMaxDuration(t *testing.T) {
+	samples := renderSource(t, newCounter(-1).constant(0.5), muse.RenderOptions{TailDuration: 50, MaxDuration: 0.25})
+
+	if len(samples) != frames(250) {
+		t.Fatalf("rendered %d frames of a source that never ends, expected %d", len(samples), frames(250))
+	}
+}
+
+func TestRenderStems(t *testing.T) {
+	root := muse.New(2)
+
+	low := newCounter(-1).constant(0.25).AddTo(root)
+	high := newCounter(-1).constant(0.5).AddTo(root)
+
+	low.Connect(0, root, 0)
+	high.Connect(0, root, 1)
+
+	path := filepath.Join(t.TempDir(), "song.wav")
+
+	err := root.Render(path, muse.RenderOptions{
+		FileFormat: writer.WAV,
+		BitDepth:   16,
+		Duration:   0.1,
+		Stems: []muse.RenderStem{
+			{Name: "low", Sources: []muse.RecordingSource{{Module: low, Output: 0}}},
+			{Name: "high", Sources: []muse.RecordingSource{{Module: high, Output: 0}}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expected := range map[string]int16{"low": 8192, "high": 16384} {
+		samples := readSamples(t, filepath.Join(filepath.Dir(path), "song-"+name+".wav"))
+
+		if len(samples) != frames(100) || samples[0] != expected || samples[len(samples)-1] != expected {
+			t.Fatalf("stem %s has %d frames of %v, expected %d frames of %d", name, len(samples), samples[0], frames(100), expected)
+		}
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("rendering stems wrote %s", path)
+	}
+}
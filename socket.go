@@ -21,3 +21,12 @@ func (s *Socket) AddConnection(c *Connection) {
 func (s *Socket) IsConnected() bool {
 	return len(s.Connections) > 0
 }
+
+// Value returns sample i of the socket buffer if the socket is connected, fallback otherwise
+func (s *Socket) Value(i int, fallback float64) float64 {
+	if len(s.Connections) > 0 {
+		return s.Buffer[i]
+	}
+
+	return fallback
+}
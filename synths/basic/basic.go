@@ -83,44 +83,62 @@ func (v *Voice) Clear() {
 }
 
 func (v *Voice) Note(duration float64, amplitude float64, msg any, config *muse.Configuration) {
-	if values, ok := msg.(map[string]any); ok {
-		if attackDuration, ok := values["attackDuration"].(float64); ok {
+	v.NoteAt(duration, amplitude, msg, config, 0)
+}
+
+// NoteAt starts a note with a duration, the envelopes are triggered at a sample offset within the next block
+func (v *Voice) NoteAt(duration float64, amplitude float64, msg any, config *muse.Configuration, offset int) {
+	if content, ok := muse.DecodeMessage(v, msg); ok {
+		if attackDuration, ok := content.Float("attackDuration"); ok {
 			v.ampEnvSetting.AttackDuration = attackDuration
 			v.filterEnvSetting.AttackDuration = attackDuration
 		}
-		if releaseDuration, ok := values["releaseDuration"].(float64); ok {
+		if releaseDuration, ok := content.Float("releaseDuration"); ok {
 			v.ampEnvSetting.ReleaseDuration = releaseDuration
 			v.filterEnvSetting.ReleaseDuration = releaseDuration
 		}
-		if panning, ok := values["pan"].(float64); ok {
+		if panning, ok := content.Float("pan"); ok {
 			v.panner.SetPan(panning)
 		}
-		if filterFcMin, ok := values["filterFcMin"].(float64); ok {
+		if filterFcMin, ok := content.Float("filterFcMin"); ok {
 			v.filterFcMin = filterFcMin
 		}
-		if filterFcMax, ok := values["filterFcMax"].(float64); ok {
+		if filterFcMax, ok := content.Float("filterFcMax"); ok {
 			v.filterFcMax = filterFcMax
 		}
-		if filterRes, ok := values["filterResonance"].(float64); ok {
+		if filterRes, ok := content.Float("filterResonance"); ok {
 			v.filter.SetResonance(filterRes)
 		}
 
-		v.source.Activate(msg.(map[string]any))
+		v.source.Activate(content.Values())
 	}
 
-	v.ampEnv.TriggerFull(duration, amplitude, v.ampEnvSetting, adsrc.Duration)
-	v.filterEnv.TriggerFull(duration, 1.0, v.filterEnvSetting, adsrc.Duration)
+	v.ampEnv.TriggerFullAt(offset, duration, amplitude, v.ampEnvSetting, adsrc.Duration)
+	v.filterEnv.TriggerFullAt(offset, duration, 1.0, v.filterEnvSetting, adsrc.Duration)
 }
 
 func (v *Voice) NoteOn(amplitude float64, msg any, config *muse.Configuration) {
-	v.source.Activate(msg.(map[string]any))
-	v.ampEnv.TriggerFull(0, amplitude, v.ampEnvSetting, adsrc.NoteOff)
-	v.filterEnv.TriggerFull(0, 1.0, v.filterEnvSetting, adsrc.NoteOff)
+	v.NoteOnAt(amplitude, msg, config, 0)
+}
+
+// NoteOnAt starts a note, the envelopes are triggered at a sample offset within the next block
+func (v *Voice) NoteOnAt(amplitude float64, msg any, config *muse.Configuration, offset int) {
+	if content, ok := muse.DecodeMessage(v, msg); ok {
+		v.source.Activate(content.Values())
+	}
+
+	v.ampEnv.TriggerFullAt(offset, 0, amplitude, v.ampEnvSetting, adsrc.NoteOff)
+	v.filterEnv.TriggerFullAt(offset, 0, 1.0, v.filterEnvSetting, adsrc.NoteOff)
 }
 
 func (v *Voice) NoteOff() {
-	v.ampEnv.Release()
-	v.filterEnv.Release()
+	v.NoteOffAt(0)
+}
+
+// NoteOffAt releases the envelopes at a sample offset within the next block
+func (v *Voice) NoteOffAt(offset int) {
+	v.ampEnv.ReleaseAt(offset)
+	v.filterEnv.ReleaseAt(offset)
 }
 
 func (v *Voice) SetValue(key string, value any) {
@@ -131,10 +149,14 @@ func (v *Voice) SetValues(values map[string]any) {
 	v.source.SetValues(values)
 }
 
-func (v *Voice) ReceiveMessage(msg any) []*muse.Message {
-	if values, ok := msg.(map[string]any); ok {
+// Parameters returns the parameters of the source, messages are passed on to it
+func (v *Voice) Parameters() []muse.Parameter {
+	return muse.ParametersOf(v.source)
+}
 
-		v.source.SetValues(values)
+func (v *Voice) ReceiveMessage(msg any) []*muse.Message {
+	if content, ok := muse.DecodeMessage(v, msg); ok {
+		v.source.SetValues(content.Values())
 	}
 
 	return nil
@@ -106,37 +106,50 @@ func (v *Voice) Clear() {
 }
 
 func (v *Voice) Note(duration float64, amplitude float64, msg any, config *muse.Configuration) {
-	content := msg.(map[string]any)
+	v.NoteAt(duration, amplitude, msg, config, 0)
+}
+
+// NoteAt starts a note with a duration, the envelopes are triggered at a sample offset within the next block
+func (v *Voice) NoteAt(duration float64, amplitude float64, msg any, config *muse.Configuration, offset int) {
+	content, _ := muse.DecodeMessage(v, msg)
 
 	v.handleMessage(content)
 
-	if fcRaw, ok := content["frequency"]; ok {
-		fc := fcRaw.(float64)
+	if fc, ok := content.Float("frequency"); ok {
 		v.Osc1.SetFrequency(fc)
 		v.Osc2.SetFrequency(fc * v.osc2Tuning)
 	}
 
-	v.ampEnv.TriggerFull(duration, amplitude, v.ampEnvSetting, adsrc.Duration)
-	v.filterEnv.TriggerFull(duration, 1.0, v.filterEnvSetting, adsrc.Duration)
+	v.ampEnv.TriggerFullAt(offset, duration, amplitude, v.ampEnvSetting, adsrc.Duration)
+	v.filterEnv.TriggerFullAt(offset, duration, 1.0, v.filterEnvSetting, adsrc.Duration)
 }
 
 func (v *Voice) NoteOn(amplitude float64, msg any, config *muse.Configuration) {
-	content := msg.(map[string]any)
+	v.NoteOnAt(amplitude, msg, config, 0)
+}
+
+// NoteOnAt starts a note, the envelopes are triggered at a sample offset within the next block
+func (v *Voice) NoteOnAt(amplitude float64, msg any, config *muse.Configuration, offset int) {
+	content, _ := muse.DecodeMessage(v, msg)
 
 	v.handleMessage(content)
 
-	if fcRaw, ok := content["frequency"]; ok {
-		fc := fcRaw.(float64)
+	if fc, ok := content.Float("frequency"); ok {
 		v.Osc1.SetFrequency(fc)
 		v.Osc2.SetFrequency(fc * v.osc2Tuning)
-		v.ampEnv.TriggerFull(0, amplitude, v.ampEnvSetting, adsrc.NoteOff)
-		v.filterEnv.TriggerFull(0, 1.0, v.filterEnvSetting, adsrc.NoteOff)
+		v.ampEnv.TriggerFullAt(offset, 0, amplitude, v.ampEnvSetting, adsrc.NoteOff)
+		v.filterEnv.TriggerFullAt(offset, 0, 1.0, v.filterEnvSetting, adsrc.NoteOff)
 	}
 }
 
 func (v *Voice) NoteOff() {
-	v.ampEnv.Release()
-	v.filterEnv.Release()
+	v.NoteOffAt(0)
+}
+
+// NoteOffAt releases the envelopes at a sample offset within the next block
+func (v *Voice) NoteOffAt(offset int) {
+	v.ampEnv.ReleaseAt(offset)
+	v.filterEnv.ReleaseAt(offset)
 }
 
 func (v *Voice) SetOsc1Mix(mix float64) {
@@ -219,82 +232,135 @@ func (v *Voice) SetFilterFcMax(max float64) {
 	v.filterFcMax = max
 }
 
-func (v *Voice) handleMessage(content map[string]any) {
-	if osc1Mix, ok := content["osc1Mix"]; ok {
-		v.SetOsc1Mix(osc1Mix.(float64))
+// Setting returns the current voice setting
+func (v *Voice) Setting() Setting {
+	return Setting{
+		Osc1Mix:         v.SourceMixer.MixAt(0),
+		Osc2Mix:         v.SourceMixer.MixAt(1),
+		NoiseMix:        v.SourceMixer.MixAt(2),
+		Osc1PulseWidth:  v.Osc1.PulseWidth(),
+		Osc2PulseWidth:  v.Osc2.PulseWidth(),
+		FilterResonance: v.filter.Resonance(),
+		Osc1SineMix:     v.Osc1.MixAt(0),
+		Osc1SawMix:      v.Osc1.MixAt(1),
+		Osc1PulseMix:    v.Osc1.MixAt(2),
+		Osc1TriMix:      v.Osc1.MixAt(3),
+		Osc2SineMix:     v.Osc2.MixAt(0),
+		Osc2SawMix:      v.Osc2.MixAt(1),
+		Osc2PulseMix:    v.Osc2.MixAt(2),
+		Osc2TriMix:      v.Osc2.MixAt(3),
+		Osc2Tuning:      v.osc2Tuning,
+		Pan:             v.panner.Pan(),
+		FilterFcMin:     v.filterFcMin,
+		FilterFcMax:     v.filterFcMax,
+	}
+}
+
+func (v *Voice) handleMessage(content muse.Content) {
+	if osc1Mix, ok := content.Float("osc1Mix"); ok {
+		v.SetOsc1Mix(osc1Mix)
 	}
 
-	if osc2Mix, ok := content["osc2Mix"]; ok {
-		v.SetOsc2Mix(osc2Mix.(float64))
+	if osc2Mix, ok := content.Float("osc2Mix"); ok {
+		v.SetOsc2Mix(osc2Mix)
 	}
 
-	if noiseMix, ok := content["noiseMix"]; ok {
-		v.SetNoiseMix(noiseMix.(float64))
+	if noiseMix, ok := content.Float("noiseMix"); ok {
+		v.SetNoiseMix(noiseMix)
 	}
 
-	if osc1PulseWidth, ok := content["osc1PulseWidth"]; ok {
-		v.SetOsc1PulseWidth(osc1PulseWidth.(float64))
+	if osc1PulseWidth, ok := content.Float("osc1PulseWidth"); ok {
+		v.SetOsc1PulseWidth(osc1PulseWidth)
 	}
 
-	if osc2PulseWidth, ok := content["osc2PulseWidth"]; ok {
-		v.SetOsc2PulseWidth(osc2PulseWidth.(float64))
+	if osc2PulseWidth, ok := content.Float("osc2PulseWidth"); ok {
+		v.SetOsc2PulseWidth(osc2PulseWidth)
 	}
 
-	if filterResonance, ok := content["filterResonance"]; ok {
-		v.SetFilterResonance(filterResonance.(float64))
+	if filterResonance, ok := content.Float("filterResonance"); ok {
+		v.SetFilterResonance(filterResonance)
 	}
 
-	if osc1SineMix, ok := content["osc1SineMix"]; ok {
-		v.SetOsc1SineMix(osc1SineMix.(float64))
+	if osc1SineMix, ok := content.Float("osc1SineMix"); ok {
+		v.SetOsc1SineMix(osc1SineMix)
 	}
 
-	if osc1SawMix, ok := content["osc1SawMix"]; ok {
-		v.SetOsc1SawMix(osc1SawMix.(float64))
+	if osc1SawMix, ok := content.Float("osc1SawMix"); ok {
+		v.SetOsc1SawMix(osc1SawMix)
 	}
 
-	if osc1PulseMix, ok := content["osc1PulseMix"]; ok {
-		v.SetOsc1PulseMix(osc1PulseMix.(float64))
+	if osc1PulseMix, ok := content.Float("osc1PulseMix"); ok {
+		v.SetOsc1PulseMix(osc1PulseMix)
 	}
 
-	if osc1TriMix, ok := content["osc1TriMix"]; ok {
-		v.SetOsc1TriMix(osc1TriMix.(float64))
+	if osc1TriMix, ok := content.Float("osc1TriMix"); ok {
+		v.SetOsc1TriMix(osc1TriMix)
 	}
 
-	if osc2SineMix, ok := content["osc2SineMix"]; ok {
-		v.SetOsc2SineMix(osc2SineMix.(float64))
+	if osc2SineMix, ok := content.Float("osc2SineMix"); ok {
+		v.SetOsc2SineMix(osc2SineMix)
 	}
 
-	if osc2SawMix, ok := content["osc2SawMix"]; ok {
-		v.SetOsc2SawMix(osc2SawMix.(float64))
+	if osc2SawMix, ok := content.Float("osc2SawMix"); ok {
+		v.SetOsc2SawMix(osc2SawMix)
 	}
 
-	if osc2PulseMix, ok := content["osc2PulseMix"]; ok {
-		v.SetOsc2PulseMix(osc2PulseMix.(float64))
+	if osc2PulseMix, ok := content.Float("osc2PulseMix"); ok {
+		v.SetOsc2PulseMix(osc2PulseMix)
 	}
 
-	if osc2TriMix, ok := content["osc2TriMix"]; ok {
-		v.SetOsc2TriMix(osc2TriMix.(float64))
+	if osc2TriMix, ok := content.Float("osc2TriMix"); ok {
+		v.SetOsc2TriMix(osc2TriMix)
 	}
 
-	if osc2Tuning, ok := content["osc2Tuning"]; ok {
-		v.SetOsc2Tuning(osc2Tuning.(float64))
+	if osc2Tuning, ok := content.Float("osc2Tuning"); ok {
+		v.SetOsc2Tuning(osc2Tuning)
 	}
 
-	if p, ok := content["pan"]; ok {
-		v.SetPan(p.(float64))
+	if p, ok := content.Float("pan"); ok {
+		v.SetPan(p)
 	}
 
-	if filterFcMin, ok := content["filterFcMin"]; ok {
-		v.SetFilterFcMin(filterFcMin.(float64))
+	if filterFcMin, ok := content.Float("filterFcMin"); ok {
+		v.SetFilterFcMin(filterFcMin)
 	}
 
-	if filterFcMax, ok := content["filterFcMax"]; ok {
-		v.SetFilterFcMax(filterFcMax.(float64))
+	if filterFcMax, ok := content.Float("filterFcMax"); ok {
+		v.SetFilterFcMax(filterFcMax)
+	}
+}
+
+// Parameters describes the message keys of the voice, defaults are taken from DefaultSetting
+func (v *Voice) Parameters() []muse.Parameter {
+	setting := v.Setting()
+	defaults := DefaultSetting()
+
+	return []muse.Parameter{
+		{Name: "osc1Mix", ControlIndex: -1, MessageKey: "osc1Mix", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: defaults.Osc1Mix, Value: setting.Osc1Mix},
+		{Name: "osc2Mix", ControlIndex: -1, MessageKey: "osc2Mix", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: defaults.Osc2Mix, Value: setting.Osc2Mix},
+		{Name: "noiseMix", ControlIndex: -1, MessageKey: "noiseMix", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: defaults.NoiseMix, Value: setting.NoiseMix},
+		{Name: "osc1PulseWidth", ControlIndex: -1, MessageKey: "osc1PulseWidth", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: defaults.Osc1PulseWidth, Value: setting.Osc1PulseWidth},
+		{Name: "osc2PulseWidth", ControlIndex: -1, MessageKey: "osc2PulseWidth", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: defaults.Osc2PulseWidth, Value: setting.Osc2PulseWidth},
+		{Name: "filterResonance", ControlIndex: -1, MessageKey: "filterResonance", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: defaults.FilterResonance, Value: setting.FilterResonance},
+		{Name: "osc1SineMix", ControlIndex: -1, MessageKey: "osc1SineMix", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: defaults.Osc1SineMix, Value: setting.Osc1SineMix},
+		{Name: "osc1SawMix", ControlIndex: -1, MessageKey: "osc1SawMix", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: defaults.Osc1SawMix, Value: setting.Osc1SawMix},
+		{Name: "osc1PulseMix", ControlIndex: -1, MessageKey: "osc1PulseMix", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: defaults.Osc1PulseMix, Value: setting.Osc1PulseMix},
+		{Name: "osc1TriMix", ControlIndex: -1, MessageKey: "osc1TriMix", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: defaults.Osc1TriMix, Value: setting.Osc1TriMix},
+		{Name: "osc2SineMix", ControlIndex: -1, MessageKey: "osc2SineMix", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: defaults.Osc2SineMix, Value: setting.Osc2SineMix},
+		{Name: "osc2SawMix", ControlIndex: -1, MessageKey: "osc2SawMix", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: defaults.Osc2SawMix, Value: setting.Osc2SawMix},
+		{Name: "osc2PulseMix", ControlIndex: -1, MessageKey: "osc2PulseMix", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: defaults.Osc2PulseMix, Value: setting.Osc2PulseMix},
+		{Name: "osc2TriMix", ControlIndex: -1, MessageKey: "osc2TriMix", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: defaults.Osc2TriMix, Value: setting.Osc2TriMix},
+		{Name: "osc2Tuning", ControlIndex: -1, MessageKey: "osc2Tuning", Type: muse.ParameterFloat, Min: 0.25, Max: 4, Default: defaults.Osc2Tuning, Scaling: muse.ScalingExponential, Value: setting.Osc2Tuning},
+		{Name: "pan", ControlIndex: -1, MessageKey: "pan", Type: muse.ParameterFloat, Min: 0, Max: 1, Default: defaults.Pan, Value: setting.Pan},
+		{Name: "filterFcMin", ControlIndex: -1, MessageKey: "filterFcMin", Type: muse.ParameterFloat, Min: 20, Max: 20000, Default: defaults.FilterFcMin, Unit: "Hz", Scaling: muse.ScalingExponential, Value: setting.FilterFcMin},
+		{Name: "filterFcMax", ControlIndex: -1, MessageKey: "filterFcMax", Type: muse.ParameterFloat, Min: 20, Max: 20000, Default: defaults.FilterFcMax, Unit: "Hz", Scaling: muse.ScalingExponential, Value: setting.FilterFcMax},
 	}
 }
 
 func (v *Voice) ReceiveMessage(msg any) []*muse.Message {
-	v.handleMessage(msg.(map[string]any))
+	if content, ok := muse.DecodeMessage(v, msg); ok {
+		v.handleMessage(content)
+	}
 	return nil
 }
 
@@ -385,6 +451,17 @@ func (s *Synth) Set(setting Setting) {
 	s.SetFilterFcMax(setting.FilterFcMax)
 }
 
+// Setting returns the current setting, all voices share the same setting
+func (s *Synth) Setting() Setting {
+	setting := DefaultSetting()
+
+	s.CallVoices(func(v polyphony.Voice) {
+		setting = v.(*Voice).Setting()
+	})
+
+	return setting
+}
+
 func (s *Synth) SetOsc1Mix(mix float64) {
 	s.CallVoices(func(v polyphony.Voice) {
 		v.(*Voice).SetOsc1Mix(mix)
@@ -489,6 +566,6 @@ func (s *Synth) SetFilterFcMin(min float64) {
 
 func (s *Synth) SetFilterFcMax(max float64) {
 	s.CallVoices(func(v polyphony.Voice) {
-		v.(*Voice).SetFilterFcMin(max)
+		v.(*Voice).SetFilterFcMax(max)
 	})
 }
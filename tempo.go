@@ -0,0 +1,180 @@
+package muse
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// TempoPoint sets the tempo at a beat, beats are quarter notes. With Ramp the tempo changes linearly from the
+// previous point to this point instead of jumping at Beat
+type TempoPoint struct {
+	Beat float64 `json:"beat"`
+	BPM  float64 `json:"bpm"`
+	Ramp bool    `json:"ramp,omitempty"`
+}
+
+// TempoMap converts between beats and seconds for a tempo that changes over time
+type TempoMap struct {
+	points []TempoPoint
+	// seconds is the time at each point
+	seconds []float64
+}
+
+// NewTempoMap returns a tempo map with a constant tempo
+func NewTempoMap(bpm float64) *TempoMap {
+	tm := &TempoMap{}
+	tm.SetPoints([]TempoPoint{{Beat: 0, BPM: bpm}})
+
+	return tm
+}
+
+// Points returns a copy of the tempo points ordered by beat
+func (tm *TempoMap) Points() []TempoPoint {
+	return slices.Clone(tm.points)
+}
+
+// SetPoints replaces all tempo points, points with a tempo of zero or less are ignored and the tempo of
+// the first point is used from beat 0. The points are copied into the storage of the map, so changing the
+// tempo on the audio thread does not allocate once the map has held as many points
+func (tm *TempoMap) SetPoints(points []TempoPoint) {
+	tm.points = tm.points[:0]
+
+	for _, p := range points {
+		if p.BPM > 0 && p.Beat >= 0 {
+			tm.points = append(tm.points, p)
+		}
+	}
+
+	if len(tm.points) == 0 {
+		tm.points = append(tm.points, TempoPoint{BPM: 120})
+	}
+
+	slices.SortStableFunc(tm.points, func(a, b TempoPoint) int {
+		return cmp.Compare(a.Beat, b.Beat)
+	})
+
+	tm.points[0].Beat = 0
+	tm.points[0].Ramp = false
+
+	tm.update()
+}
+
+// SetTempo sets the tempo from beat on, points after beat are kept
+func (tm *TempoMap) SetTempo(beat float64, bpm float64) {
+	tm.set(TempoPoint{Beat: beat, BPM: bpm})
+}
+
+// RampTempo ramps the tempo from the previous point to bpm at beat
+func (tm *TempoMap) RampTempo(beat float64, bpm float64) {
+	tm.set(TempoPoint{Beat: beat, BPM: bpm, Ramp: true})
+}
+
+// setConstant replaces all points with a constant tempo
+func (tm *TempoMap) setConstant(bpm float64) {
+	tm.points = append(tm.points[:0], TempoPoint{BPM: bpm})
+	tm.SetPoints(tm.points)
+}
+
+func (tm *TempoMap) set(point TempoPoint) {
+	i := slices.IndexFunc(tm.points, func(p TempoPoint) bool {
+		return p.Beat == point.Beat
+	})
+
+	if i >= 0 {
+		tm.points[i] = point
+	} else {
+		tm.points = append(tm.points, point)
+	}
+
+	// SetPoints filters and sorts in place
+	tm.SetPoints(tm.points)
+}
+
+func (tm *TempoMap) update() {
+	tm.seconds = slices.Grow(tm.seconds[:0], len(tm.points))[:len(tm.points)]
+	tm.seconds[0] = 0
+
+	for i := 1; i < len(tm.points); i++ {
+		tm.seconds[i] = tm.seconds[i-1] + tm.secondsIn(i-1, tm.points[i].Beat-tm.points[i-1].Beat)
+	}
+}
+
+// slope returns the tempo change per beat of the segment starting at point i
+func (tm *TempoMap) slope(i int) float64 {
+	if i+1 < len(tm.points) && tm.points[i+1].Ramp {
+		next := tm.points[i+1]
+		return (next.BPM - tm.points[i].BPM) / (next.Beat - tm.points[i].Beat)
+	}
+
+	return 0
+}
+
+// secondsIn returns the duration of beats from the start of the segment at point i
+func (tm *TempoMap) secondsIn(i int, beats float64) float64 {
+	bpm := tm.points[i].BPM
+	k := tm.slope(i)
+
+	if math.Abs(k) < 1e-12 {
+		return 60.0 * beats / bpm
+	}
+
+	return 60.0 / k * math.Log((bpm+k*beats)/bpm)
+}
+
+// beatsIn returns the number of beats in seconds from the start of the segment at point i
+func (tm *TempoMap) beatsIn(i int, seconds float64) float64 {
+	bpm := tm.points[i].BPM
+	k := tm.slope(i)
+
+	if math.Abs(k) < 1e-12 {
+		return seconds * bpm / 60.0
+	}
+
+	return bpm * (math.Exp(k*seconds/60.0) - 1) / k
+}
+
+// segmentAtBeat returns the index of the point that starts the segment containing beat
+func (tm *TempoMap) segmentAtBeat(beat float64) int {
+	i, found := slices.BinarySearchFunc(tm.points, beat, func(p TempoPoint, beat float64) int {
+		return cmp.Compare(p.Beat, beat)
+	})
+
+	if found {
+		return i
+	}
+
+	return max(0, i-1)
+}
+
+// BPMAt returns the tempo at beat
+func (tm *TempoMap) BPMAt(beat float64) float64 {
+	i := tm.segmentAtBeat(beat)
+
+	return tm.points[i].BPM + tm.slope(i)*(beat-tm.points[i].Beat)
+}
+
+// SecondsAt returns the time of beat
+func (tm *TempoMap) SecondsAt(beat float64) float64 {
+	if beat <= 0 {
+		return 0
+	}
+
+	i := tm.segmentAtBeat(beat)
+
+	return tm.seconds[i] + tm.secondsIn(i, beat-tm.points[i].Beat)
+}
+
+// BeatAt returns the beat at time seconds, it is the inverse of SecondsAt
+func (tm *TempoMap) BeatAt(seconds float64) float64 {
+	if seconds <= 0 {
+		return 0
+	}
+
+	i, found := slices.BinarySearch(tm.seconds, seconds)
+	if !found {
+		i = max(0, i-1)
+	}
+
+	return tm.points[i].Beat + tm.beatsIn(i, seconds-tm.seconds[i])
+}
@@ -0,0 +1,128 @@
+package muse
+
+// TimedControlReceiver receives control values at a sample offset within the current block
+type TimedControlReceiver interface {
+	ReceiveControlValueAt(value any, index int, offset int)
+}
+
+// TimedMessageReceiver receives messages at a sample offset within the current block
+type TimedMessageReceiver interface {
+	ReceiveMessageAt(msg any, offset int) []*Message
+}
+
+// MessageIndex is the index of a ControlEvent that holds a message instead of a control value
+const MessageIndex = -1
+
+// ControlEvent is a control value or message scheduled on a timeline
+type ControlEvent struct {
+	Index int
+	Value any
+}
+
+// ReceiveControlEvent passes a control event to the receiver as a message or control value
+func ReceiveControlEvent(receiver interface {
+	ControlReceiver
+	MessageReceiver
+}, event ControlEvent) {
+	if event.Index == MessageIndex {
+		receiver.ReceiveMessage(event.Value)
+	} else {
+		receiver.ReceiveControlValue(event.Value, event.Index)
+	}
+}
+
+// maxTimelineEvents limits the number of pending events for modules that are not synthesized
+const maxTimelineEvents = 256
+
+// timelineCapacity is the number of events a timeline holds before it needs to grow
+const timelineCapacity = 16
+
+type timedEvent[E any] struct {
+	offset int
+	event  E
+}
+
+// Timeline lets a module apply events at their sample offset, the module schedules events with At
+// and splits its processing with Render. Events are stored by value so scheduling does not allocate
+type Timeline[E any] struct {
+	events []timedEvent[E]
+	apply  func(E)
+}
+
+// NewTimeline creates a timeline that calls apply for each event when its offset is reached
+func NewTimeline[E any](apply func(E)) Timeline[E] {
+	return Timeline[E]{
+		events: make([]timedEvent[E], 0, timelineCapacity),
+		apply:  apply,
+	}
+}
+
+// At schedules an event at a sample offset within the next synthesized block, the event is applied
+// right away if the offset is not positive. Events with the same offset keep their order
+func (tl *Timeline[E]) At(offset int, event E) {
+	if offset <= 0 {
+		tl.apply(event)
+		return
+	}
+
+	if len(tl.events) >= maxTimelineEvents {
+		// The module is not synthesized, deliver everything now instead of growing forever
+		tl.flush()
+	}
+
+	i := len(tl.events)
+	for i > 0 && tl.events[i-1].offset > offset {
+		i--
+	}
+
+	tl.events = append(tl.events, timedEvent[E]{})
+	copy(tl.events[i+1:], tl.events[i:])
+	tl.events[i] = timedEvent[E]{offset: offset, event: event}
+}
+
+// Clear removes all pending events without applying them
+func (tl *Timeline[E]) Clear() {
+	clear(tl.events)
+	tl.events = tl.events[:0]
+}
+
+// Pending returns true if there are events waiting for the next block
+func (tl *Timeline[E]) Pending() bool {
+	return len(tl.events) > 0
+}
+
+func (tl *Timeline[E]) flush() {
+	for i := range tl.events {
+		tl.apply(tl.events[i].event)
+		tl.events[i] = timedEvent[E]{}
+	}
+
+	tl.events = tl.events[:0]
+}
+
+// Render calls render for each part of a block of length samples, split at the offsets of the
+// pending events. Events are applied between the parts, events beyond the block are applied at the end
+func (tl *Timeline[E]) Render(length int, render func(start int, end int)) {
+	start := 0
+
+	for i := range tl.events {
+		end := tl.events[i].offset
+		if end > length {
+			end = length
+		}
+
+		if end > start {
+			render(start, end)
+			start = end
+		}
+
+		tl.apply(tl.events[i].event)
+		tl.events[i] = timedEvent[E]{}
+	}
+
+	tl.events = tl.events[:0]
+
+	if start < length {
+		render(start, length)
+	}
+}
@@ -0,0 +1,143 @@
+package muse_test
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/almerlucke/muse"
+	"github.com/almerlucke/muse/modules/osc"
+)
+
+func TestTimelineRender(t *testing.T) {
+	var log []string
+
+	tl := muse.NewTimeline(func(event string) {
+		log = append(log, event)
+	})
+
+	tl.At(5, "c")
+	tl.At(2, "a")
+	tl.At(2, "b")
+	tl.At(100, "d")
+	// Events without a positive offset are applied right away
+	tl.At(0, "now")
+
+	if !tl.Pending() {
+		t.Fatal("expected pending events")
+	}
+
+	tl.Render(10, func(start int, end int) {
+		log = append(log, fmt.Sprintf("%d-%d", start, end))
+	})
+
+	expected := []string{"now", "0-2", "a", "b", "2-5", "c", "5-10", "d"}
+	if !slices.Equal(log, expected) {
+		t.Fatalf("rendered %v, expected %v", log, expected)
+	}
+
+	if tl.Pending() {
+		t.Fatal("events are pending after render")
+	}
+
+	// Without events the whole block is rendered at once
+	log = log[:0]
+	tl.Render(10, func(start int, end int) {
+		log = append(log, fmt.Sprintf("%d-%d", start, end))
+	})
+
+	if expected := []string{"0-10"}; !slices.Equal(log, expected) {
+		t.Fatalf("rendered %v, expected %v", log, expected)
+	}
+}
+
+func TestTimelineClear(t *testing.T) {
+	applied := 0
+
+	tl := muse.NewTimeline(func(int) { applied++ })
+	tl.At(3, 1)
+	tl.Clear()
+	tl.Render(10, func(int, int) {})
+
+	if applied != 0 {
+		t.Fatalf("%d cleared events were applied", applied)
+	}
+}
+
+func TestTimelineLimit(t *testing.T) {
+	applied := 0
+
+	tl := muse.NewTimeline(func(int) { applied++ })
+
+	// A timeline that is never rendered delivers its events instead of growing forever
+	for i := 0; i < 1000; i++ {
+		tl.At(1, i)
+	}
+
+	if applied == 0 {
+		t.Fatal("events of a timeline that is not rendered are never applied")
+	}
+
+	tl.Render(10, func(int, int) {})
+
+	if applied != 1000 {
+		t.Fatalf("%d of 1000 events applied", applied)
+	}
+}
+
+// renderFrequencyChange renders a block of an oscillator at 440 Hz after calling change, change
+// sets the frequency to 880 Hz
+func renderFrequencyChange(change func(root *muse.Muse, o muse.Module)) []float64 {
+	root := muse.New(1)
+	o := osc.NewOsc2(440.0, 0, 0.5, 1.0, osc.SINE).Named("osc").AddTo(root)
+	root.In(o)
+
+	change(root, o)
+	root.Synthesize()
+
+	return slices.Clone(root.OutputAtIndex(0).Buffer)
+}
+
+// expectChangeAt checks if the output follows the unchanged output until offset and differs after
+func expectChangeAt(t *testing.T, output []float64, unchanged []float64, offset int) {
+	t.Helper()
+
+	if !slices.Equal(output[:offset], unchanged[:offset]) {
+		t.Fatalf("output changed before offset %d", offset)
+	}
+
+	if slices.Equal(output[offset:], unchanged[offset:]) {
+		t.Fatalf("output did not change after offset %d", offset)
+	}
+}
+
+func TestOffsets(t *testing.T) {
+	const offset = 32
+
+	unchanged := renderFrequencyChange(func(*muse.Muse, muse.Module) {})
+
+	tests := map[string]func(root *muse.Muse, o muse.Module){
+		"control value": func(root *muse.Muse, o muse.Module) {
+			o.(muse.TimedControlReceiver).ReceiveControlValueAt(880.0, 0, offset)
+		},
+		"message": func(root *muse.Muse, o muse.Module) {
+			root.SendMessage(&muse.Message{Address: "osc", Content: map[string]any{"frequency": 880.0}, Offset: offset})
+		},
+		"control connection": func(root *muse.Muse, o muse.Module) {
+			// Controls pass the offset on with the values they send
+			thru := muse.NewControlThru()
+			thru.CtrlConnect(0, o, 0)
+			thru.ReceiveControlValueAt(880.0, 0, offset)
+		},
+		"patch control input": func(root *muse.Muse, o muse.Module) {
+			root.InternalInputControl().CtrlConnect(0, o, 0)
+			root.ReceiveControlValueAt(880.0, 0, offset)
+		},
+	}
+
+	for name, change := range tests {
+		t.Run(name, func(t *testing.T) {
+			expectChangeAt(t, renderFrequencyChange(change), unchanged, offset)
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package muse
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/almerlucke/muse/utils"
+)
+
+// TicksPerBeat is the tick resolution of transport positions
+const TicksPerBeat = 960
+
+// TimeSignature is the number of beats per bar and the note value of a beat, 6/8 has 6 beats of an eighth note
+type TimeSignature struct {
+	Numerator   int `json:"numerator"`
+	Denominator int `json:"denominator"`
+}
+
+// QuartersPerBar returns the length of a bar in quarter notes
+func (ts TimeSignature) QuartersPerBar() float64 {
+	return float64(ts.Numerator) * 4.0 / float64(ts.Denominator)
+}
+
+// Position is a musical position, Bar and Beat start at 1 and Tick counts TicksPerBeat per beat of the time signature
+type Position struct {
+	Bar  int
+	Beat int
+	Tick int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d.%d.%d", p.Bar, p.Beat, p.Tick)
+}
+
+// transportSegment is a continuous part of the current block, a block is split where the transport loops
+type transportSegment struct {
+	start float64
+	end   float64
+	frame int
+	// continues is false if the segment does not continue from where the previous segment ended
+	continues bool
+}
+
+// Transport is the shared musical time of a patch: tempo map, time signature, play state, loop range and song
+// position. Positions are in beats of a quarter note. Every muse owns a transport and advances it at the start of
+// every block, it is passed to the objects of the patch that implement TransportUser. They find the sample offsets of beats within
+// the block with Offset and Steps. Like other patch state it is changed on the audio thread, or with Muse.Post
+// or messages from other goroutines.
+//
+// As message receiver the transport accepts {"play": true}, {"locate": beat}, {"bpm": tempo}, {"loop": [start, end]}
+// and {"loop": false}
+type Transport struct {
+	tempo      *TempoMap
+	signature  TimeSignature
+	playing    bool
+	beat       float64
+	jumped     bool
+	loop       bool
+	loopStart  float64
+	loopEnd    float64
+	sampleRate float64
+	segments   []transportSegment
+}
+
+func NewTransport(bpm float64) *Transport {
+	return &Transport{
+		tempo:      NewTempoMap(bpm),
+		signature:  TimeSignature{Numerator: 4, Denominator: 4},
+		sampleRate: SampleRate(),
+		jumped:     true,
+	}
+}
+
+func (t *Transport) TempoMap() *TempoMap {
+	return t.tempo
+}
+
+// BPM returns the tempo at the current position
+func (t *Transport) BPM() float64 {
+	return t.tempo.BPMAt(t.Beat())
+}
+
+// SetBPM replaces the tempo map with a constant tempo
+func (t *Transport) SetBPM(bpm float64) {
+	t.tempo.setConstant(bpm)
+}
+
+func (t *Transport) TimeSignature() TimeSignature {
+	return t.signature
+}
+
+func (t *Transport) SetTimeSignature(numerator int, denominator int) {
+	if numerator > 0 && denominator > 0 {
+		t.signature = TimeSignature{Numerator: numerator, Denominator: denominator}
+	}
+}
+
+func (t *Transport) Playing() bool {
+	return t.playing
+}
+
+// Play starts playing from the current position
+func (t *Transport) Play() {
+	if !t.playing {
+		t.playing = true
+		t.jumped = true
+	}
+}
+
+func (t *Transport) Stop() {
+	t.playing = false
+}
+
+// Locate moves the position to beat
+func (t *Transport) Locate(beat float64) {
+	t.beat = math.Max(0, beat)
+	t.jumped = true
+}
+
+// LocatePosition moves the position to a bar, beat and tick
+func (t *Transport) LocatePosition(pos Position) {
+	t.Locate(t.BeatOf(pos))
+}
+
+// SetLoop loops the range start - end in beats while playing
+func (t *Transport) SetLoop(start float64, end float64) {
+	if end > start && start >= 0 {
+		t.loop = true
+		t.loopStart = start
+		t.loopEnd = end
+	}
+}
+
+func (t *Transport) ClearLoop() {
+	t.loop = false
+}
+
+// Loop returns the loop range and true if looping is on
+func (t *Transport) Loop() (float64, float64, bool) {
+	return t.loopStart, t.loopEnd, t.loop
+}
+
+// Beat returns the position at the start of the current block in beats
+func (t *Transport) Beat() float64 {
+	if len(t.segments) > 0 {
+		return t.segments[0].start
+	}
+
+	return t.beat
+}
+
+// Seconds returns the time of the current position
+func (t *Transport) Seconds() float64 {
+	return t.tempo.SecondsAt(t.Beat())
+}
+
+// Position returns the current position in bars, beats and ticks
+func (t *Transport) Position() Position {
+	return t.PositionOf(t.Beat())
+}
+
+// PositionOf converts beat to bars, beats and ticks of the time signature
+func (t *Transport) PositionOf(beat float64) Position {
+	quartersPerBar := t.signature.QuartersPerBar()
+	bar := math.Floor(beat / quartersPerBar)
+	inBar := (beat - bar*quartersPerBar) * float64(t.signature.Denominator) / 4.0
+	beatInBar := math.Floor(inBar)
+
+	return Position{
+		Bar:  int(bar) + 1,
+		Beat: int(beatInBar) + 1,
+		Tick: int((inBar - beatInBar) * TicksPerBeat),
+	}
+}
+
+// BeatOf converts a position in bars, beats and ticks to beats
+func (t *Transport) BeatOf(pos Position) float64 {
+	inBar := float64(pos.Beat-1) + float64(pos.Tick)/TicksPerBeat
+
+	return float64(pos.Bar-1)*t.signature.QuartersPerBar() + inBar*4.0/float64(t.signature.Denominator)
+}
+
+// Advance moves the transport over a block of numFrames samples, it is called by the muse before the
+// messengers and controls of the block run
+func (t *Transport) Advance(numFrames int, sampleRate float64) {
+	t.sampleRate = sampleRate
+	t.segments = t.segments[:0]
+
+	if !t.playing {
+		return
+	}
+
+	beat := t.beat
+	continues := !t.jumped
+	frame := 0
+
+	t.jumped = false
+
+	for frame < numFrames {
+		startSeconds := t.tempo.SecondsAt(beat)
+		end := t.tempo.BeatAt(startSeconds + float64(numFrames-frame)/sampleRate)
+
+		if t.loop && beat < t.loopEnd && end >= t.loopEnd {
+			t.segments = append(t.segments, transportSegment{start: beat, end: t.loopEnd, frame: frame, continues: continues})
+			frame += int(math.Ceil((t.tempo.SecondsAt(t.loopEnd) - startSeconds) * sampleRate))
+			beat = t.loopStart
+			continues = false
+
+			continue
+		}
+
+		t.segments = append(t.segments, transportSegment{start: beat, end: end, frame: frame, continues: continues})
+		beat = end
+
+		break
+	}
+
+	t.beat = beat
+}
+
+func (t *Transport) offset(seg transportSegment, beat float64) int {
+	return seg.frame + int((t.tempo.SecondsAt(beat)-t.tempo.SecondsAt(seg.start))*t.sampleRate)
+}
+
+// Offset returns the sample offset of beat within the current block, false if beat is not played in the block
+func (t *Transport) Offset(beat float64) (int, bool) {
+	for _, seg := range t.segments {
+		if beat >= seg.start && beat < seg.end {
+			return t.offset(seg, beat), true
+		}
+	}
+
+	return 0, false
+}
+
+// Steps calls step for every beat within the current block starting at next, step returns the beat of the
+// following step. After a jump like a locate or a loop the steps restart at the new position. Steps returns
+// the beat of the first step after the block, pass it as next for the next block
+func (t *Transport) Steps(next float64, step func(beat float64, offset int) float64) float64 {
+	for _, seg := range t.segments {
+		if !seg.continues || next < seg.start {
+			next = seg.start
+		}
+
+		for next < seg.end {
+			following := step(next, t.offset(seg, next))
+			if following <= next {
+				// A step without duration would never end
+				return following
+			}
+
+			next = following
+		}
+	}
+
+	return next
+}
+
+// Parameters describes the messages the transport receives
+func (t *Transport) Parameters() []Parameter {
+	start, end, _ := t.Loop()
+
+	return []Parameter{
+		{Name: "play", ControlIndex: -1, MessageKey: "play", Type: ParameterBool, Default: false, Value: t.playing},
+		{Name: "locate", ControlIndex: -1, MessageKey: "locate", Type: ParameterFloat, Min: 0, Max: 10000, Default: 0.0, Unit: "beats", Value: t.Beat()},
+		{Name: "bpm", ControlIndex: -1, MessageKey: "bpm", Type: ParameterFloat, Min: 20, Max: 300, Default: 120.0, Unit: "bpm", Value: t.BPM()},
+		{Name: "loop", ControlIndex: -1, MessageKey: "loop", Type: ParameterAny, Value: []any{start, end, t.loop}},
+	}
+}
+
+func (t *Transport) ReceiveMessage(msg any) []*Message {
+	content, ok := DecodeMessage(t, msg)
+	if !ok {
+		return nil
+	}
+
+	if locate, ok := content.Float("locate"); ok {
+		t.Locate(locate)
+	}
+
+	if bpm, ok := content.Float("bpm"); ok && bpm > 0 {
+		t.SetBPM(bpm)
+	}
+
+	if play, ok := content.Bool("play"); ok {
+		if play {
+			t.Play()
+		} else {
+			t.Stop()
+		}
+	}
+
+	content.Receive("loop", func(v any) bool {
+		if v == false {
+			t.ClearLoop()
+			return true
+		}
+
+		loop, ok := v.([]any)
+		if !ok || len(loop) != 2 {
+			return false
+		}
+
+		start, ok1 := utils.ToFloat(loop[0])
+		end, ok2 := utils.ToFloat(loop[1])
+		if ok1 && ok2 {
+			t.SetLoop(start, end)
+		}
+
+		return ok1 && ok2
+	})
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package muse_test
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/almerlucke/muse"
+)
+
+const transportSampleRate = 48000.0
+
+func expectClose(t *testing.T, name string, value float64, expected float64) {
+	t.Helper()
+
+	if math.Abs(value-expected) > 1e-9 {
+		t.Fatalf("%s is %v, expected %v", name, value, expected)
+	}
+}
+
+func TestTempoMap(t *testing.T) {
+	tm := muse.NewTempoMap(120)
+
+	expectClose(t, "seconds at beat 4", tm.SecondsAt(4), 2)
+
+	// From beat 4 on a beat lasts a second
+	tm.SetTempo(4, 60)
+	expectClose(t, "seconds at beat 6", tm.SecondsAt(6), 4)
+	expectClose(t, "bpm at beat 5", tm.BPMAt(5), 60)
+
+	// Ramp from 60 bpm at beat 4 to 120 bpm at beat 8, the tempo rises 15 bpm per beat
+	tm.RampTempo(8, 120)
+	expectClose(t, "bpm at beat 6", tm.BPMAt(6), 90)
+	expectClose(t, "seconds at beat 8", tm.SecondsAt(8), 2+4*math.Log(2))
+	expectClose(t, "seconds at beat 10", tm.SecondsAt(10), 3+4*math.Log(2))
+
+	for _, beat := range []float64{0, 1, 4, 5.5, 8, 12.25} {
+		expectClose(t, fmt.Sprintf("beat at the seconds of beat %v", beat), tm.BeatAt(tm.SecondsAt(beat)), beat)
+	}
+
+	// Setting a point at the same beat replaces it
+	tm.SetTempo(4, 30)
+
+	if points := tm.Points(); len(points) != 3 || points[1] != (muse.TempoPoint{Beat: 4, BPM: 30}) {
+		t.Fatalf("points %v", points)
+	}
+
+	// Invalid points are ignored and the first point starts at beat 0
+	tm.SetPoints([]muse.TempoPoint{{Beat: 2, BPM: 90}, {Beat: 1, BPM: 0}})
+	expectClose(t, "bpm at beat 0", tm.BPMAt(0), 90)
+	expectClose(t, "seconds at beat 3", tm.SecondsAt(3), 2)
+}
+
+func TestSetBPMDoesNotAllocate(t *testing.T) {
+	tr := muse.NewTransport(120)
+	tr.TempoMap().SetTempo(4, 60)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		tr.SetBPM(90)
+		tr.TempoMap().SetTempo(4, 100)
+	})
+
+	if allocs != 0 {
+		t.Fatalf("changing the tempo allocates %v times", allocs)
+	}
+}
+
+type step struct {
+	beat   float64
+	offset int
+}
+
+// sixteenthSteps returns the sixteenth note steps within the current block and the next step
+func sixteenthSteps(tr *muse.Transport, next float64) ([]step, float64) {
+	var steps []step
+
+	next = tr.Steps(next, func(beat float64, offset int) float64 {
+		steps = append(steps, step{beat: beat, offset: offset})
+		return beat + 0.25
+	})
+
+	return steps, next
+}
+
+func expectSteps(t *testing.T, steps []step, expected []step) {
+	t.Helper()
+
+	if !slices.Equal(steps, expected) {
+		t.Fatalf("steps %v, expected %v", steps, expected)
+	}
+}
+
+func TestTransportSegments(t *testing.T) {
+	// A block of 24000 frames lasts a beat at 120 bpm
+	const frames = 24000
+
+	tr := muse.NewTransport(120)
+
+	tr.Advance(frames, transportSampleRate)
+
+	if _, ok := tr.Offset(0); ok {
+		t.Fatal("a stopped transport plays beats")
+	}
+
+	tr.Play()
+	tr.Advance(frames, transportSampleRate)
+
+	steps, next := sixteenthSteps(tr, 0)
+	expectSteps(t, steps, []step{{0, 0}, {0.25, 6000}, {0.5, 12000}, {0.75, 18000}})
+	expectClose(t, "next step", next, 1)
+
+	// The block wraps around at the end of the loop
+	tr.SetLoop(0, 1.5)
+	tr.Advance(frames, transportSampleRate)
+	expectClose(t, "beat", tr.Beat(), 1)
+
+	steps, next = sixteenthSteps(tr, next)
+	expectSteps(t, steps, []step{{1, 0}, {1.25, 6000}, {0, 12000}, {0.25, 18000}})
+	expectClose(t, "next step", next, 0.5)
+
+	if offset, ok := tr.Offset(0.25); !ok || offset != 18000 {
+		t.Fatalf("offset of beat 0.25 is %v %v, expected 18000", offset, ok)
+	}
+
+	// A locate restarts the steps at the new position
+	tr.ClearLoop()
+	tr.Locate(8)
+	tr.Advance(frames, transportSampleRate)
+
+	steps, _ = sixteenthSteps(tr, next)
+	expectSteps(t, steps, []step{{8, 0}, {8.25, 6000}, {8.5, 12000}, {8.75, 18000}})
+
+	// At half the tempo a block lasts half a beat
+	tr.SetBPM(60)
+	tr.Advance(frames, transportSampleRate)
+
+	steps, _ = sixteenthSteps(tr, 9)
+	expectSteps(t, steps, []step{{9, 0}, {9.25, 12000}})
+}
+
+func TestTransportPositions(t *testing.T) {
+	tr := muse.NewTransport(120)
+
+	tests := []struct {
+		numerator   int
+		denominator int
+		beat        float64
+		position    muse.Position
+	}{
+		{4, 4, 0, muse.Position{Bar: 1, Beat: 1}},
+		{4, 4, 5.5, muse.Position{Bar: 2, Beat: 2, Tick: muse.TicksPerBeat / 2}},
+		{6, 8, 3.5, muse.Position{Bar: 2, Beat: 2}},
+		{3, 4, 7.25, muse.Position{Bar: 3, Beat: 2, Tick: muse.TicksPerBeat / 4}},
+	}
+
+	for _, test := range tests {
+		tr.SetTimeSignature(test.numerator, test.denominator)
+
+		if position := tr.PositionOf(test.beat); position != test.position {
+			t.Errorf("%d/%d: position of beat %v is %v, expected %v", test.numerator, test.denominator, test.beat, position, test.position)
+		}
+
+		if beat := tr.BeatOf(test.position); beat != test.beat {
+			t.Errorf("%d/%d: beat of %v is %v, expected %v", test.numerator, test.denominator, test.position, beat, test.beat)
+		}
+	}
+}
+
+func TestTransportMessages(t *testing.T) {
+	tr := muse.NewTransport(120)
+
+	tr.ReceiveMessage(map[string]any{"play": true, "locate": 2.0, "bpm": 60.0, "loop": []any{2, 4}})
+
+	if start, end, loop := tr.Loop(); !tr.Playing() || tr.Beat() != 2 || tr.BPM() != 60 || start != 2 || end != 4 || !loop {
+		t.Fatalf("playing %v at beat %v with %v bpm, loop %v - %v %v", tr.Playing(), tr.Beat(), tr.BPM(), start, end, loop)
+	}
+
+	tr.ReceiveMessage(map[string]any{"play": false, "loop": false})
+
+	if _, _, loop := tr.Loop(); tr.Playing() || loop {
+		t.Fatalf("playing %v, loop %v after stop", tr.Playing(), loop)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"fyne.io/fyne/v2/widget"
 
 	adsrc "github.com/almerlucke/genny/float/envelopes/adsr"
+	"github.com/almerlucke/muse"
 	"github.com/almerlucke/muse/ui"
 )
 
@@ -31,6 +32,7 @@ type Control struct {
 	releaseDurationSliderBinding binding.Float
 	releaseShapeLabelBinding     binding.String
 	releaseShapeSliderBinding    binding.Float
+	muse                         *muse.Muse
 }
 
 func (ctrl *Control) UI() fyne.CanvasObject {
@@ -45,7 +47,7 @@ func (ctrl *Control) UI() fyne.CanvasObject {
 		v, err := ctrl.attackDurationSliderBinding.Get()
 		if err == nil {
 			_ = ctrl.attackDurationLabelBinding.Set(fmt.Sprintf("%.2f", v))
-			ctrl.setting.AttackDuration = v
+			ctrl.apply(func() { ctrl.setting.AttackDuration = v })
 		}
 	}))
 	attackDurationSlider := widget.NewSliderWithData(1.0, 500.0, ctrl.attackDurationSliderBinding)
@@ -62,7 +64,7 @@ func (ctrl *Control) UI() fyne.CanvasObject {
 		v, err := ctrl.attackLevelSliderBinding.Get()
 		if err == nil {
 			_ = ctrl.attackLevelLabelBinding.Set(fmt.Sprintf("%.2f", v))
-			ctrl.setting.AttackLevel = v
+			ctrl.apply(func() { ctrl.setting.AttackLevel = v })
 		}
 	}))
 	attackLevelSlider := widget.NewSliderWithData(0.0, 1.0, ctrl.attackLevelSliderBinding)
@@ -80,7 +82,7 @@ func (ctrl *Control) UI() fyne.CanvasObject {
 		v, err := ctrl.attackShapeSliderBinding.Get()
 		if err == nil {
 			_ = ctrl.attackShapeLabelBinding.Set(fmt.Sprintf("%.2f", v))
-			ctrl.setting.AttackShape = v
+			ctrl.apply(func() { ctrl.setting.AttackShape = v })
 		}
 	}))
 	attackShapeSlider := widget.NewSliderWithData(-1.0, 1.0, ctrl.attackShapeSliderBinding)
@@ -99,7 +101,7 @@ func (ctrl *Control) UI() fyne.CanvasObject {
 		v, err := ctrl.decayDurationSliderBinding.Get()
 		if err == nil {
 			_ = ctrl.decayDurationLabelBinding.Set(fmt.Sprintf("%.2f", v))
-			ctrl.setting.DecayDuration = v
+			ctrl.apply(func() { ctrl.setting.DecayDuration = v })
 		}
 	}))
 	decayDurationSlider := widget.NewSliderWithData(1.0, 500.0, ctrl.decayDurationSliderBinding)
@@ -116,7 +118,7 @@ func (ctrl *Control) UI() fyne.CanvasObject {
 		v, err := ctrl.decayLevelSliderBinding.Get()
 		if err == nil {
 			_ = ctrl.decayLevelLabelBinding.Set(fmt.Sprintf("%.2f", v))
-			ctrl.setting.DecayLevel = v
+			ctrl.apply(func() { ctrl.setting.DecayLevel = v })
 		}
 	}))
 	decayLevelSlider := widget.NewSliderWithData(0.0, 1.0, ctrl.decayLevelSliderBinding)
@@ -134,7 +136,7 @@ func (ctrl *Control) UI() fyne.CanvasObject {
 		v, err := ctrl.decayShapeSliderBinding.Get()
 		if err == nil {
 			_ = ctrl.decayShapeLabelBinding.Set(fmt.Sprintf("%.2f", v))
-			ctrl.setting.DecayShape = v
+			ctrl.apply(func() { ctrl.setting.DecayShape = v })
 		}
 	}))
 	decayShapeSlider := widget.NewSliderWithData(-1.0, 1.0, ctrl.decayShapeSliderBinding)
@@ -152,7 +154,7 @@ func (ctrl *Control) UI() fyne.CanvasObject {
 		v, err := ctrl.releaseDurationSliderBinding.Get()
 		if err == nil {
 			_ = ctrl.releaseDurationLabelBinding.Set(fmt.Sprintf("%.2f", v))
-			ctrl.setting.ReleaseDuration = v
+			ctrl.apply(func() { ctrl.setting.ReleaseDuration = v })
 		}
 	}))
 	releaseDurationSlider := widget.NewSliderWithData(50.0, 2500.0, ctrl.releaseDurationSliderBinding)
@@ -170,7 +172,7 @@ func (ctrl *Control) UI() fyne.CanvasObject {
 		v, err := ctrl.releaseShapeSliderBinding.Get()
 		if err == nil {
 			_ = ctrl.releaseShapeLabelBinding.Set(fmt.Sprintf("%.2f", v))
-			ctrl.setting.ReleaseShape = v
+			ctrl.apply(func() { ctrl.setting.ReleaseShape = v })
 		}
 	}))
 	releaseShapeSlider := widget.NewSliderWithData(-1.0, 1.0, ctrl.releaseShapeSliderBinding)
@@ -216,61 +218,81 @@ func (ctrl *Control) Setting() *adsrc.Setting {
 }
 
 func (ctrl *Control) SetAttackDuration(ms float64) {
-	ctrl.setting.AttackDuration = ms
+	ctrl.apply(func() { ctrl.setting.AttackDuration = ms })
 	if ctrl.attackDurationSliderBinding != nil {
 		_ = ctrl.attackDurationSliderBinding.Set(ms)
 	}
 }
 
 func (ctrl *Control) SetAttackLevel(level float64) {
-	ctrl.setting.AttackLevel = level
+	ctrl.apply(func() { ctrl.setting.AttackLevel = level })
 	if ctrl.attackLevelSliderBinding != nil {
 		_ = ctrl.attackLevelSliderBinding.Set(level)
 	}
 }
 
 func (ctrl *Control) SetAttackShape(shape float64) {
-	ctrl.setting.AttackShape = shape
+	ctrl.apply(func() { ctrl.setting.AttackShape = shape })
 	if ctrl.attackShapeSliderBinding != nil {
 		_ = ctrl.attackShapeSliderBinding.Set(shape)
 	}
 }
 
 func (ctrl *Control) SetDecayDuration(ms float64) {
-	ctrl.setting.DecayDuration = ms
+	ctrl.apply(func() { ctrl.setting.DecayDuration = ms })
 	if ctrl.decayDurationSliderBinding != nil {
 		_ = ctrl.decayDurationSliderBinding.Set(ms)
 	}
 }
 
 func (ctrl *Control) SetDecayLevel(level float64) {
-	ctrl.setting.DecayLevel = level
+	ctrl.apply(func() { ctrl.setting.DecayLevel = level })
 	if ctrl.decayLevelSliderBinding != nil {
 		_ = ctrl.decayLevelSliderBinding.Set(level)
 	}
 }
 
 func (ctrl *Control) SetDecayShape(shape float64) {
-	ctrl.setting.DecayShape = shape
+	ctrl.apply(func() { ctrl.setting.DecayShape = shape })
 	if ctrl.decayShapeSliderBinding != nil {
 		_ = ctrl.decayShapeSliderBinding.Set(shape)
 	}
 }
 
 func (ctrl *Control) SetReleaseDuration(ms float64) {
-	ctrl.setting.ReleaseDuration = ms
+	ctrl.apply(func() { ctrl.setting.ReleaseDuration = ms })
 	if ctrl.releaseDurationSliderBinding != nil {
 		_ = ctrl.releaseDurationSliderBinding.Set(ms)
 	}
 }
 
 func (ctrl *Control) SetReleaseShape(shape float64) {
-	ctrl.setting.ReleaseShape = shape
+	ctrl.apply(func() { ctrl.setting.ReleaseShape = shape })
 	if ctrl.releaseShapeSliderBinding != nil {
 		_ = ctrl.releaseShapeSliderBinding.Set(shape)
 	}
 }
 
+// PostTo posts changes to the setting to the audio thread of m, without it the setting is changed directly which
+// is only safe while the audio is not running
+func (ctrl *Control) PostTo(m *muse.Muse) *Control {
+	ctrl.muse = m
+	return ctrl
+}
+
+// apply changes the setting on the audio thread if the control posts its changes
+func (ctrl *Control) apply(f func()) {
+	if ctrl.muse == nil {
+		f()
+		return
+	}
+
+	_ = ctrl.muse.Post(func(*muse.Muse) error {
+		f()
+		return nil
+	})
+}
+
 func NewADSRControl(title string, setting *adsrc.Setting) *Control {
 	control := &Control{
 		title:   title,
@@ -0,0 +1,67 @@
+package queue
+
+import "sync/atomic"
+
+// Ring is a lock-free single producer single consumer queue with a fixed capacity,
+// Push may only be called from one goroutine and Pop from one other goroutine
+type Ring[T any] struct {
+	buf  []T
+	mask uint64
+	// head is the next position to read, only written by the consumer
+	head atomic.Uint64
+	// tail is the next position to write, only written by the producer
+	tail atomic.Uint64
+}
+
+// NewRing creates a ring, the capacity is rounded up to a power of two
+func NewRing[T any](capacity int) *Ring[T] {
+	size := 1
+	for size < capacity {
+		size <<= 1
+	}
+
+	return &Ring[T]{
+		buf:  make([]T, size),
+		mask: uint64(size - 1),
+	}
+}
+
+// Capacity returns the maximum number of elements in the ring
+func (r *Ring[T]) Capacity() int {
+	return len(r.buf)
+}
+
+// Len returns the number of elements in the ring
+func (r *Ring[T]) Len() int {
+	return int(r.tail.Load() - r.head.Load())
+}
+
+// Push adds an element to the ring, returns false if the ring is full
+func (r *Ring[T]) Push(v T) bool {
+	tail := r.tail.Load()
+	if tail-r.head.Load() == uint64(len(r.buf)) {
+		return false
+	}
+
+	r.buf[tail&r.mask] = v
+	r.tail.Store(tail + 1)
+
+	return true
+}
+
+// Pop removes the oldest element from the ring, returns false if the ring is empty
+func (r *Ring[T]) Pop() (T, bool) {
+	var zero T
+
+	head := r.head.Load()
+	if head == r.tail.Load() {
+		return zero, false
+	}
+
+	v := r.buf[head&r.mask]
+	// Release the reference so it can be garbage collected
+	r.buf[head&r.mask] = zero
+	r.head.Store(head + 1)
+
+	return v, true
+}
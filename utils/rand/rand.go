@@ -1,14 +1,50 @@
 package rand
 
 import (
+	"hash/fnv"
 	"math"
-	"math/rand"
+	mathrand "math/rand"
+	"sync/atomic"
+	"time"
 )
 
 const (
 	defaultSeed uint64 = 1
 )
 
+// autoSeed is the source of AutoSeed, it starts at the time the program starts
+var autoSeed atomic.Uint64
+
+func init() {
+	autoSeed.Store(uint64(time.Now().UnixNano()))
+}
+
+// mix is the splitmix64 finalizer, it spreads the bits of x over the whole result
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return x
+}
+
+// AutoSeed returns a different seed on every call, for random sources that are not seeded explicitly.
+// Seed them with SetSeed to get the same random values on every run
+func AutoSeed() uint64 {
+	return mix(autoSeed.Add(0x9e3779b97f4a7c15))
+}
+
+// DeriveSeed returns the seed of the child with key of a random source with seed, the same seed and key
+// always derive the same child seed
+func DeriveSeed(seed uint64, key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+
+	return mix(seed ^ mix(h.Sum64()))
+}
+
 type Rand struct {
 	u, v, w uint64
 }
@@ -23,6 +59,11 @@ func NewRandWithSeed(seed uint64) *Rand {
 	return r
 }
 
+// Seed restarts the random sequence of r from seed
+func (r *Rand) Seed(s uint64) {
+	r.seed(s)
+}
+
 func (r *Rand) seed(s uint64) {
 	r.v = 4101842887655102017
 	r.w = 1
@@ -53,16 +94,38 @@ func (r *Rand) RandFloat() float64 {
 	return 5.42101086242752217e-20 * float64(r.RandInt())
 }
 
+// Intn Random number between [0, n)
+func (r *Rand) Intn(n int) int {
+	return int(r.RandInt() % uint64(n))
+}
+
+// source is a random source for the cluster functions
+type source interface {
+	RandFloat() float64
+	Intn(n int) int
+}
+
+// globalRand draws from the global math/rand source
+type globalRand struct{}
+
+func (globalRand) RandFloat() float64 {
+	return mathrand.Float64()
+}
+
+func (globalRand) Intn(n int) int {
+	return mathrand.Intn(n)
+}
+
 // distribution 0 -> center
 // distribution 1 -> center +- width random 100%
-func randCenter(center float64, width float64, distribution float64) float64 {
+func randCenter(r source, center float64, width float64, distribution float64) float64 {
 	mult := 0.0
 
 	if distribution >= 0.001 {
-		mult = 1.0 - math.Pow(rand.Float64(), distribution)
+		mult = 1.0 - math.Pow(r.RandFloat(), distribution)
 	}
 
-	left := rand.Intn(2)
+	left := r.Intn(2)
 	out := 0.0
 	if left == 0 {
 		out = center - width*0.5*mult
@@ -78,17 +141,27 @@ type Cluster struct {
 	Width  float64
 }
 
+// Rand draws from the global math/rand source, a ClusterRand draws from its own seeded source
 func (c *Cluster) Rand(distribution float64) float64 {
-	return randCenter(c.Center, c.Width, distribution)
+	return randCenter(globalRand{}, c.Center, c.Width, distribution)
+}
+
+func (c *Cluster) rand(r source, distribution float64) float64 {
+	return randCenter(r, c.Center, c.Width, distribution)
 }
 
 type Clusters []*Cluster
 
+// Rand draws from the global math/rand source, a ClusterRand draws from its own seeded source
 func (clusters Clusters) Rand(clusterDistribution float64, valueDistribution float64) float64 {
+	return clusters.rand(globalRand{}, clusterDistribution, valueDistribution)
+}
+
+func (clusters Clusters) rand(r source, clusterDistribution float64, valueDistribution float64) float64 {
 	nf := float64(len(clusters))
-	clusterIndex := int(randCenter(nf/2.0, nf, clusterDistribution))
+	clusterIndex := int(randCenter(r, nf/2.0, nf, clusterDistribution))
 	cluster := clusters[clusterIndex]
-	return cluster.Rand(valueDistribution)
+	return cluster.rand(r, valueDistribution)
 }
 
 type ClusterRand struct {
@@ -99,10 +172,11 @@ type ClusterRand struct {
 	valueDistribution   float64
 	clusterDistribution float64
 	needUpdate          bool
+	rand                *Rand
 }
 
 func NewClusterRand(center float64, width float64, density float64, valueDistribution float64, clusterDistribution float64) *ClusterRand {
-	cl := &ClusterRand{}
+	cl := &ClusterRand{rand: NewRandWithSeed(AutoSeed())}
 	cl.SetCenter(center)
 	cl.SetWidth(width)
 	cl.SetDensity(density)
@@ -190,6 +264,10 @@ func (c *ClusterRand) SetClusterDistribution(dist float64) {
 	c.clusterDistribution = dist
 }
 
+func (c *ClusterRand) SetSeed(seed uint64) {
+	c.rand.Seed(seed)
+}
+
 func (c *ClusterRand) Rand() float64 {
-	return c.clusters.Rand(c.clusterDistribution, c.valueDistribution)
+	return c.clusters.rand(c.rand, c.clusterDistribution, c.valueDistribution)
 }
@@ -0,0 +1,152 @@
+package workers
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Task is a unit of parallel work, RunTask is called exactly once for each index of a run
+type Task interface {
+	RunTask(index int)
+}
+
+// Batch runs a task over a range of indices, a batch is reused for every run so running
+// does not allocate. A batch must not be run concurrently with itself
+type Batch struct {
+	task Task
+	n    atomic.Int64
+	// state holds the generation in the high 32 bits and the next index in the low 32 bits,
+	// helpers that wake up for an older generation can not claim indices of a newer run
+	state atomic.Uint64
+	wg    sync.WaitGroup
+}
+
+// NewBatch creates a reusable batch for a task
+func NewBatch(task Task) *Batch {
+	return &Batch{task: task}
+}
+
+// claim claims the next index for generation gen
+func (b *Batch) claim(gen uint64) (int, bool) {
+	for {
+		s := b.state.Load()
+		if s>>32 != gen || int64(uint32(s)) >= b.n.Load() {
+			return 0, false
+		}
+
+		// A stale helper can read the size of a newer run, the swap fails in that case
+		if b.state.CompareAndSwap(s, s+1) {
+			return int(uint32(s)), true
+		}
+	}
+}
+
+// work runs indices of generation gen until all are claimed
+func (b *Batch) work(gen uint64) {
+	for {
+		index, ok := b.claim(gen)
+		if !ok {
+			return
+		}
+
+		b.task.RunTask(index)
+		b.wg.Done()
+	}
+}
+
+type token struct {
+	batch *Batch
+	gen   uint64
+}
+
+// Pool is a fixed set of worker goroutines, the goroutine that runs a batch takes part in
+// the work itself so runs can be nested (for instance a voice of a polyphony inside a
+// patch that is rendered in parallel) without the risk of a deadlock
+type Pool struct {
+	numWorkers int
+	tokens     chan token
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+// New creates a pool with numWorkers goroutines, if numWorkers <= 0 one worker less
+// than the number of CPUs is used because the calling goroutine also does work
+func New(numWorkers int) *Pool {
+	if numWorkers <= 0 {
+		numWorkers = runtime.NumCPU() - 1
+	}
+
+	pool := &Pool{
+		numWorkers: numWorkers,
+		tokens:     make(chan token, numWorkers*4),
+		done:       make(chan struct{}),
+	}
+
+	for i := 0; i < numWorkers; i++ {
+		go pool.worker()
+	}
+
+	return pool
+}
+
+func (pool *Pool) worker() {
+	for {
+		select {
+		case t := <-pool.tokens:
+			t.batch.work(t.gen)
+		case <-pool.done:
+			return
+		}
+	}
+}
+
+// NumWorkers returns the number of worker goroutines
+func (pool *Pool) NumWorkers() int {
+	if pool == nil {
+		return 0
+	}
+
+	return pool.numWorkers
+}
+
+// Close stops all workers, the pool must not be used after closing
+func (pool *Pool) Close() {
+	pool.closeOnce.Do(func() {
+		close(pool.done)
+	})
+}
+
+// Run calls the task of the batch for indices 0 to n-1 and returns when all calls are
+// done. A nil pool runs all indices in order on the calling goroutine
+func (pool *Pool) Run(b *Batch, n int) {
+	if pool == nil || pool.numWorkers == 0 || n < 2 {
+		for i := 0; i < n; i++ {
+			b.task.RunTask(i)
+		}
+
+		return
+	}
+
+	gen := (b.state.Load() >> 32) + 1
+
+	b.n.Store(int64(n))
+	b.wg.Add(n)
+	b.state.Store(gen << 32)
+
+	// Wake up helpers, skip if all workers are already busy
+	numHelpers := n - 1
+	if numHelpers > pool.numWorkers {
+		numHelpers = pool.numWorkers
+	}
+
+	for i := 0; i < numHelpers; i++ {
+		select {
+		case pool.tokens <- token{batch: b, gen: gen}:
+		default:
+		}
+	}
+
+	b.work(gen)
+	b.wg.Wait()
+}
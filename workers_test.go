@@ -0,0 +1,94 @@
+package muse_test
+
+import (
+	"testing"
+
+	adsrc "github.com/almerlucke/genny/float/envelopes/adsr"
+	"github.com/almerlucke/muse"
+	"github.com/almerlucke/muse/modules/effects/freeverb"
+	"github.com/almerlucke/muse/modules/filters/moog"
+	"github.com/almerlucke/muse/modules/mixer"
+	"github.com/almerlucke/muse/modules/noise"
+	"github.com/almerlucke/muse/modules/osc"
+	"github.com/almerlucke/muse/synths/classic"
+)
+
+// newWorkersPatch builds a patch with independent branches and a polyphonic synth
+func newWorkersPatch() (*muse.Muse, *classic.Synth) {
+	root := muse.New(2)
+
+	env := adsrc.NewSetting(1.0, 5.0, 0.3, 20.0, 0.0, 100.0)
+	synth := classic.New(8, env, env, &moog.Factory{}, moog.DefaultConfig()).AddTo(root).(*classic.Synth)
+
+	mix := mixer.New(5)
+	mix.SetMix([]float64{0.2, 0.2, 0.2, 0.1, 0.3})
+	mix.AddTo(root)
+
+	for i := 0; i < 3; i++ {
+		o := osc.New(110.0*float64(i+1), 0).AddTo(root)
+		filter := moog.New(800.0*float64(i+1), 0.5, 1.0).AddTo(root).In(o)
+		mix.In(filter, 0, i)
+	}
+
+	n := noise.New(1).AddTo(root)
+	mix.In(moog.New(2000.0, 0.3, 1.0).AddTo(root).In(n), 0, 3)
+	mix.In(synth, 0, 4)
+
+	reverb := freeverb.New().AddTo(root).In(mix, mix)
+	root.In(reverb, reverb, 1)
+
+	return root, synth
+}
+
+// renderWorkersPatch renders blocks of the patch and triggers notes at offsets within the blocks
+func renderWorkersPatch(numWorkers int, blocks int) [][]float64 {
+	root, synth := newWorkersPatch()
+	root.SetWorkers(numWorkers)
+	defer root.SetWorkers(0)
+
+	outputs := make([][]float64, root.NumOutputs())
+
+	for block := 0; block < blocks; block++ {
+		if block%3 == 0 {
+			synth.ReceiveMessageAt(map[string]any{
+				"command":   "trigger",
+				"duration":  50.0,
+				"amplitude": 0.5,
+				"message":   map[string]any{"frequency": 220.0 + 20.0*float64(block)},
+			}, block%root.Config.BufferSize)
+		}
+
+		root.Synthesize()
+
+		for i := range outputs {
+			outputs[i] = append(outputs[i], root.OutputAtIndex(i).Buffer...)
+		}
+	}
+
+	return outputs
+}
+
+func TestWorkersRenderIdentical(t *testing.T) {
+	const blocks = 64
+
+	serial := renderWorkersPatch(0, blocks)
+	parallel := renderWorkersPatch(4, blocks)
+
+	nonZero := false
+
+	for i := range serial {
+		for j := range serial[i] {
+			if serial[i][j] != parallel[i][j] {
+				t.Fatalf("output %d differs at frame %d: %v without workers, %v with workers", i, j, serial[i][j], parallel[i][j])
+			}
+
+			if serial[i][j] != 0 {
+				nonZero = true
+			}
+		}
+	}
+
+	if !nonZero {
+		t.Fatal("the patch rendered silence")
+	}
+}